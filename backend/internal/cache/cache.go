@@ -0,0 +1,110 @@
+// Package cache is a small in-process TTL cache for hot read paths.
+package cache
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+type entry[V any] struct {
+	value   V
+	found   bool
+	expires time.Time
+}
+
+// TTL caches loader results per key. Misses (found == false) are cached too,
+// for a shorter time, so unknown keys do not hit the database on every call.
+// Flush drops everything and also discards loads that were in flight, so a
+// read racing a write cannot put stale data back.
+type TTL[V any] struct {
+	mu          sync.RWMutex
+	items       map[string]entry[V]
+	gen         uint64
+	ttl         time.Duration
+	negativeTTL time.Duration
+	max         int
+	now         func() time.Time
+}
+
+// New returns a cache holding at most max keys
+func New[V any](ttl, negativeTTL time.Duration, max int) *TTL[V] {
+	return &TTL[V]{items: map[string]entry[V]{}, ttl: ttl, negativeTTL: negativeTTL, max: max, now: time.Now}
+}
+
+// Get returns the value for key, calling load on a miss or after expiry.
+// Load errors are returned and not cached.
+func (c *TTL[V]) Get(key string, load func() (V, bool, error)) (V, bool, error) {
+	now := c.now()
+	c.mu.RLock()
+	e, ok := c.items[key]
+	gen := c.gen
+	c.mu.RUnlock()
+	if ok && now.Before(e.expires) {
+		return e.value, e.found, nil
+	}
+
+	v, found, err := load()
+	if err != nil {
+		return v, false, err
+	}
+	ttl := c.ttl
+	if !found {
+		ttl = c.negativeTTL
+	}
+	if ttl <= 0 {
+		return v, found, nil
+	}
+	c.mu.Lock()
+	if c.gen == gen {
+		if len(c.items) >= c.max {
+			c.evict(now)
+		}
+		c.items[key] = entry[V]{value: v, found: found, expires: now.Add(ttl)}
+	}
+	c.mu.Unlock()
+	return v, found, nil
+}
+
+// evict makes room for one more key: it drops expired keys and, if the cache
+// is still full, the tenth closest to expiry. Every entry of a kind lives for
+// the same ttl, so those are the oldest ones (or short-lived misses). Caller
+// holds mu.
+func (c *TTL[V]) evict(now time.Time) {
+	for k, e := range c.items {
+		if !now.Before(e.expires) {
+			delete(c.items, k)
+		}
+	}
+	if len(c.items) < c.max {
+		return
+	}
+	type aged struct {
+		key     string
+		expires time.Time
+	}
+	all := make([]aged, 0, len(c.items))
+	for k, e := range c.items {
+		all = append(all, aged{k, e.expires})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].expires.Before(all[j].expires) })
+	n := max(c.max/10, len(all)-c.max+1)
+	for _, a := range all[:n] {
+		delete(c.items, a.key)
+	}
+}
+
+// Flush empties the cache
+func (c *TTL[V]) Flush() {
+	c.mu.Lock()
+	c.items = map[string]entry[V]{}
+	c.gen++
+	c.mu.Unlock()
+}
+
+// Len reports the number of cached keys, expired ones included
+func (c *TTL[V]) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.items)
+}
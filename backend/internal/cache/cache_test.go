@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestTTLCachesHitsAndMisses(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := New[string](time.Minute, 10*time.Second, 10)
+	c.now = func() time.Time { return now }
+	loads := 0
+	load := func(v string, found bool) func() (string, bool, error) {
+		return func() (string, bool, error) { loads++; return v, found, nil }
+	}
+
+	if v, ok, _ := c.Get("a", load("shop", true)); v != "shop" || !ok {
+		t.Fatalf("got %q %v", v, ok)
+	}
+	c.Get("a", load("other", true))
+	if _, ok, _ := c.Get("missing", load("", false)); ok {
+		t.Fatal("miss reported as found")
+	}
+	c.Get("missing", load("", false))
+	if loads != 2 {
+		t.Fatalf("loads = %d, want 2", loads)
+	}
+
+	now = now.Add(11 * time.Second) // negative entry expired, positive still fresh
+	c.Get("a", load("other", true))
+	c.Get("missing", load("", false))
+	if loads != 3 {
+		t.Fatalf("loads = %d, want 3", loads)
+	}
+}
+
+func TestTTLDoesNotCacheErrors(t *testing.T) {
+	c := New[int](time.Minute, time.Minute, 10)
+	boom := errors.New("db down")
+	if _, _, err := c.Get("k", func() (int, bool, error) { return 0, false, boom }); err != boom {
+		t.Fatalf("err = %v", err)
+	}
+	if c.Len() != 0 {
+		t.Fatal("error result was cached")
+	}
+}
+
+func TestFlushDiscardsInFlightLoad(t *testing.T) {
+	c := New[int](time.Minute, time.Minute, 10)
+	c.Get("k", func() (int, bool, error) {
+		c.Flush() // a write lands while the read is loading
+		return 1, true, nil
+	})
+	if c.Len() != 0 {
+		t.Fatal("stale load was stored after Flush")
+	}
+}
+
+func TestTTLBoundsSize(t *testing.T) {
+	c := New[int](time.Minute, time.Minute, 3)
+	for i := 0; i < 10; i++ {
+		c.Get(strconv.Itoa(i), func() (int, bool, error) { return i, true, nil })
+	}
+	if n := c.Len(); n > 3 {
+		t.Fatalf("len = %d, want <= 3", n)
+	}
+}
+
+func TestTTLEvictsExpiredThenOldest(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := New[int](time.Minute, 5*time.Second, 3)
+	c.now = func() time.Time { return now }
+	loads := map[string]int{}
+	get := func(key string, found bool) {
+		c.Get(key, func() (int, bool, error) { loads[key]++; return 1, found, nil })
+	}
+
+	get("a", true)
+	now = now.Add(time.Second)
+	get("miss", false) // expires at +6s
+	now = now.Add(time.Second)
+	get("b", true)
+	now = now.Add(10 * time.Second)
+	get("c", true) // full: only the expired miss goes
+	for _, k := range []string{"a", "b", "c"} {
+		get(k, true)
+		if loads[k] != 1 {
+			t.Fatalf("%s was evicted while an expired key was available", k)
+		}
+	}
+
+	now = now.Add(time.Second)
+	get("d", true) // full, nothing expired: the oldest goes
+	if c.Len() != 3 {
+		t.Fatalf("len = %d, want 3", c.Len())
+	}
+	for _, k := range []string{"b", "c", "d", "a"} {
+		get(k, true)
+	}
+	if loads["a"] != 2 || loads["b"] != 1 || loads["c"] != 1 || loads["d"] != 1 {
+		t.Fatalf("loads = %v, want only the oldest key reloaded", loads)
+	}
+}
@@ -3,6 +3,8 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -12,9 +14,66 @@ type Config struct {
 	DBDSN        string
 	ClientOrigin string
 	RateLimit    int
-	SlackWebhook string
+	SlackWebhook string // legacy: seeds a Slack channel when none is configured
 	Timezone     string
 	Port         string
+	MFAIssuer    string
+	// Login brute-force protection
+	LoginBackoffAfter int
+	LoginLockAfter    int
+	LoginLockDuration time.Duration
+	// Outgoing mail: MailDriver is "smtp" or "log" (writes to MailLogFile or stdout)
+	MailDriver       string
+	MailFrom         string
+	MailLogFile      string
+	SMTPHost         string
+	SMTPPort         int
+	SMTPUsername     string
+	SMTPPassword     string
+	PasswordResetURL string
+	// Sign-up: when RegistrationEnabled is false, accounts come from invitations
+	RegistrationEnabled bool
+	InviteURL           string
+	// AppSecret signs invitation links; a random one is used when empty
+	AppSecret string
+	// Ed25519 keys ("kid:base64seed,...") signing public check responses
+	SigningKeys      string
+	SigningActiveKID string
+	// Shop lifecycle windows, in days
+	ShopGraceDays        int
+	ShopExpiringSoonDays int
+	// ShopMaxDomains is the default number of domains per shop, primary included
+	ShopMaxDomains int
+	// DomainStripWWW treats www.example.com as example.com everywhere
+	DomainStripWWW bool
+	// Domain ownership verification
+	RequireDomainVerification bool
+	DomainVerifyInterval      time.Duration
+	// /shops/check caching: server-side lookup cache and client max-age
+	CheckCacheTTL    time.Duration
+	CheckNegativeTTL time.Duration
+	CheckMaxAge      time.Duration
+	// Batched API log writer
+	APILogBuffer        int
+	APILogWorkers       int
+	APILogBatchSize     int
+	APILogFlushInterval time.Duration
+	APILogPolicy        string
+	// Raw API log retention in days (0 keeps everything) and delete chunk size
+	APILogRetentionDays int
+	APILogPruneChunk    int
+	// Licence abuse detection over check traffic; AbuseScanInterval 0 disables the job
+	AbuseScanInterval time.Duration
+	AbuseWindow       time.Duration
+	AbuseMaxIPs       int
+	AbuseAutoSuspend  bool
+	AbuseIgnore       []string
+	// /shops/check rate limits per minute (0 disables); RateLimitStore is "memory" or "db"
+	CheckRateLimitIP   int
+	CheckRateLimitShop int
+	RateLimitStore     string
+	// TrustedProxies are the CIDRs whose X-Forwarded-For is believed for the client IP
+	TrustedProxies []string
 }
 
 // Load reads configuration from environment variables and .env file
@@ -30,6 +89,58 @@ func Load() *Config {
 		SlackWebhook: getEnv("SLACK_WEBHOOK_URL", ""),
 		Timezone:     getEnv("APP_TIMEZONE", "+07:00"),
 		Port:         getEnv("APP_PORT", "8080"),
+		MFAIssuer:    getEnv("MFA_ISSUER", "Subly"),
+
+		LoginBackoffAfter: getInt("LOGIN_BACKOFF_AFTER", 5),
+		LoginLockAfter:    getInt("LOGIN_LOCK_AFTER", 10),
+		LoginLockDuration: getDuration("LOGIN_LOCK_DURATION", 15*time.Minute),
+
+		MailDriver:       getEnv("MAIL_DRIVER", "log"),
+		MailFrom:         getEnv("MAIL_FROM", "Subly <no-reply@localhost>"),
+		MailLogFile:      getEnv("MAIL_LOG_FILE", ""),
+		SMTPHost:         getEnv("SMTP_HOST", ""),
+		SMTPPort:         getInt("SMTP_PORT", 587),
+		SMTPUsername:     getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:     getEnv("SMTP_PASSWORD", ""),
+		PasswordResetURL: getEnv("PASSWORD_RESET_URL", "http://localhost:8084/auth/reset-password"),
+
+		RegistrationEnabled: getBool("REGISTRATION_ENABLED", false),
+		InviteURL:           getEnv("INVITE_URL", "http://localhost:8084/auth/accept-invite"),
+		AppSecret:           getEnv("APP_SECRET", ""),
+
+		SigningKeys:      getEnv("SIGNING_KEYS", ""),
+		SigningActiveKID: getEnv("SIGNING_ACTIVE_KID", ""),
+
+		ShopGraceDays:        getInt("SHOP_GRACE_DAYS", 0),
+		ShopExpiringSoonDays: getInt("SHOP_EXPIRING_SOON_DAYS", 30),
+		ShopMaxDomains:       getInt("SHOP_MAX_DOMAINS", 5),
+		DomainStripWWW:       getBool("DOMAIN_STRIP_WWW", false),
+
+		RequireDomainVerification: getBool("REQUIRE_DOMAIN_VERIFICATION", false),
+		DomainVerifyInterval:      getDuration("DOMAIN_VERIFY_INTERVAL", 15*time.Minute),
+
+		CheckCacheTTL:    getDuration("CHECK_CACHE_TTL", 30*time.Second),
+		CheckNegativeTTL: getDuration("CHECK_NEGATIVE_TTL", 10*time.Second),
+		CheckMaxAge:      getDuration("CHECK_MAX_AGE", 0),
+
+		APILogBuffer:        getInt("API_LOG_BUFFER", 10000),
+		APILogWorkers:       getInt("API_LOG_WORKERS", 2),
+		APILogBatchSize:     getInt("API_LOG_BATCH_SIZE", 200),
+		APILogFlushInterval: getDuration("API_LOG_FLUSH_INTERVAL", time.Second),
+		APILogPolicy:        getEnv("API_LOG_POLICY", "drop"),
+		APILogRetentionDays: getInt("API_LOG_RETENTION_DAYS", 90),
+		APILogPruneChunk:    getInt("API_LOG_PRUNE_CHUNK", 5000),
+
+		AbuseScanInterval: getDuration("ABUSE_SCAN_INTERVAL", time.Hour),
+		AbuseWindow:       getDuration("ABUSE_WINDOW", 24*time.Hour),
+		AbuseMaxIPs:       getInt("ABUSE_MAX_IPS", 20),
+		AbuseAutoSuspend:  getBool("ABUSE_AUTO_SUSPEND", false),
+		AbuseIgnore:       getList("ABUSE_IGNORE_DOMAINS", "localhost,127.0.0.1"),
+
+		CheckRateLimitIP:   getInt("CHECK_RATE_LIMIT_IP", 120),
+		CheckRateLimitShop: getInt("CHECK_RATE_LIMIT_SHOP", 600),
+		RateLimitStore:     getEnv("RATE_LIMIT_STORE", "memory"),
+		TrustedProxies:     getList("TRUSTED_PROXIES", "127.0.0.0/8,10.0.0.0/8,172.16.0.0/12,192.168.0.0/16,::1/128"),
 	}
 	return cfg
 }
@@ -53,3 +164,38 @@ func getInt(key string, def int) int {
 	}
 	return i
 }
+
+func getBool(key string, def bool) bool {
+	val := os.Getenv(key)
+	if val == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		return def
+	}
+	return b
+}
+
+func getDuration(key string, def time.Duration) time.Duration {
+	val := os.Getenv(key)
+	if val == "" {
+		return def
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		return def
+	}
+	return d
+}
+
+// getList splits a comma-separated value, dropping blanks; entries are lower-cased
+func getList(key, def string) []string {
+	var out []string
+	for _, v := range strings.Split(getEnv(key, def), ",") {
+		if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -50,11 +50,24 @@ func connectOnce(dsn string) (*gorm.DB, error) {
 	}
 	if err := db.AutoMigrate(
 		&model.User{},
+		&model.UserRecoveryCode{},
+		&model.LoginAttempt{},
+		&model.Invitation{},
+		&model.AuditEvent{},
+		&model.ShopLicense{},
 		&model.Role{},
 		&model.Permission{},
 		&model.Shop{},
 		&model.ShopRenewal{},
+		&model.ShopSuspension{},
+		&model.ShopDomain{},
+		&model.ShopIPRange{},
 		&model.ShopAPILog{},
+		&model.ShopAPILogDaily{},
+		&model.AbuseFinding{},
+		&model.RateLimitBucket{},
+		&model.NotificationChannel{},
+		&model.NotificationDelivery{},
 		&model.CustomerShop{},
 		&domain.Token{},
 	); err != nil {
@@ -1,9 +1,60 @@
 package database
 
-import "gorm.io/gorm"
+import (
+	"errors"
 
-// Seed creates default roles and demo users for quick login
+	"gorm.io/gorm"
+
+	"metronic/internal/model"
+)
+
+// Seed creates the built-in permissions and the admin role holding all of them.
+// It is idempotent and runs on every start so newly added permission codes
+// are granted to admin automatically. When the admin role is created, on the
+// first start with roles, the oldest user is promoted so an existing install
+// is not locked out. Later starts never promote anyone: an admin removed on
+// purpose stays removed. On a fresh install the first account registered
+// becomes admin instead (AuthService.WithRoles).
 func Seed(db *gorm.DB) error {
-	_ = db
-	return nil
+	return db.Transaction(func(tx *gorm.DB) error {
+		perms := make([]model.Permission, 0, len(model.BuiltinPermissions))
+		for _, p := range model.BuiltinPermissions {
+			var existing model.Permission
+			err := tx.Where("code = ?", p.Code).First(&existing).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				existing = p
+				if err := tx.Create(&existing).Error; err != nil {
+					return err
+				}
+			} else if err != nil {
+				return err
+			} else if existing.Name != p.Name {
+				if err := tx.Model(&existing).Update("name", p.Name).Error; err != nil {
+					return err
+				}
+			}
+			perms = append(perms, existing)
+		}
+
+		var admin model.Role
+		res := tx.Where("name = ?", model.AdminRoleName).FirstOrCreate(&admin, model.Role{Name: model.AdminRoleName})
+		if res.Error != nil {
+			return res.Error
+		}
+		created := res.RowsAffected > 0
+		if err := tx.Model(&admin).Association("Permissions").Append(perms); err != nil {
+			return err
+		}
+		if !created {
+			return nil
+		}
+		var first model.User
+		if err := tx.Order("id ASC").First(&first).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+		return tx.Model(&first).Association("Roles").Append(&admin)
+	})
 }
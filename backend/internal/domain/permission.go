@@ -0,0 +1,6 @@
+package domain
+
+// PermissionRepository resolves the permission codes a user holds through roles
+type PermissionRepository interface {
+	PermissionCodesByUserID(userID uint) ([]string, error)
+}
@@ -2,12 +2,37 @@ package domain
 
 import "time"
 
+// Token kinds stored in api_tokens
+const (
+	TokenKindAccess  = "access"
+	TokenKindRefresh = "refresh"
+	TokenKindAPIKey  = "api_key"
+	// TokenKindMFAPending is the short-lived challenge between password and 2FA code
+	TokenKindMFAPending = "mfa_pending"
+	// TokenKindPasswordReset is the single-use token mailed by forgot-password
+	TokenKindPasswordReset = "password_reset"
+)
+
+// NoExpiry is stored as ExpiresAt for API keys created without an expiry
+var NoExpiry = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// SessionTokenKinds are the kinds that make up an interactive login session
+var SessionTokenKinds = []string{TokenKindAccess, TokenKindRefresh}
+
 // Token represents an opaque API token stored hashed in the database
 // The plain token is never stored.
 type Token struct {
-	ID         uint      `gorm:"primaryKey"`
-	UserID     uint      `gorm:"index:idx_user_id"`
-	Name       *string   `gorm:"size:255"`
+	ID     uint   `gorm:"primaryKey"`
+	UserID uint   `gorm:"index:idx_user_id"`
+	Kind   string `gorm:"size:16;default:access;index"`
+	// FamilyID groups the access and refresh tokens descending from one login.
+	// Reuse of a rotated refresh token revokes the whole family.
+	FamilyID string  `gorm:"size:32;index"`
+	Name     *string `gorm:"size:255"`
+	// Prefix is the non-secret head of an API key, shown in lists and logs
+	Prefix string `gorm:"size:16"`
+	// Scopes is a space separated scope list, only used by API keys
+	Scopes     string    `gorm:"size:500"`
 	TokenHash  string    `gorm:"uniqueIndex:ux_token_hash;size:64"`
 	Revoked    bool      `gorm:"default:false"`
 	ExpiresAt  time.Time `gorm:"index"`
@@ -26,8 +51,18 @@ func (Token) TableName() string {
 // TokenRepository defines persistence operations for tokens
 type TokenRepository interface {
 	Create(t *Token) error
+	FindByID(id uint) (*Token, error)
 	FindByHash(hash string) (*Token, error)
+	// ListActiveByUserID returns non-revoked, unexpired tokens of the given kinds, newest first
+	ListActiveByUserID(userID uint, kinds []string, now time.Time) ([]Token, error)
+	// CountActiveByUserID counts distinct sessions (token families) among active tokens
+	CountActiveByUserID(userID uint, kinds []string, now time.Time) (int64, error)
 	RevokeByID(id uint) error
+	// RevokeIfActive revokes the token only if it is not revoked yet and
+	// reports whether this call performed the revocation.
+	RevokeIfActive(id uint) (bool, error)
+	RevokeFamily(familyID string) error
 	RevokeAllByUserID(userID uint) error
+	RevokeAllByUserIDAndKind(userID uint, kind string) error
 	UpdateUsage(id uint, ip, ua string, t time.Time) error
 }
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"metronic/internal/service"
+)
+
+// APIKeyHandler handles personal API key management
+type APIKeyHandler struct {
+	svc *service.APIKeyService
+}
+
+func NewAPIKeyHandler(s *service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{svc: s}
+}
+
+// ListScopes GET /auth/api-keys/scopes
+func (h *APIKeyHandler) ListScopes(c *gin.Context) {
+	c.JSON(http.StatusOK, h.svc.Scopes())
+}
+
+// ListAPIKeys GET /auth/api-keys
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	items, err := h.svc.List(c.GetUint("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+// CreateAPIKey POST /auth/api-keys { name, scopes, expires_at? }
+// The plain key is returned only in this response.
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	var req struct {
+		Name      string     `json:"name" binding:"required"`
+		Scopes    []string   `json:"scopes" binding:"required"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	plain, key, err := h.svc.Create(c.GetUint("userID"), req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"key": plain, "api_key": key})
+}
+
+// RevokeAPIKey DELETE /auth/api-keys/:id
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if err := h.svc.Revoke(c.GetUint("userID"), uint(id)); err != nil {
+		if errors.Is(err, service.ErrAPIKeyNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"metronic/internal/repository"
+	"metronic/internal/service"
+)
+
+// AuditHandler exposes the audit trail
+type AuditHandler struct {
+	svc *service.AuditService
+}
+
+func NewAuditHandler(s *service.AuditService) *AuditHandler {
+	return &AuditHandler{svc: s}
+}
+
+// actorFrom describes the authenticated caller for the audit trail
+func actorFrom(c *gin.Context) service.Actor {
+	return service.Actor{
+		UserID:    c.GetUint("userID"),
+		IP:        c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+	}
+}
+
+// ListAuditEvents GET /audit-events?actor_id=&action=&entity_type=&entity_id=&from=&to=&page=&limit=
+func (h *AuditHandler) ListAuditEvents(c *gin.Context) {
+	page, limit := parsePaging(c, 200)
+	f := repository.AuditFilter{
+		Action:     c.Query("action"),
+		EntityType: c.Query("entity_type"),
+	}
+	for key, dst := range map[string]*uint{"actor_id": &f.ActorID, "entity_id": &f.EntityID} {
+		if v := c.Query(key); v != "" {
+			n, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + key})
+				return
+			}
+			*dst = uint(n)
+		}
+	}
+	f.From, f.To = parseTimeRange(c)
+	items, total, err := h.svc.List(f, page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items, "page": page, "limit": limit, "total": total})
+}
+
+// ShopTimeline GET /shops/:id/timeline
+func (h *AuditHandler) ShopTimeline(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	page, limit := parsePaging(c, 200)
+	items, total, err := h.svc.ShopTimeline(uint(id), page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items, "page": page, "limit": limit, "total": total})
+}
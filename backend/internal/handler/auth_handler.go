@@ -1,8 +1,11 @@
 package handler
 
 import (
+	"errors"
 	"io"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -31,7 +34,11 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 	if err := h.svc.Register(req.Email, req.Password); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		if errors.Is(err, service.ErrRegistrationClosed) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
 		return
 	}
 	c.Status(http.StatusCreated)
@@ -46,58 +53,111 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	token, exp, user, err := h.svc.Login(req.Email, req.Password, c.ClientIP(), c.GetHeader("User-Agent"))
+	pair, challenge, user, err := h.svc.Login(req.Email, req.Password, c.ClientIP(), c.GetHeader("User-Agent"))
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		if !abortLoginBlocked(c, err) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	if challenge != nil {
+		// Password is fine but a second factor is needed; see VerifyMFA
+		c.JSON(http.StatusOK, gin.H{
+			"mfa_required":   true,
+			"mfa_token":      challenge.Token,
+			"mfa_expires_in": int(time.Until(challenge.ExpiresAt).Seconds()),
+		})
 		return
 	}
-	ttl := int(time.Until(exp).Seconds())
 	headers := map[string]string{
 		"Origin":     c.GetHeader("Origin"),
 		"Referer":    c.GetHeader("Referer"),
 		"User-Agent": c.GetHeader("User-Agent"),
 	}
-	c.JSON(http.StatusOK, gin.H{
-		"access_token":       token,
-		"access_expires_in":  ttl,
-		"refresh_token":      token,
-		"refresh_expires_in": ttl,
-		"user":               user,
-		"headers":            headers,
-	})
+	resp := tokenPairJSON(pair)
+	resp["user"] = user
+	resp["headers"] = headers
+	c.JSON(http.StatusOK, resp)
+}
+
+// VerifyMFA POST /auth/mfa/verify { mfa_token, code }
+// code is either the current TOTP code or one of the recovery codes.
+func (h *AuthHandler) VerifyMFA(c *gin.Context) {
+	var req struct {
+		MFAToken string `json:"mfa_token" binding:"required"`
+		Code     string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	pair, user, err := h.svc.VerifyMFA(req.MFAToken, req.Code, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		if !abortLoginBlocked(c, err) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	resp := tokenPairJSON(pair)
+	resp["user"] = user
+	c.JSON(http.StatusOK, resp)
 }
 
+// Refresh POST /auth/refresh { refresh_token }
+// The body wins over the Authorization header, because clients usually still
+// attach their (expired) access token as bearer on every request.
 func (h *AuthHandler) Refresh(c *gin.Context) {
 	tok := ""
-	auth := c.GetHeader("Authorization")
-	if strings.HasPrefix(auth, "Bearer ") {
-		tok = strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := c.ShouldBindJSON(&req); err == nil {
+		tok = strings.TrimSpace(req.RefreshToken)
 	}
 	if tok == "" {
-		var req struct {
-			RefreshToken string `json:"refresh_token"`
-		}
-		if err := c.ShouldBindJSON(&req); err == nil {
-			tok = req.RefreshToken
+		auth := c.GetHeader("Authorization")
+		if strings.HasPrefix(auth, "Bearer ") {
+			tok = strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
 		}
 	}
 	if tok == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "missing token"})
 		return
 	}
-	token, exp, user, err := h.svc.Refresh(tok, c.ClientIP(), c.GetHeader("User-Agent"))
+	pair, user, err := h.svc.Refresh(tok, c.ClientIP(), c.GetHeader("User-Agent"))
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
-	ttl := int(time.Until(exp).Seconds())
-	c.JSON(http.StatusOK, gin.H{
-		"access_token":       token,
-		"access_expires_in":  ttl,
-		"refresh_token":      token,
-		"refresh_expires_in": ttl,
-		"user":               user,
-	})
+	resp := tokenPairJSON(pair)
+	resp["user"] = user
+	c.JSON(http.StatusOK, resp)
+}
+
+// abortLoginBlocked answers a refused login: 423 for a locked account,
+// 429 while backing off. It reports whether err was such a refusal.
+func abortLoginBlocked(c *gin.Context, err error) bool {
+	var blocked *service.LoginBlockedError
+	if !errors.As(err, &blocked) {
+		return false
+	}
+	retry := int(math.Ceil(blocked.RetryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(retry))
+	if blocked.Locked {
+		c.JSON(http.StatusLocked, gin.H{"error": "account locked", "code": "account_locked", "retry_after": retry})
+	} else {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error(), "code": "login_throttled", "retry_after": retry})
+	}
+	return true
+}
+
+func tokenPairJSON(p *service.TokenPair) gin.H {
+	return gin.H{
+		"access_token":       p.AccessToken,
+		"access_expires_in":  int(time.Until(p.AccessExpiresAt).Seconds()),
+		"refresh_token":      p.RefreshToken,
+		"refresh_expires_in": int(time.Until(p.RefreshExpiresAt).Seconds()),
+	}
 }
 
 func (h *AuthHandler) Me(c *gin.Context) {
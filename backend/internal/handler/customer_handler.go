@@ -61,7 +61,7 @@ func (h *CustomerHandler) DeleteCustomer(c *gin.Context) {
         c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
         return
     }
-    if err := h.svc.Delete(uint(id)); err != nil {
+    if err := h.svc.Delete(actorFrom(c), uint(id)); err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         return
     }
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"metronic/internal/service"
+)
+
+// InvitationHandler handles admin invitations and their public acceptance
+type InvitationHandler struct {
+	svc *service.InvitationService
+}
+
+func NewInvitationHandler(s *service.InvitationService) *InvitationHandler {
+	return &InvitationHandler{svc: s}
+}
+
+// CreateInvitation POST /invitations { email, role_id }
+func (h *InvitationHandler) CreateInvitation(c *gin.Context) {
+	var req struct {
+		Email  string `json:"email" binding:"required,email"`
+		RoleID uint   `json:"role_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	inv, err := h.svc.Invite(req.Email, req.RoleID, c.GetUint("userID"))
+	if err != nil {
+		writeInvitationError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, inv)
+}
+
+// ListInvitations GET /invitations?status=pending|accepted|revoked|expired
+func (h *InvitationHandler) ListInvitations(c *gin.Context) {
+	status := c.DefaultQuery("status", "pending")
+	switch status {
+	case "pending", "accepted", "revoked", "expired", "all":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
+		return
+	}
+	items, err := h.svc.List(status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+// ResendInvitation POST /invitations/:id/resend
+func (h *InvitationHandler) ResendInvitation(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	inv, err := h.svc.Resend(uint(id))
+	if err != nil {
+		writeInvitationError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, inv)
+}
+
+// RevokeInvitation DELETE /invitations/:id
+func (h *InvitationHandler) RevokeInvitation(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if err := h.svc.Revoke(uint(id)); err != nil {
+		writeInvitationError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetInvitation GET /auth/invitations?token=...
+// Lets the accept page show which email and role the link is for.
+func (h *InvitationHandler) GetInvitation(c *gin.Context) {
+	inv, err := h.svc.Lookup(c.Query("token"))
+	if err != nil {
+		writeInvitationError(c, err)
+		return
+	}
+	resp := gin.H{"email": inv.Email, "expires_at": inv.ExpiresAt}
+	if inv.Role != nil {
+		resp["role"] = inv.Role.Name
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// AcceptInvitation POST /auth/invitations/accept { token, name, password }
+func (h *InvitationHandler) AcceptInvitation(c *gin.Context) {
+	var req struct {
+		Token    string `json:"token" binding:"required"`
+		Name     string `json:"name" binding:"required"`
+		Password string `json:"password" binding:"required,min=6"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	user, err := h.svc.Accept(req.Token, req.Name, req.Password)
+	if err != nil {
+		writeInvitationError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, user)
+}
+
+func writeInvitationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "invitation not found"})
+	case errors.Is(err, service.ErrRoleNotGrantable):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvitePending), errors.Is(err, service.ErrEmailTaken), errors.Is(err, service.ErrInviteNotOpen):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidInvite), errors.Is(err, service.ErrUnknownRole):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
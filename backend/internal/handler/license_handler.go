@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"metronic/internal/model"
+	"metronic/internal/service"
+)
+
+// LicenseHandler serves signed shop license files
+type LicenseHandler struct {
+	svc *service.LicenseService
+}
+
+func NewLicenseHandler(s *service.LicenseService) *LicenseHandler {
+	return &LicenseHandler{svc: s}
+}
+
+// DownloadLicense GET /shops/:id/license
+// Returns the current license as a file to install on the shop's server.
+func (h *LicenseHandler) DownloadLicense(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	l, shop, err := h.svc.Current(uint(id))
+	if err != nil {
+		writeLicenseError(c, err)
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="subly-license-`+shop.UUID+`.json"`)
+	writeLicense(c, l)
+}
+
+// RefreshLicense GET /licenses/:shop_uuid
+// Public: lets a shop server replace its cached license before it runs out.
+func (h *LicenseHandler) RefreshLicense(c *gin.Context) {
+	l, _, err := h.svc.CurrentByUUID(c.Param("shop_uuid"))
+	if err != nil {
+		writeLicenseError(c, err)
+		return
+	}
+	writeLicense(c, l)
+}
+
+func writeLicense(c *gin.Context, l *model.ShopLicense) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-License-Serial", strconv.FormatUint(uint64(l.Serial), 10))
+	c.Data(http.StatusOK, "application/json; charset=utf-8", []byte(l.Document))
+}
+
+func writeLicenseError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "shop not found"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
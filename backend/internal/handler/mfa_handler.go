@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"metronic/internal/service"
+)
+
+// MFAHandler handles TOTP enrolment for the current user and admin resets
+type MFAHandler struct {
+	svc *service.MFAService
+}
+
+func NewMFAHandler(s *service.MFAService) *MFAHandler {
+	return &MFAHandler{svc: s}
+}
+
+// Status GET /auth/mfa
+func (h *MFAHandler) Status(c *gin.Context) {
+	enabled, remaining, err := h.svc.Status(c.GetUint("userID"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"totp_enabled": enabled, "recovery_codes_remaining": remaining})
+}
+
+// SetupTOTP POST /auth/mfa/totp/setup
+// Returns a new secret and otpauth URI; 2FA stays off until confirmed.
+func (h *MFAHandler) SetupTOTP(c *gin.Context) {
+	secret, uri, err := h.svc.Setup(c.GetUint("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"secret": secret, "otpauth_uri": uri})
+}
+
+// ConfirmTOTP POST /auth/mfa/totp/confirm { code }
+// Enables 2FA and returns recovery codes, shown only once.
+func (h *MFAHandler) ConfirmTOTP(c *gin.Context) {
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	codes, err := h.svc.Confirm(c.GetUint("userID"), req.Code)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// RegenerateRecoveryCodes POST /auth/mfa/recovery-codes { code }
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	codes, err := h.svc.RegenerateRecoveryCodes(c.GetUint("userID"), req.Code)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// DisableTOTP POST /auth/mfa/totp/disable { code }
+func (h *MFAHandler) DisableTOTP(c *gin.Context) {
+	var req struct {
+		Code string `json:"code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.svc.Disable(c.GetUint("userID"), req.Code); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ResetUserMFA DELETE /users/:id/mfa
+// Admin escape hatch for users who lost both their device and recovery codes.
+func (h *MFAHandler) ResetUserMFA(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if err := h.svc.Reset(uint(id)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"metronic/internal/model"
+	"metronic/internal/notify"
+	"metronic/internal/service"
+)
+
+// NotificationHandler manages notification channels and their delivery log
+type NotificationHandler struct {
+	svc *service.NotificationService
+}
+
+func NewNotificationHandler(s *service.NotificationService) *NotificationHandler {
+	return &NotificationHandler{svc: s}
+}
+
+// ListChannels GET /notification-channels
+// Also lists the supported kinds and events for building the form.
+func (h *NotificationHandler) ListChannels(c *gin.Context) {
+	items, err := h.svc.ListChannels()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items, "kinds": notify.Kinds, "events": model.NotificationEvents})
+}
+
+// CreateChannel POST /notification-channels { name, kind, config, events?, enabled? }
+func (h *NotificationHandler) CreateChannel(c *gin.Context) {
+	var req service.ChannelInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ch, err := h.svc.CreateChannel(actorFrom(c), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, ch)
+}
+
+// UpdateChannel POST /notification-channels/:id
+func (h *NotificationHandler) UpdateChannel(c *gin.Context) {
+	id, ok := channelID(c)
+	if !ok {
+		return
+	}
+	var req service.ChannelInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ch, err := h.svc.UpdateChannel(actorFrom(c), id, req)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "channel not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, ch)
+}
+
+// DeleteChannel DELETE /notification-channels/:id
+func (h *NotificationHandler) DeleteChannel(c *gin.Context) {
+	id, ok := channelID(c)
+	if !ok {
+		return
+	}
+	err := h.svc.DeleteChannel(actorFrom(c), id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "channel not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// TestChannel POST /notification-channels/:id/test sends a test message
+func (h *NotificationHandler) TestChannel(c *gin.Context) {
+	id, ok := channelID(c)
+	if !ok {
+		return
+	}
+	d, err := h.svc.Test(c.Request.Context(), id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "channel not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "delivery": d})
+		return
+	}
+	c.JSON(http.StatusOK, d)
+}
+
+// ListDeliveries GET /notification-deliveries?channel_id=&status=&page=&limit=
+func (h *NotificationHandler) ListDeliveries(c *gin.Context) {
+	page, limit := parsePaging(c, 200)
+	var channel uint64
+	if v := c.Query("channel_id"); v != "" {
+		var err error
+		if channel, err = strconv.ParseUint(v, 10, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid channel_id"})
+			return
+		}
+	}
+	items, total, err := h.svc.ListDeliveries(uint(channel), c.Query("status"), page, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items, "page": page, "limit": limit, "total": total})
+}
+
+func channelID(c *gin.Context) (uint, bool) {
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return 0, false
+	}
+	return uint(id64), true
+}
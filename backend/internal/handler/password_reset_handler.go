@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"metronic/internal/service"
+)
+
+// PasswordResetHandler handles forgot/reset password requests
+type PasswordResetHandler struct {
+	svc *service.PasswordResetService
+}
+
+func NewPasswordResetHandler(s *service.PasswordResetService) *PasswordResetHandler {
+	return &PasswordResetHandler{svc: s}
+}
+
+// ForgotPassword POST /auth/forgot-password { email }
+// Always answers 202 so the response does not reveal whether the email exists.
+func (h *PasswordResetHandler) ForgotPassword(c *gin.Context) {
+	var req struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// a failure only happens for existing accounts, so it is logged, not returned
+	if err := h.svc.Forgot(req.Email, c.ClientIP(), c.GetHeader("User-Agent")); err != nil {
+		log.Printf("forgot password: %v", err)
+	}
+	c.Status(http.StatusAccepted)
+}
+
+// ResetPassword POST /auth/reset-password { token, password }
+func (h *PasswordResetHandler) ResetPassword(c *gin.Context) {
+	var req struct {
+		Token    string `json:"token" binding:"required"`
+		Password string `json:"password" binding:"required,min=6"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.svc.Reset(req.Token, req.Password); err != nil {
+		if errors.Is(err, service.ErrInvalidResetToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parsePaging reads ?page= (>=1) and ?limit= (default 50, capped at max)
+func parsePaging(c *gin.Context, max int) (int, int) {
+	page := 1
+	limit := 50
+	if v := c.Query("page"); v != "" {
+		if p, err := strconv.Atoi(v); err == nil && p > 0 {
+			page = p
+		}
+	}
+	if v := c.Query("limit"); v != "" {
+		if l, err := strconv.Atoi(v); err == nil && l > 0 {
+			limit = l
+		}
+	}
+	if max > 0 && limit > max {
+		limit = max
+	}
+	return page, limit
+}
+
+// parseTimeRange reads the optional ?from= and ?to= filters.
+// Both accept RFC3339 or YYYY-MM-DD; a date-only "to" is an exclusive next-day boundary (UTC).
+func parseTimeRange(c *gin.Context) (*time.Time, *time.Time) {
+	var fromTime *time.Time
+	var toTime *time.Time
+	if v := c.Query("from"); v != "" {
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			fromTime = &t
+		} else if d, err := time.Parse("2006-01-02", v); err == nil {
+			t := time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.UTC)
+			fromTime = &t
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			toTime = &t
+		} else if d, err := time.Parse("2006-01-02", v); err == nil {
+			// exclusive next day start (UTC)
+			t := time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.UTC).Add(24 * time.Hour)
+			toTime = &t
+		}
+	}
+	return fromTime, toTime
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"metronic/internal/domain"
+	"metronic/internal/service"
+)
+
+// SessionHandler exposes login sessions to their owner and to admins
+type SessionHandler struct {
+	svc *service.SessionService
+}
+
+func NewSessionHandler(s *service.SessionService) *SessionHandler {
+	return &SessionHandler{svc: s}
+}
+
+// ListMySessions GET /auth/sessions
+func (h *SessionHandler) ListMySessions(c *gin.Context) {
+	tokAny, ok := c.Get("token")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	tok := tokAny.(*domain.Token)
+	items, total, err := h.svc.List(tok.UserID, tok)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items, "total": total})
+}
+
+// RevokeMySession DELETE /auth/sessions/:id
+func (h *SessionHandler) RevokeMySession(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	h.revoke(c, c.GetUint("userID"), uint(id))
+}
+
+// ListUserSessions GET /users/:id/sessions
+func (h *SessionHandler) ListUserSessions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var current *domain.Token
+	if tokAny, ok := c.Get("token"); ok {
+		current = tokAny.(*domain.Token)
+	}
+	items, total, err := h.svc.List(uint(id), current)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items, "total": total})
+}
+
+// RevokeUserSession DELETE /users/:id/sessions/:session_id
+func (h *SessionHandler) RevokeUserSession(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	sid, err := strconv.ParseUint(c.Param("session_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session_id"})
+		return
+	}
+	h.revoke(c, uint(id), uint(sid))
+}
+
+// RevokeAllUserSessions DELETE /users/:id/sessions
+// Force-logout of every session of the user.
+func (h *SessionHandler) RevokeAllUserSessions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if err := h.svc.RevokeAll(uint(id)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *SessionHandler) revoke(c *gin.Context, userID, sessionID uint) {
+	if err := h.svc.Revoke(userID, sessionID); err != nil {
+		if errors.Is(err, service.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -7,6 +7,7 @@ import (
 
     "github.com/gin-gonic/gin"
 
+    "metronic/internal/model"
     "metronic/internal/repository"
     "metronic/internal/service"
 )
@@ -21,12 +22,9 @@ func NewShopCustomerHandler(s *service.ShopCustomerService, shops *repository.Sh
     return &ShopCustomerHandler{svc: s, shops: shops}
 }
 
-func (h *ShopCustomerHandler) resolveShopUUID(param string) (string, error) {
-    if param == "" {
-        return "", nil
-    }
-    // If all digits, treat as numeric ID and resolve to UUID
-    isDigits := true
+// resolveShop accepts a numeric shop ID or a shop UUID
+func (h *ShopCustomerHandler) resolveShop(param string) (*model.Shop, error) {
+    isDigits := param != ""
     for _, r := range param {
         if !unicode.IsDigit(r) {
             isDigits = false
@@ -34,25 +32,19 @@ func (h *ShopCustomerHandler) resolveShopUUID(param string) (string, error) {
         }
     }
     if !isDigits {
-        return param, nil
+        return h.shops.FindByUUID(param)
     }
-    // lookup by ID
     id64, err := strconv.ParseUint(param, 10, 64)
     if err != nil {
-        return "", err
-    }
-    s, err := h.shops.FindByID(uint(id64))
-    if err != nil {
-        return "", err
+        return nil, err
     }
-    return s.UUID, nil
+    return h.shops.FindByID(uint(id64))
 }
 
 // Assign POST /shops/:id/customers
 func (h *ShopCustomerHandler) Assign(c *gin.Context) {
-    raw := c.Param("id")
-    shopUUID, err := h.resolveShopUUID(raw)
-    if err != nil || shopUUID == "" {
+    shop, err := h.resolveShop(c.Param("id"))
+    if err != nil {
         c.JSON(http.StatusBadRequest, gin.H{"error": "invalid shop identifier"})
         return
     }
@@ -65,7 +57,7 @@ func (h *ShopCustomerHandler) Assign(c *gin.Context) {
         c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
         return
     }
-    if err := h.svc.Assign(shopUUID, req.CustomerID, req.Role, req.IsOwner); err != nil {
+    if err := h.svc.Assign(actorFrom(c), shop, req.CustomerID, req.Role, req.IsOwner); err != nil {
         c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
         return
     }
@@ -74,9 +66,8 @@ func (h *ShopCustomerHandler) Assign(c *gin.Context) {
 
 // Remove DELETE /shops/:id/customers/:user_id
 func (h *ShopCustomerHandler) Remove(c *gin.Context) {
-    raw := c.Param("id")
-    shopUUID, err := h.resolveShopUUID(raw)
-    if err != nil || shopUUID == "" {
+    shop, err := h.resolveShop(c.Param("id"))
+    if err != nil {
         c.JSON(http.StatusBadRequest, gin.H{"error": "invalid shop identifier"})
         return
     }
@@ -86,7 +77,7 @@ func (h *ShopCustomerHandler) Remove(c *gin.Context) {
         c.JSON(http.StatusBadRequest, gin.H{"error": "invalid customer_id"})
         return
     }
-    if err := h.svc.Remove(shopUUID, uint(userID)); err != nil {
+    if err := h.svc.Remove(actorFrom(c), shop, uint(userID)); err != nil {
         c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
         return
     }
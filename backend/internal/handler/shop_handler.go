@@ -1,29 +1,57 @@
 package handler
 
 import (
-	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 
+	"metronic/internal/middleware"
 	"metronic/internal/model"
+	"metronic/internal/ratelimit"
 	"metronic/internal/repository"
+	"metronic/internal/security"
 	"metronic/internal/service"
 )
 
+// shopChecker is the part of ShopService behind the public check endpoints;
+// ShopService satisfies it and tests swap in a stub
+type shopChecker interface {
+	LookupForCheck(shopUUID, domain string) (*model.Shop, error)
+	FindBatch(uuids, domains []string) (map[string]*model.Shop, map[string]*model.Shop, error)
+	IPAllowed(shopID uint, ip string) (bool, error)
+	IPRestricted(shopID uint) bool
+	State(m *model.Shop, now time.Time) (string, *time.Time)
+}
+
 // ShopHandler handles HTTP requests for shops
 type ShopHandler struct {
-	svc          *service.ShopService
-	apiLogs      *repository.ShopAPILogRepository
-	slackWebhook string
+	svc         *service.ShopService
+	check       shopChecker
+	apiLogs     *repository.ShopAPILogRepository
+	notices     *service.NotificationService
+	signer      *security.KeyRing
+	checkMaxAge time.Duration
+	logWriter   *service.APILogWriter
+	retention   *service.APILogRetention
+	abuse       *service.AbuseDetector
+	limits      ratelimit.Store
+	ipLimit     ratelimit.Limit
+	shopLimit   ratelimit.Limit
 }
 
 func NewShopHandler(s *service.ShopService) *ShopHandler {
-	return &ShopHandler{svc: s}
+	return &ShopHandler{svc: s, check: s}
 }
 
 func (h *ShopHandler) WithAPILogRepo(r *repository.ShopAPILogRepository) *ShopHandler {
@@ -31,8 +59,55 @@ func (h *ShopHandler) WithAPILogRepo(r *repository.ShopAPILogRepository) *ShopHa
 	return h
 }
 
-func (h *ShopHandler) WithSlackWebhook(url string) *ShopHandler {
-	h.slackWebhook = url
+// WithNotifications sends the manual expiry report through the configured channels
+func (h *ShopHandler) WithNotifications(n *service.NotificationService) *ShopHandler {
+	h.notices = n
+	return h
+}
+
+// WithSigner makes CheckStatus sign its responses with the ring's active key
+func (h *ShopHandler) WithSigner(k *security.KeyRing) *ShopHandler {
+	h.signer = k
+	return h
+}
+
+// WithAPILogWriter records /shops/check calls through the batched pipeline
+func (h *ShopHandler) WithAPILogWriter(w *service.APILogWriter) *ShopHandler {
+	h.logWriter = w
+	return h
+}
+
+// WithRetention enables the manual API log prune endpoint
+func (h *ShopHandler) WithRetention(r *service.APILogRetention) *ShopHandler {
+	h.retention = r
+	return h
+}
+
+// WithAbuseDetector enables the abuse findings endpoints
+func (h *ShopHandler) WithAbuseDetector(d *service.AbuseDetector) *ShopHandler {
+	h.abuse = d
+	return h
+}
+
+// WithCheckRateLimit caps the public check endpoints per client IP and
+// /shops/check per resolved shop, whatever IP or domain spelling it comes from
+func (h *ShopHandler) WithCheckRateLimit(store ratelimit.Store, perIP, perShop ratelimit.Limit) *ShopHandler {
+	h.limits, h.ipLimit, h.shopLimit = store, perIP, perShop
+	return h
+}
+
+// CheckThrottle is the per-IP limit for the public check routes
+func (h *ShopHandler) CheckThrottle() gin.HandlerFunc {
+	if h.limits == nil {
+		return func(c *gin.Context) { c.Next() }
+	}
+	return middleware.Throttle(h.limits, "check:ip:", h.ipLimit)
+}
+
+// WithCheckMaxAge lets clients reuse /shops/check answers for d without
+// revalidating; zero makes them revalidate with If-None-Match every time
+func (h *ShopHandler) WithCheckMaxAge(d time.Duration) *ShopHandler {
+	h.checkMaxAge = d
 	return h
 }
 
@@ -57,7 +132,7 @@ func (h *ShopHandler) CreateShop(c *gin.Context) {
 	if req.CycleMonths != nil {
 		cycle = int(*req.CycleMonths)
 	}
-	m, err := h.svc.Create(req.Domain, req.UUID, req.ExpiredAt, price, cycle)
+	m, err := h.svc.Create(actorFrom(c), req.Domain, req.UUID, req.ExpiredAt, price, cycle)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -146,7 +221,7 @@ func (h *ShopHandler) UpdateShop(c *gin.Context) {
 		return
 	}
 	// Do not allow updating expired_at via Update; pass nil to keep existing
-	m, err := h.svc.Update(uint(id), req.Domain, nil)
+	m, err := h.svc.Update(actorFrom(c), uint(id), req.Domain, nil)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -162,7 +237,7 @@ func (h *ShopHandler) UpdateShop(c *gin.Context) {
 			v := int(*req.CycleMonths)
 			cm = &v
 		}
-		m, err = h.svc.UpdateBilling(uint(id), pp, cm)
+		m, err = h.svc.UpdateBilling(actorFrom(c), uint(id), pp, cm)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
@@ -171,6 +246,33 @@ func (h *ShopHandler) UpdateShop(c *gin.Context) {
 	c.JSON(http.StatusOK, m)
 }
 
+// SetGracePeriod POST /shops/:id/grace { grace_days } (null restores the global default)
+func (h *ShopHandler) SetGracePeriod(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req struct {
+		GraceDays *IntOrString `json:"grace_days"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var days *int
+	if req.GraceDays != nil {
+		v := int(*req.GraceDays)
+		days = &v
+	}
+	m, err := h.svc.SetGracePeriod(actorFrom(c), uint(id), days)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, m)
+}
+
 // DeleteShop DELETE /shops/:id
 func (h *ShopHandler) DeleteShop(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
@@ -178,7 +280,7 @@ func (h *ShopHandler) DeleteShop(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 		return
 	}
-	if err := h.svc.Delete(uint(id)); err != nil {
+	if err := h.svc.Delete(actorFrom(c), uint(id)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -192,7 +294,7 @@ func (h *ShopHandler) ForceDeleteShop(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 		return
 	}
-	if err := h.svc.ForceDelete(uint(id)); err != nil {
+	if err := h.svc.ForceDelete(actorFrom(c), uint(id)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -206,7 +308,7 @@ func (h *ShopHandler) RestoreShop(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 		return
 	}
-	if err := h.svc.Restore(uint(id)); err != nil {
+	if err := h.svc.Restore(actorFrom(c), uint(id)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -221,7 +323,7 @@ func (h *ShopHandler) RevokeShop(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 		return
 	}
-	m, err := h.svc.RevokeNow(uint(id64))
+	m, err := h.svc.RevokeNow(actorFrom(c), uint(id64))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -229,77 +331,287 @@ func (h *ShopHandler) RevokeShop(c *gin.Context) {
 	c.JSON(http.StatusOK, m)
 }
 
-// NotifyNotOver1m POST /shops/notify/not-over-1m
-// Sends a Slack message summarizing shops within ±30 days (expired + expiring)
-func (h *ShopHandler) NotifyNotOver1m(c *gin.Context) {
-	if h.slackWebhook == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "slack webhook not configured"})
+// SuspendShop POST /shops/:id/suspend { reason, message? }
+// Turns the shop off without changing expired_at; message is shown to the customer.
+func (h *ShopHandler) SuspendShop(c *gin.Context) {
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 		return
 	}
-	now := time.Now()
-	items, _, err := h.svc.ListPagedFiltered(1, 2000, "notOver1y", "")
+	var req struct {
+		Reason  string `json:"reason" binding:"required"`
+		Message string `json:"message"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	m, err := h.svc.Suspend(actorFrom(c), uint(id64), req.Reason, req.Message)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(suspendStatus(err), gin.H{"error": err.Error()})
 		return
 	}
-	var expired []string
-	var expiring []string
-	for _, s := range items {
-		if s.ExpiredAt == nil {
-			continue
-		}
-		days := int(s.ExpiredAt.Sub(now).Hours() / 24)
-		if s.ExpiredAt.After(now) && (s.ExpiredAt.Sub(now).Hours() > 0) {
-			if days < 1 {
-				days = 1
-			}
-		}
-		if s.ExpiredAt.Before(now) {
-			expired = append(expired, s.Domain+" — hết hạn "+itoa(-days)+" ngày")
-		} else {
-			expiring = append(expiring, s.Domain+" — còn "+itoa(days)+" ngày")
-		}
+	c.JSON(http.StatusOK, m)
+}
+
+// ResumeShop POST /shops/:id/resume { reason }
+func (h *ShopHandler) ResumeShop(c *gin.Context) {
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	m, err := h.svc.Resume(actorFrom(c), uint(id64), req.Reason)
+	if err != nil {
+		c.JSON(suspendStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, m)
+}
+
+func suspendStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrShopSuspended), errors.Is(err, service.ErrShopNotSuspended), errors.Is(err, service.ErrDomainUnverified):
+		return http.StatusConflict
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// ListDomains GET /shops/:id/domains
+func (h *ShopHandler) ListDomains(c *gin.Context) {
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	items, err := h.svc.ListDomains(uint(id64))
+	if err != nil {
+		c.JSON(domainStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+// AddDomain POST /shops/:id/domains { domain, kind? } kind is alias (default) or staging
+func (h *ShopHandler) AddDomain(c *gin.Context) {
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req struct {
+		Domain string `json:"domain" binding:"required"`
+		Kind   string `json:"kind"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	d, err := h.svc.AddDomain(actorFrom(c), uint(id64), req.Domain, req.Kind)
+	if err != nil {
+		c.JSON(domainStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, d)
+}
+
+// RemoveDomain DELETE /shops/:id/domains/:domain_id
+func (h *ShopHandler) RemoveDomain(c *gin.Context) {
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	domainID, err := strconv.ParseUint(c.Param("domain_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid domain_id"})
+		return
+	}
+	if err := h.svc.RemoveDomain(actorFrom(c), uint(id64), uint(domainID)); err != nil {
+		c.JSON(domainStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// VerifyDomain POST /shops/:id/domains/:domain_id/verify
+// Checks the DNS TXT record or well-known file now instead of waiting for the job.
+func (h *ShopHandler) VerifyDomain(c *gin.Context) {
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	domainID, err := strconv.ParseUint(c.Param("domain_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid domain_id"})
+		return
 	}
-	msg := "Báo cáo — Shop không quá 1 tháng\nNgày: " + now.Format("2006-01-02") + "\nTổng: " + itoa(len(items)) + "\nHết hạn: " + itoa(len(expired)) + "\nSắp hết hạn: " + itoa(len(expiring)) + "\n\n"
-	if len(expired) > 0 {
-		msg += "Hết hạn:\n" + joinLinesSimple(expired) + "\n\n"
+	d, err := h.svc.VerifyDomain(c.Request.Context(), actorFrom(c), uint(id64), uint(domainID))
+	if err != nil {
+		c.JSON(domainStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, d)
+}
+
+// SetDomainLimit POST /shops/:id/domain-limit { max_domains } (null restores the global default)
+func (h *ShopHandler) SetDomainLimit(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req struct {
+		MaxDomains *IntOrString `json:"max_domains"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-	if len(expiring) > 0 {
-		msg += "Sắp hết hạn:\n" + joinLinesSimple(expiring)
+	var max *int
+	if req.MaxDomains != nil {
+		v := int(*req.MaxDomains)
+		max = &v
 	}
-	if err := httpPostJSON(h.slackWebhook, []byte(`{"text":`+strconv.Quote(msg)+`}`)); err != nil {
+	m, err := h.svc.SetDomainLimit(actorFrom(c), uint(id), max)
+	if err != nil {
+		c.JSON(domainStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, m)
+}
+
+func domainStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrDomainTaken), errors.Is(err, service.ErrDomainLimit), errors.Is(err, service.ErrPrimaryDomain):
+		return http.StatusConflict
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// ListIPRanges GET /shops/:id/ip-ranges
+func (h *ShopHandler) ListIPRanges(c *gin.Context) {
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	items, err := h.svc.ListIPRanges(uint(id64))
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"sent": true, "total": len(items)})
+	c.JSON(http.StatusOK, items)
 }
 
-func itoa(i int) string { return strconv.FormatInt(int64(i), 10) }
+// AddIPRange POST /shops/:id/ip-ranges { cidr, note }
+func (h *ShopHandler) AddIPRange(c *gin.Context) {
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req struct {
+		CIDR string `json:"cidr" binding:"required"`
+		Note string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	rec, err := h.svc.AddIPRange(actorFrom(c), uint(id64), req.CIDR, req.Note)
+	if err != nil {
+		c.JSON(ipRangeStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, rec)
+}
 
-func joinLinesSimple(lines []string) string {
-	if len(lines) == 0 {
-		return ""
+// RemoveIPRange DELETE /shops/:id/ip-ranges/:range_id
+func (h *ShopHandler) RemoveIPRange(c *gin.Context) {
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
 	}
-	out := lines[0]
-	for i := 1; i < len(lines); i++ {
-		out += "\n" + lines[i]
+	rangeID, err := strconv.ParseUint(c.Param("range_id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid range_id"})
+		return
 	}
-	return out
+	if err := h.svc.RemoveIPRange(actorFrom(c), uint(id64), uint(rangeID)); err != nil {
+		c.JSON(ipRangeStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
 }
 
-func httpPostJSON(url string, body []byte) error {
-	req, _ := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
-	req.Header.Set("Content-Type", "application/json")
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+func ipRangeStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrIPRangeTaken), errors.Is(err, service.ErrIPRangeLimit):
+		return http.StatusConflict
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrIPRangeInvalid):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// ListSuspensions GET /shops/:id/suspensions
+func (h *ShopHandler) ListSuspensions(c *gin.Context) {
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
-		return err
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	items, err := h.svc.ListSuspensions(uint(id64))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+// NotifyNotOver1m POST /shops/notify/not-over-1m
+// Sends a report of shops within ±30 days (expired + expiring) to every
+// channel subscribed to shops.expiry_report
+func (h *ShopHandler) NotifyNotOver1m(c *gin.Context) {
+	if h.notices == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "notifications not configured"})
+		return
+	}
+	rep, err := h.svc.ExpiryReport(time.Now(), "Báo cáo — Shop không quá 1 tháng")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	deliveries, err := h.notices.Notify(c.Request.Context(), model.NotifyExpiryReport, rep.Message)
+	if errors.Is(err, service.ErrNoChannels) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode >= 300 {
-		return fmt.Errorf("slack http status %d", resp.StatusCode)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "deliveries": deliveries})
+		return
 	}
-	return nil
+	c.JSON(http.StatusOK, gin.H{"sent": true, "total": rep.Total, "deliveries": deliveries})
 }
 
 // RenewShop POST /shops/:id/renew { months, note? }
@@ -318,14 +630,12 @@ func (h *ShopHandler) RenewShop(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	uidRaw, _ := c.Get("userID")
-	performedBy, _ := uidRaw.(uint)
 	var shop *model.Shop
 	var rec *model.ShopRenewal
 	if req.NextExpiredAt != nil {
-		shop, rec, err = h.svc.RenewToDate(uint(id64), *req.NextExpiredAt, performedBy, req.Note)
+		shop, rec, err = h.svc.RenewToDate(actorFrom(c), uint(id64), *req.NextExpiredAt, req.Note)
 	} else if req.Months != nil && int(*req.Months) > 0 {
-		shop, rec, err = h.svc.Renew(uint(id64), int(*req.Months), performedBy, req.Note)
+		shop, rec, err = h.svc.Renew(actorFrom(c), uint(id64), int(*req.Months), req.Note)
 	} else {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "months or next_expired_at required"})
 		return
@@ -368,9 +678,7 @@ func (h *ShopHandler) SetExpiredAt(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	uidRaw, _ := c.Get("userID")
-	performedBy, _ := uidRaw.(uint)
-	shop, rec, err := h.svc.SetExpiryDate(uint(id64), req.NewExpiredAt, performedBy, req.Note)
+	shop, rec, err := h.svc.SetExpiryDate(actorFrom(c), uint(id64), req.NewExpiredAt, req.Note)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -378,59 +686,106 @@ func (h *ShopHandler) SetExpiredAt(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"shop": shop, "renewal": rec})
 }
 
-// CheckStatus GET /shops/check?shop_uuid=...|domain=...
-// Returns JSON with status and expiry information without auth.
+// CheckStatus GET /shops/check?shop_uuid=...|domain=...&nonce=...
+// Returns JSON with status and expiry information without auth. With a signer
+// configured the response carries a "signature" over a canonical payload that
+// echoes the client's nonce, so SDKs can detect forged or replayed answers.
 func (h *ShopHandler) CheckStatus(c *gin.Context) {
 	shopUUID := c.Query("shop_uuid")
 	domain := c.Query("domain")
+	nonce := c.Query("nonce")
 	var m *model.Shop
 	var err error
 	if shopUUID == "" && domain == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "shop_uuid or domain required"})
 		return
 	}
-	if shopUUID != "" {
-		m, err = h.svc.FindByUUID(shopUUID)
-	} else {
-		m, err = h.svc.FindByDomain(domain)
+	if !noncePattern.MatchString(nonce) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "nonce must be at most 128 characters of [A-Za-z0-9_-]"})
+		return
 	}
+	m, err = h.check.LookupForCheck(shopUUID, domain)
 	if err != nil {
 		// Return a consistent payload for not-found to simplify integrations
-		c.JSON(http.StatusOK, gin.H{"status": "not_found"})
-		// Ensure we still log the attempt even when no shop matched
-		if h.apiLogs != nil {
-			go func() {
-				domainPtr := func() *string {
-					if domain == "" {
-						return nil
-					}
-					v := domain
-					return &v
-				}()
-				uuidPtr := func() *string {
-					if shopUUID == "" {
-						return nil
-					}
-					v := shopUUID
-					return &v
-				}()
-				_ = h.apiLogs.Create(&model.ShopAPILog{
-					ShopID:      0,
-					ShopUUID:    "",
-					DomainParam: domainPtr,
-					UUIDParam:   uuidPtr,
-					ClientIP:    c.ClientIP(),
-					UserAgent:   c.GetHeader("User-Agent"),
-					Status:      "not_found",
-				})
-			}()
-		}
+		m = nil
+	}
+	if m != nil && !h.allowShop(c, m) {
 		return
 	}
 	now := time.Now()
+	resp := h.checkResult(m, shopUUID, domain, nonce, c.ClientIP(), now)
+	etag := checkETag(resp, nonce)
+	c.Header("ETag", etag)
+	if h.checkMaxAge > 0 {
+		// an allowlisted shop answers per client address: keep it out of shared caches
+		scope := "public"
+		if m != nil && h.check.IPRestricted(m.ID) {
+			scope = "private"
+		}
+		c.Header("Cache-Control", fmt.Sprintf("%s, max-age=%d", scope, int(h.checkMaxAge/time.Second)))
+	} else {
+		c.Header("Cache-Control", "no-cache")
+	}
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+	} else {
+		c.JSON(http.StatusOK, resp)
+	}
+
+	// log call (best-effort, batched); not-found attempts are logged too.
+	// Everything is read from the request before it completes.
+	if h.logWriter != nil {
+		h.logWriter.Write(*checkLog(m, shopUUID, domain, resp, c.ClientIP(), c.GetHeader("User-Agent"), now))
+	}
+}
+
+// allowShop takes a token from the shop's bucket, answering 429 when it is empty.
+// Refused calls are not logged, so a flood costs no writes.
+func (h *ShopHandler) allowShop(c *gin.Context, m *model.Shop) bool {
+	ok, wait := h.takeShop(c, m)
+	if !ok {
+		middleware.AbortThrottled(c, wait)
+	}
+	return ok
+}
+
+// takeShop takes a token from the shop's bucket and reports how long to wait
+// when it is empty. A failing store lets the call through.
+func (h *ShopHandler) takeShop(c *gin.Context, m *model.Shop) (bool, time.Duration) {
+	if h.limits == nil || !h.shopLimit.Enabled() {
+		return true, 0
+	}
+	ok, wait, err := h.limits.Take(c.Request.Context(), "check:shop:"+m.UUID, h.shopLimit)
+	if err != nil {
+		log.Printf("rate limit store: %v", err)
+		return true, 0
+	}
+	return ok, wait
+}
+
+// checkResult builds one /shops/check answer; m is nil when nothing matched
+func (h *ShopHandler) checkResult(m *model.Shop, shopUUID, domain, nonce, clientIP string, now time.Time) gin.H {
+	if m == nil {
+		resp := gin.H{"status": "not_found"}
+		h.sign(resp, checkPayload{ShopUUID: shopUUID, Domain: domain, Status: "not_found", Nonce: nonce}, now)
+		return resp
+	}
+	// outside the shop's allowlist: reveal nothing about the shop beyond the verdict
+	allowed, err := h.check.IPAllowed(m.ID, clientIP)
+	if err != nil {
+		log.Printf("ip allowlist: %v", err)
+	}
+	if !allowed {
+		resp := gin.H{"status": "ip_not_allowed", "client_ip": clientIP}
+		h.sign(resp, checkPayload{ShopUUID: shopUUID, Domain: domain, Status: "ip_not_allowed", Nonce: nonce}, now)
+		return resp
+	}
 	var expiredAt *time.Time = m.ExpiredAt
 	unlimited := expiredAt == nil
-	valid := m.Active && (unlimited || !expiredAt.Before(now))
+	// status stays "valid" through the grace period so older SDKs keep the
+	// site up; newer ones read state to show a warning banner
+	state, graceUntil := h.check.State(m, now)
+	valid := state != model.ShopStateSuspended
 	var daysRemaining *int
 	if !unlimited {
 		d := int(expiredAt.Sub(now).Hours() / 24)
@@ -445,61 +800,219 @@ func (h *ShopHandler) CheckStatus(c *gin.Context) {
 				return "expired"
 			}
 		}(),
-		"active":     m.Active,
-		"unlimited":  unlimited,
-		"expired_at": m.ExpiredAt,
-		"now":        now,
-		"shop_uuid":  m.UUID,
-		"domain":     m.Domain,
+		"state":       state,
+		"active":      m.Active,
+		"unlimited":   unlimited,
+		"expired_at":  m.ExpiredAt,
+		"grace_until": graceUntil,
+		"now":         now,
+		"shop_uuid":   m.UUID,
+		"domain":      m.Domain,
 	}
 	if daysRemaining != nil {
 		resp["days_remaining"] = *daysRemaining
 	}
-	c.JSON(http.StatusOK, resp)
+	var message string
+	if !m.Active {
+		message = m.Notice
+		resp["message"] = message
+	}
+	h.sign(resp, checkPayload{ShopUUID: m.UUID, Domain: m.Domain, Status: resp["status"].(string), State: state,
+		ExpiredAt: m.ExpiredAt, GraceUntil: graceUntil, Message: message, Nonce: nonce}, now)
+	return resp
+}
+
+// checkETag is a weak validator over the answer minus the fields that change
+// on every call (now, signature), so an unchanged shop revalidates with 304.
+// The nonce is included: a cached body only carries a valid signature for
+// the nonce it was issued for.
+func checkETag(resp gin.H, nonce string) string {
+	stable := make(gin.H, len(resp)+1)
+	stable["nonce"] = nonce
+	for k, v := range resp {
+		if k != "now" && k != "signature" {
+			stable[k] = v
+		}
+	}
+	b, _ := json.Marshal(stable)
+	sum := sha256.Sum256(b)
+	return `W/"` + hex.EncodeToString(sum[:12]) + `"`
+}
+
+// etagMatches implements the weak comparison of If-None-Match
+func etagMatches(header, etag string) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// checkLog is the API log row for one check; empty params are stored as NULL
+func checkLog(m *model.Shop, shopUUID, domain string, resp gin.H, ip, userAgent string, now time.Time) *model.ShopAPILog {
+	// cut client-supplied values to their column widths: one oversized row
+	// would fail the whole batch insert on strict MySQL
+	optional := func(v string) *string {
+		if v == "" {
+			return nil
+		}
+		v = clip(v, 255)
+		return &v
+	}
+	rec := &model.ShopAPILog{
+		DomainParam: optional(domain),
+		UUIDParam:   optional(shopUUID),
+		ClientIP:    clip(ip, 64),
+		UserAgent:   clip(userAgent, 500),
+		Status:      "unknown",
+		CreatedAt:   now,
+	}
+	if m != nil {
+		rec.ShopID, rec.ShopUUID = m.ID, m.UUID
+	}
+	if s, ok := resp["status"].(string); ok {
+		rec.Status = s
+	}
+	return rec
+}
 
-	// log call (best-effort)
-	if h.apiLogs != nil {
-		go func() {
-			domainPtr := func() *string {
-				if domain == "" {
-					return nil
-				}
-				v := domain
-				return &v
-			}()
-			uuidPtr := func() *string {
-				if shopUUID == "" {
-					return nil
-				}
-				v := shopUUID
-				return &v
-			}()
-			_ = h.apiLogs.Create(&model.ShopAPILog{
-				ShopID: func() uint {
-					if m != nil {
-						return m.ID
-					}
-					return 0
-				}(),
-				ShopUUID: func() string {
-					if m != nil {
-						return m.UUID
-					}
-					return ""
-				}(),
-				DomainParam: domainPtr,
-				UUIDParam:   uuidPtr,
-				ClientIP:    c.ClientIP(),
-				UserAgent:   c.GetHeader("User-Agent"),
-				Status: func() string {
-					if s, ok := resp["status"].(string); ok {
-						return s
-					}
-					return "unknown"
-				}(),
-			})
-		}()
+// clip keeps the first n characters of s, as a VARCHAR(n) column counts them
+func clip(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	i := 0
+	for j := range s {
+		if i == n {
+			return s[:j]
+		}
+		i++
 	}
+	return s
+}
+
+// maxBatchCheck caps the items of one /shops/check/batch request
+const maxBatchCheck = 500
+
+// CheckBatch POST /shops/check/batch { items: [{shop_uuid|domain}], nonce? }
+// Answers many checks at once: results[i] has the CheckStatus shape for
+// items[i] and echoes the item's query. Shops are resolved in one query and
+// the calls are logged in one bulk insert. Every resolved item takes a token
+// from its shop's bucket like a single check; an item refused that way is
+// answered {status: rate_limited, retry_after} and not logged.
+func (h *ShopHandler) CheckBatch(c *gin.Context) {
+	var req struct {
+		Items []struct {
+			ShopUUID string `json:"shop_uuid"`
+			Domain   string `json:"domain"`
+		} `json:"items" binding:"required"`
+		Nonce string `json:"nonce"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.Items) == 0 || len(req.Items) > maxBatchCheck {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("items must contain 1 to %d entries", maxBatchCheck)})
+		return
+	}
+	if !noncePattern.MatchString(req.Nonce) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "nonce must be at most 128 characters of [A-Za-z0-9_-]"})
+		return
+	}
+	var uuids, domains []string
+	for i, it := range req.Items {
+		switch {
+		case it.ShopUUID != "":
+			uuids = append(uuids, it.ShopUUID)
+		case it.Domain != "":
+			domains = append(domains, it.Domain)
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("items[%d]: shop_uuid or domain required", i)})
+			return
+		}
+	}
+	byUUID, byDomain, err := h.check.FindBatch(uuids, domains)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	now := time.Now()
+	ip, ua := c.ClientIP(), c.GetHeader("User-Agent")
+	results := make([]gin.H, len(req.Items))
+	for i, it := range req.Items {
+		var m *model.Shop
+		if it.ShopUUID != "" {
+			m = byUUID[it.ShopUUID]
+		} else {
+			m = byDomain[it.Domain]
+		}
+		if m != nil {
+			if ok, wait := h.takeShop(c, m); !ok {
+				results[i] = gin.H{"status": "rate_limited", "retry_after": middleware.RetryAfter(wait),
+					"query": gin.H{"shop_uuid": it.ShopUUID, "domain": it.Domain}}
+				continue
+			}
+		}
+		results[i] = h.checkResult(m, it.ShopUUID, it.Domain, req.Nonce, ip, now)
+		results[i]["query"] = gin.H{"shop_uuid": it.ShopUUID, "domain": it.Domain}
+		if h.logWriter != nil {
+			h.logWriter.Write(*checkLog(m, it.ShopUUID, it.Domain, results[i], ip, ua, now))
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+var noncePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{0,128}$`)
+
+// checkPayload is the signed part of a check response. The struct fixes the
+// field order, so its JSON encoding is canonical; clients verify the signature
+// over the exact payload bytes and then read the fields from them.
+type checkPayload struct {
+	ShopUUID   string     `json:"shop_uuid"`
+	Domain     string     `json:"domain"`
+	Status     string     `json:"status"`
+	State      string     `json:"state,omitempty"`
+	ExpiredAt  *time.Time `json:"expired_at"`
+	GraceUntil *time.Time `json:"grace_until"`
+	Message    string     `json:"message,omitempty"`
+	IssuedAt   time.Time  `json:"issued_at"`
+	Nonce      string     `json:"nonce"`
+}
+
+// sign adds {kid, alg, payload, sig} (base64url) to resp when a signer is set
+func (h *ShopHandler) sign(resp gin.H, p checkPayload, now time.Time) {
+	if h.signer == nil {
+		return
+	}
+	p.IssuedAt = now.UTC().Truncate(time.Second)
+	p.ExpiredAt, p.GraceUntil = utcSeconds(p.ExpiredAt), utcSeconds(p.GraceUntil)
+	env, err := h.signer.Seal(p)
+	if err != nil {
+		return
+	}
+	resp["signature"] = env
+}
+
+func utcSeconds(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	v := t.UTC().Truncate(time.Second)
+	return &v
+}
+
+// CheckKeys GET /shops/check/keys
+// Publishes the public keys that verify check signatures, active key first.
+func (h *ShopHandler) CheckKeys(c *gin.Context) {
+	if h.signer == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "response signing not configured"})
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.JSON(http.StatusOK, gin.H{"keys": h.signer.PublicKeys()})
 }
 
 // ListAPILogs GET /shops/:id/api-logs
@@ -529,6 +1042,34 @@ func (h *ShopHandler) ListAPILogs(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"items": items, "page": page, "limit": limit, "total": total})
 }
 
+// APILogStats GET /api-logs/stats reports the log pipeline counters
+func (h *ShopHandler) APILogStats(c *gin.Context) {
+	if h.logWriter == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "api log writer not configured"})
+		return
+	}
+	c.JSON(http.StatusOK, h.logWriter.Stats())
+}
+
+// PruneAPILogs POST /api-logs/prune
+// Runs the retention job now: rolls up old rows into daily totals, then deletes them.
+func (h *ShopHandler) PruneAPILogs(c *gin.Context) {
+	if h.retention == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "api log retention not configured"})
+		return
+	}
+	rep, err := h.retention.Run(c.Request.Context())
+	if errors.Is(err, service.ErrRetentionRunning) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "report": rep})
+		return
+	}
+	c.JSON(http.StatusOK, rep)
+}
+
 // ListAllAPILogs GET /api-logs
 func (h *ShopHandler) ListAllAPILogs(c *gin.Context) {
 	page := 1
@@ -560,30 +1101,220 @@ func (h *ShopHandler) ListAllAPILogs(c *gin.Context) {
 		status = &[]string{v}[0]
 	}
 	// optional time range filters: from, to
-	// from/to accept either RFC3339 or YYYY-MM-DD; to is exclusive end boundary if date-only provided
-	var fromTime *time.Time
-	var toTime *time.Time
-	if v := c.Query("from"); v != "" {
-		if t, err := time.Parse(time.RFC3339, v); err == nil {
-			fromTime = &t
-		} else if d, err := time.Parse("2006-01-02", v); err == nil {
-			t := time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.UTC)
-			fromTime = &t
+	fromTime, toTime := parseTimeRange(c)
+	items, total, err := h.apiLogs.ListAllPaged(page, limit, domainParam, uuidParam, status, fromTime, toTime)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items, "page": page, "limit": limit, "total": total})
+}
+
+// analyticsDefaultRange applies when ?from= is missing; hourly series are capped at analyticsMaxHourly
+const (
+	analyticsDefaultRange = 7 * 24 * time.Hour
+	analyticsMaxHourly    = 31 * 24 * time.Hour
+	analyticsMaxTop       = 100
+)
+
+// APILogAnalytics GET /api-logs/analytics
+func (h *ShopHandler) APILogAnalytics(c *gin.Context) {
+	h.apiLogAnalytics(c, nil)
+}
+
+// ShopAPILogAnalytics GET /shops/:id/api-logs/analytics
+func (h *ShopHandler) ShopAPILogAnalytics(c *gin.Context) {
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	id := uint(id64)
+	h.apiLogAnalytics(c, &id)
+}
+
+// apiLogAnalytics answers both analytics endpoints.
+// Query: from, to (same as /api-logs), bucket=hour|day (default day), top=N (default 10).
+func (h *ShopHandler) apiLogAnalytics(c *gin.Context, shopID *uint) {
+	if h.apiLogs == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "api logs repository not configured"})
+		return
+	}
+	bucket := c.DefaultQuery("bucket", "day")
+	if bucket != "hour" && bucket != "day" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bucket must be hour or day"})
+		return
+	}
+	top := 10
+	if v := c.Query("top"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			top = n
 		}
 	}
-	if v := c.Query("to"); v != "" {
-		if t, err := time.Parse(time.RFC3339, v); err == nil {
-			toTime = &t
-		} else if d, err := time.Parse("2006-01-02", v); err == nil {
-			// exclusive next day start (UTC)
-			t := time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.UTC).Add(24 * time.Hour)
-			toTime = &t
+	if top > analyticsMaxTop {
+		top = analyticsMaxTop
+	}
+	fromTime, toTime := parseTimeRange(c)
+	if toTime == nil {
+		now := time.Now()
+		toTime = &now
+	}
+	if fromTime == nil {
+		from := toTime.Add(-analyticsDefaultRange)
+		fromTime = &from
+	}
+	if !fromTime.Before(*toTime) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return
+	}
+	if bucket == "hour" && toTime.Sub(*fromTime) > analyticsMaxHourly {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "hourly range is limited to 31 days"})
+		return
+	}
+	f := repository.APILogRange{ShopID: shopID, From: fromTime, To: toTime}
+
+	series, err := h.apiLogs.Series(f, bucket)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	totals, err := h.apiLogs.Totals(f)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	resp := gin.H{
+		"from":   fromTime.UTC(),
+		"to":     toTime.UTC(),
+		"bucket": bucket,
+		"series": series,
+		"totals": totals,
+	}
+	tops := []struct{ key, column, status string }{
+		{"top_domains", "domain_param", ""},
+		{"top_uuids", "uuid_param", ""},
+		{"top_not_found_domains", "domain_param", "not_found"},
+		{"top_not_found_uuids", "uuid_param", "not_found"},
+		{"top_user_agents", "user_agent", ""},
+	}
+	for _, t := range tops {
+		rows, err := h.apiLogs.Top(f, t.column, t.status, top)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
 		}
+		resp[t.key] = rows
 	}
-	items, total, err := h.apiLogs.ListAllPaged(page, limit, domainParam, uuidParam, status, fromTime, toTime)
+	limit := top
+	if shopID != nil {
+		limit = 1
+	}
+	shops, err := h.apiLogs.ShopActivity(f, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if shopID != nil {
+		var activity *repository.APILogShopActivity
+		if len(shops) > 0 {
+			activity = &shops[0]
+		}
+		resp["activity"] = activity
+	} else {
+		resp["shops"] = shops
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// ListAbuseFindings GET /abuse-findings?shop_id=&kind=&status=
+func (h *ShopHandler) ListAbuseFindings(c *gin.Context) {
+	var shopID *uint
+	if v := c.Query("shop_id"); v != "" {
+		id64, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid shop_id"})
+			return
+		}
+		id := uint(id64)
+		shopID = &id
+	}
+	h.listAbuseFindings(c, shopID)
+}
+
+// ListShopAbuseFindings GET /shops/:id/abuse-findings
+func (h *ShopHandler) ListShopAbuseFindings(c *gin.Context) {
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	id := uint(id64)
+	h.listAbuseFindings(c, &id)
+}
+
+func (h *ShopHandler) listAbuseFindings(c *gin.Context, shopID *uint) {
+	if h.abuse == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "abuse detector not configured"})
+		return
+	}
+	page, limit := parsePaging(c, 200)
+	items, total, err := h.abuse.List(page, limit, shopID, c.Query("kind"), c.Query("status"))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"items": items, "page": page, "limit": limit, "total": total})
 }
+
+// ReviewAbuseFinding POST /abuse-findings/:id/review { status, note }
+func (h *ShopHandler) ReviewAbuseFinding(c *gin.Context) {
+	if h.abuse == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "abuse detector not configured"})
+		return
+	}
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	var req struct {
+		Status string `json:"status" binding:"required"`
+		Note   string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	f, err := h.abuse.Review(actorFrom(c), uint(id64), req.Status, req.Note)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "finding not found"})
+		return
+	}
+	if errors.Is(err, service.ErrAbuseStatus) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, f)
+}
+
+// ScanAbuse POST /abuse-findings/scan runs the detector now
+func (h *ShopHandler) ScanAbuse(c *gin.Context) {
+	if h.abuse == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "abuse detector not configured"})
+		return
+	}
+	rep, err := h.abuse.Run(c.Request.Context())
+	if errors.Is(err, service.ErrAbuseScanRunning) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "report": rep})
+		return
+	}
+	c.JSON(http.StatusOK, rep)
+}
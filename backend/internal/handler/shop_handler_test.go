@@ -0,0 +1,401 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"metronic/internal/model"
+	"metronic/internal/ratelimit"
+	"metronic/internal/service"
+)
+
+// stubChecker answers check lookups from fixed maps; domains are matched
+// case-insensitively to stand in for normalisation
+type stubChecker struct {
+	byUUID   map[string]*model.Shop
+	byDomain map[string]*model.Shop
+	denied   map[uint]bool // shops whose allowlist refuses every client
+	batches  [][2][]string // arguments of each FindBatch call
+}
+
+func (s *stubChecker) LookupForCheck(shopUUID, domain string) (*model.Shop, error) {
+	if m, ok := s.byUUID[shopUUID]; ok && shopUUID != "" {
+		return m, nil
+	}
+	if m, ok := s.byDomain[strings.ToLower(domain)]; ok && shopUUID == "" {
+		return m, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (s *stubChecker) FindBatch(uuids, domains []string) (map[string]*model.Shop, map[string]*model.Shop, error) {
+	s.batches = append(s.batches, [2][]string{uuids, domains})
+	byUUID, byDomain := map[string]*model.Shop{}, map[string]*model.Shop{}
+	for _, u := range uuids {
+		if m, ok := s.byUUID[u]; ok {
+			byUUID[u] = m
+		}
+	}
+	for _, d := range domains {
+		if m, ok := s.byDomain[strings.ToLower(d)]; ok {
+			byDomain[d] = m
+		}
+	}
+	return byUUID, byDomain, nil
+}
+
+func (s *stubChecker) IPAllowed(shopID uint, ip string) (bool, error) {
+	return !s.denied[shopID], nil
+}
+
+func (s *stubChecker) IPRestricted(shopID uint) bool {
+	return s.denied[shopID]
+}
+
+func (s *stubChecker) State(m *model.Shop, now time.Time) (string, *time.Time) {
+	return model.ExpiryPolicy{ExpiringSoonDays: 30}.State(m, now)
+}
+
+func newStubChecker(shops ...*model.Shop) *stubChecker {
+	s := &stubChecker{byUUID: map[string]*model.Shop{}, byDomain: map[string]*model.Shop{}, denied: map[uint]bool{}}
+	for _, m := range shops {
+		s.byUUID[m.UUID] = m
+		s.byDomain[m.Domain] = m
+	}
+	return s
+}
+
+func checkRouter(h *ShopHandler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/shops/check", h.CheckStatus)
+	r.POST("/shops/check/batch", h.CheckBatch)
+	return r
+}
+
+func postBatch(t *testing.T, r http.Handler, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	b, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/shops/check/batch", bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+type batchItem struct {
+	ShopUUID string `json:"shop_uuid,omitempty"`
+	Domain   string `json:"domain,omitempty"`
+}
+
+func TestCheckBatchKeepsRequestOrder(t *testing.T) {
+	exp := time.Now().AddDate(1, 0, 0)
+	a := &model.Shop{ID: 1, UUID: "11111111-1111-4111-8111-111111111111", Domain: "a.example", Active: true, ExpiredAt: &exp}
+	b := &model.Shop{ID: 2, UUID: "22222222-2222-4222-8222-222222222222", Domain: "b.example", Active: false, Notice: "paused"}
+	stub := newStubChecker(a, b)
+	h := &ShopHandler{check: stub}
+
+	items := []batchItem{
+		{Domain: "B.example"},
+		{ShopUUID: a.UUID},
+		{Domain: "missing.example"},
+		{ShopUUID: b.UUID, Domain: "a.example"}, // the UUID wins over the domain
+		{Domain: "a.example"},
+	}
+	w := postBatch(t, checkRouter(h), gin.H{"items": items, "nonce": "n1"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body.String())
+	}
+	var out struct {
+		Results []struct {
+			Status   string `json:"status"`
+			ShopUUID string `json:"shop_uuid"`
+			Message  string `json:"message"`
+			Query    struct {
+				ShopUUID string `json:"shop_uuid"`
+				Domain   string `json:"domain"`
+			} `json:"query"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	want := []struct{ status, uuid string }{
+		{"expired", b.UUID},
+		{"valid", a.UUID},
+		{"not_found", ""},
+		{"expired", b.UUID},
+		{"valid", a.UUID},
+	}
+	if len(out.Results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(out.Results), len(want))
+	}
+	for i, e := range want {
+		got := out.Results[i]
+		if got.Status != e.status || got.ShopUUID != e.uuid {
+			t.Fatalf("results[%d] = %s/%s, want %s/%s", i, got.Status, got.ShopUUID, e.status, e.uuid)
+		}
+		if got.Query.ShopUUID != items[i].ShopUUID || got.Query.Domain != items[i].Domain {
+			t.Fatalf("results[%d] echoes %+v, want %+v", i, got.Query, items[i])
+		}
+	}
+	if out.Results[0].Message != "paused" {
+		t.Fatalf("suspended shop message %q", out.Results[0].Message)
+	}
+	if len(stub.batches) != 1 {
+		t.Fatalf("FindBatch called %d times, want one lookup for the whole batch", len(stub.batches))
+	}
+	uuids, domains := stub.batches[0][0], stub.batches[0][1]
+	if fmt.Sprint(uuids) != fmt.Sprint([]string{a.UUID, b.UUID}) || fmt.Sprint(domains) != fmt.Sprint([]string{"B.example", "missing.example", "a.example"}) {
+		t.Fatalf("FindBatch(%v, %v)", uuids, domains)
+	}
+}
+
+func TestCheckBatchLimits(t *testing.T) {
+	h := &ShopHandler{check: newStubChecker()}
+	r := checkRouter(h)
+	items := func(n int) []batchItem {
+		out := make([]batchItem, n)
+		for i := range out {
+			out[i] = batchItem{Domain: fmt.Sprintf("s%d.example", i)}
+		}
+		return out
+	}
+	cases := []struct {
+		name string
+		body interface{}
+		code int
+	}{
+		{"empty", gin.H{"items": []batchItem{}}, http.StatusBadRequest},
+		{"at the limit", gin.H{"items": items(maxBatchCheck)}, http.StatusOK},
+		{"over the limit", gin.H{"items": items(maxBatchCheck + 1)}, http.StatusBadRequest},
+		{"item without a query", gin.H{"items": []batchItem{{Domain: "a.example"}, {}}}, http.StatusBadRequest},
+		{"bad nonce", gin.H{"items": items(1), "nonce": "has space"}, http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		if w := postBatch(t, r, tc.body); w.Code != tc.code {
+			t.Fatalf("%s: status %d, want %d: %s", tc.name, w.Code, tc.code, w.Body.String())
+		}
+	}
+}
+
+func TestCheckRateLimitsPerShop(t *testing.T) {
+	exp := time.Now().AddDate(1, 0, 0)
+	a := &model.Shop{ID: 1, UUID: "11111111-1111-4111-8111-111111111111", Domain: "a.example", Active: true, ExpiredAt: &exp}
+	b := &model.Shop{ID: 2, UUID: "22222222-2222-4222-8222-222222222222", Domain: "b.example", Active: true, ExpiredAt: &exp}
+	// one token a minute after a burst of two: nothing refills during the test
+	h := (&ShopHandler{check: newStubChecker(a, b)}).WithCheckRateLimit(ratelimit.NewMemory(), ratelimit.Limit{}, ratelimit.Limit{PerMin: 1, Burst: 2})
+	r := checkRouter(h)
+
+	check := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/shops/check?"+query, nil))
+		return w
+	}
+	// the same shop by UUID and by domain shares one bucket
+	for _, q := range []string{"shop_uuid=" + a.UUID, "domain=A.example"} {
+		if w := check(q); w.Code != http.StatusOK {
+			t.Fatalf("%s: status %d inside the burst", q, w.Code)
+		}
+	}
+	w := check("domain=a.example")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("third check: status %d, want 429", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "60" {
+		t.Fatalf("Retry-After = %q, want 60", got)
+	}
+	var body struct {
+		RetryAfter int `json:"retry_after"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.RetryAfter != 60 {
+		t.Fatalf("body %s", w.Body.String())
+	}
+	if w := check("domain=missing.example"); w.Code != http.StatusOK {
+		t.Fatalf("unknown domain: status %d; misses have no shop bucket", w.Code)
+	}
+
+	// a batch cannot bypass the bucket: a is empty, b has two tokens for three items
+	w = postBatch(t, r, gin.H{"items": []batchItem{{Domain: "b.example"}, {ShopUUID: a.UUID}, {Domain: "b.example"}, {ShopUUID: b.UUID}}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("batch status %d", w.Code)
+	}
+	var out struct {
+		Results []struct {
+			Status     string `json:"status"`
+			RetryAfter int    `json:"retry_after"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"valid", "rate_limited", "valid", "rate_limited"}
+	for i, s := range want {
+		if out.Results[i].Status != s {
+			t.Fatalf("results[%d].status = %q, want %q", i, out.Results[i].Status, s)
+		}
+		if s == "rate_limited" && out.Results[i].RetryAfter != 60 {
+			t.Fatalf("results[%d].retry_after = %d", i, out.Results[i].RetryAfter)
+		}
+	}
+}
+
+func TestCheckIPNotAllowed(t *testing.T) {
+	exp := time.Now().AddDate(1, 0, 0)
+	a := &model.Shop{ID: 1, UUID: "11111111-1111-4111-8111-111111111111", Domain: "a.example", Active: true, ExpiredAt: &exp, Notice: "secret"}
+	b := &model.Shop{ID: 2, UUID: "22222222-2222-4222-8222-222222222222", Domain: "b.example", Active: true, ExpiredAt: &exp}
+	stub := newStubChecker(a, b)
+	stub.denied[a.ID] = true
+	h := (&ShopHandler{check: stub}).WithCheckMaxAge(time.Minute)
+	r := checkRouter(h)
+
+	check := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/shops/check?"+query, nil)
+		req.RemoteAddr = "198.51.100.7:1234"
+		r.ServeHTTP(w, req)
+		return w
+	}
+	w := check("domain=a.example")
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body["status"] != "ip_not_allowed" || body["client_ip"] != "198.51.100.7" {
+		t.Fatalf("denied check: %s", w.Body.String())
+	}
+	for _, k := range []string{"shop_uuid", "expired_at", "message", "state"} {
+		if _, ok := body[k]; ok {
+			t.Fatalf("denied check leaks %q: %s", k, w.Body.String())
+		}
+	}
+	// the answer depends on the caller, so shared caches must not keep it
+	if got := w.Header().Get("Cache-Control"); got != "private, max-age=60" {
+		t.Fatalf("allowlisted shop Cache-Control = %q", got)
+	}
+	if got := check("domain=b.example").Header().Get("Cache-Control"); got != "public, max-age=60" {
+		t.Fatalf("open shop Cache-Control = %q", got)
+	}
+
+	w = postBatch(t, r, gin.H{"items": []batchItem{{Domain: "a.example"}, {Domain: "b.example"}}})
+	var out struct {
+		Results []struct {
+			Status   string `json:"status"`
+			ShopUUID string `json:"shop_uuid"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Results) != 2 || out.Results[0].Status != "ip_not_allowed" || out.Results[0].ShopUUID != "" || out.Results[1].Status != "valid" {
+		t.Fatalf("batch: %s", w.Body.String())
+	}
+}
+
+func TestCheckLogFitsColumns(t *testing.T) {
+	now := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	long := strings.Repeat("é", 300) + ".example"
+	ua := strings.Repeat("Mozilla ", 100)
+	rec := checkLog(nil, strings.Repeat("u", 300), long, gin.H{"status": "not_found"}, "203.0.113.1", ua, now)
+	if n := len([]rune(*rec.DomainParam)); n != 255 {
+		t.Fatalf("domain_param has %d characters, want 255", n)
+	}
+	if !strings.HasPrefix(long, *rec.DomainParam) {
+		t.Fatal("domain_param was not cut on a character boundary")
+	}
+	if n := len([]rune(*rec.UUIDParam)); n != 255 {
+		t.Fatalf("uuid_param has %d characters, want 255", n)
+	}
+	if len(rec.UserAgent) != 500 || rec.UserAgent != ua[:500] {
+		t.Fatalf("user_agent has %d characters, want 500", len(rec.UserAgent))
+	}
+	if !rec.CreatedAt.Equal(now) {
+		t.Fatalf("created_at %v, want the request time %v", rec.CreatedAt, now)
+	}
+	if short := checkLog(nil, "", "a.example", gin.H{}, "", "curl", now); short.UUIDParam != nil || *short.DomainParam != "a.example" || short.UserAgent != "curl" {
+		t.Fatalf("short values changed: %+v", short)
+	}
+}
+
+// slowShops is a service.ShopStore answering check lookups after delay, which
+// stands in for the MySQL round trip of the real repository
+type slowShops struct {
+	service.ShopStore
+	delay            time.Duration
+	byUUID, byDomain map[string]model.Shop
+}
+
+func (s *slowShops) FindByUUID(uuid string) (*model.Shop, error) {
+	time.Sleep(s.delay)
+	if m, ok := s.byUUID[uuid]; ok {
+		return &m, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (s *slowShops) FindByDomain(domain string) (*model.Shop, error) {
+	time.Sleep(s.delay)
+	if m, ok := s.byDomain[domain]; ok {
+		return &m, nil
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// slowSink is a service.APILogSink taking delay per batch insert
+type slowSink struct{ delay time.Duration }
+
+func (s slowSink) CreateBatch(recs []model.ShopAPILog) error {
+	time.Sleep(s.delay)
+	return nil
+}
+
+// go test ./internal/handler -run '^$' -bench CheckStatus
+// Full GET /shops/check requests against a store answering in 500µs, with the
+// calls logged through the batched writer, with and without the lookup cache.
+func BenchmarkCheckStatus(b *testing.B) {
+	const delay = 500 * time.Microsecond
+	exp := time.Now().AddDate(1, 0, 0)
+	store := &slowShops{delay: delay, byUUID: map[string]model.Shop{}, byDomain: map[string]model.Shop{}}
+	for i := 1; i <= 100; i++ {
+		m := model.Shop{ID: uint(i), UUID: fmt.Sprintf("00000000-0000-4000-8000-%012d", i), Domain: fmt.Sprintf("shop%d.example", i), Active: true, ExpiredAt: &exp}
+		store.byUUID[m.UUID], store.byDomain[m.Domain] = m, m
+	}
+	gin.SetMode(gin.TestMode)
+	for _, cached := range []bool{false, true} {
+		svc := service.NewShopService(store)
+		if cached {
+			svc.WithCheckCache(30*time.Second, 10*time.Second)
+		}
+		logs := service.NewAPILogWriter(slowSink{delay: 2 * time.Millisecond}, service.DefaultAPILogWriterConfig())
+		r := checkRouter(NewShopHandler(svc).WithAPILogWriter(logs))
+		for _, by := range []string{"uuid", "domain"} {
+			b.Run(fmt.Sprintf("%s/cached=%v", by, cached), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					q := fmt.Sprintf("shop_uuid=00000000-0000-4000-8000-%012d", i%100+1)
+					if by == "domain" {
+						q = fmt.Sprintf("domain=https://Shop%d.example/", i%100+1)
+					}
+					w := httptest.NewRecorder()
+					r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/shops/check?"+q, nil))
+					if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"valid"`) {
+						b.Fatalf("%s: %d %s", q, w.Code, w.Body.String())
+					}
+				}
+			})
+		}
+		logs.Close(context.Background())
+	}
+}
@@ -30,7 +30,7 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	u, err := h.svc.Create(req.Name, req.Email, req.Password)
+	u, err := h.svc.Create(actorFrom(c), req.Name, req.Email, req.Password)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -79,7 +79,7 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	u, err := h.svc.Update(uint(id), req.Name, req.Email, req.Password)
+	u, err := h.svc.Update(actorFrom(c), uint(id), req.Name, req.Email, req.Password)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -94,8 +94,7 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 		return
 	}
-	currentID := c.GetUint("userID")
-	if err := h.svc.Delete(uint(id), uint(currentID)); err != nil {
+	if err := h.svc.Delete(actorFrom(c), uint(id)); err != nil {
 		if errors.Is(err, service.ErrDeleteSelf) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		} else {
@@ -105,3 +104,17 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 	}
 	c.Status(http.StatusOK)
 }
+
+// UnlockUser POST /users/:id/unlock
+func (h *UserHandler) UnlockUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if err := h.svc.Unlock(actorFrom(c), uint(id)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusOK)
+}
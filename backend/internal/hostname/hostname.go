@@ -0,0 +1,79 @@
+// Package hostname turns the host strings that shop owners and SDKs send us
+// (URLs, Host headers with ports, Unicode IDNs) into one canonical form.
+package hostname
+
+import (
+	"errors"
+	"net"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// ErrInvalid is returned for input that does not contain a usable host name
+var ErrInvalid = errors.New("invalid domain")
+
+// Normalizer canonicalises host names. The zero value keeps "www." prefixes.
+type Normalizer struct {
+	// StripWWW treats www.example.com as example.com
+	StripWWW bool
+}
+
+// Normalize lowercases raw and strips scheme, credentials, path, query and
+// port, then converts IDNs to punycode. A leading "*." is kept so wildcard
+// entries normalise the same way:
+//
+//	"https://Shop.vn:8080/cart" -> "shop.vn"
+//	"cửahàng.vn"                -> "xn--cahng-tqa2694c.vn"
+func (n Normalizer) Normalize(raw string) (string, error) {
+	h := strings.TrimSpace(raw)
+	if i := strings.Index(h, "://"); i >= 0 {
+		h = h[i+3:]
+	}
+	if i := strings.IndexAny(h, "/?#"); i >= 0 {
+		h = h[:i]
+	}
+	if i := strings.LastIndex(h, "@"); i >= 0 {
+		h = h[i+1:]
+	}
+	h = stripPort(h)
+	h = strings.TrimSuffix(h, ".")
+	wildcard := strings.HasPrefix(h, "*.")
+	if wildcard {
+		h = h[2:]
+	}
+	if h == "" {
+		return "", ErrInvalid
+	}
+	ascii, err := idna.Lookup.ToASCII(h)
+	if err != nil || !validLabels(ascii) {
+		return "", ErrInvalid
+	}
+	if n.StripWWW && strings.HasPrefix(ascii, "www.") && strings.Contains(ascii[4:], ".") {
+		ascii = ascii[4:]
+	}
+	if wildcard {
+		return "*." + ascii, nil
+	}
+	return ascii, nil
+}
+
+// stripPort drops a trailing ":port"; bracketed IPv6 literals lose their brackets
+func stripPort(h string) string {
+	if !strings.Contains(h, ":") {
+		return h
+	}
+	if host, _, err := net.SplitHostPort(h); err == nil {
+		return host
+	}
+	return strings.Trim(h, "[]")
+}
+
+func validLabels(h string) bool {
+	for _, l := range strings.Split(h, ".") {
+		if l == "" || len(l) > 63 {
+			return false
+		}
+	}
+	return len(h) <= 253
+}
@@ -0,0 +1,47 @@
+package hostname
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	cases := map[string]string{
+		"shop.vn":                              "shop.vn",
+		"Shop.VN":                              "shop.vn",
+		" shop.vn/ ":                           "shop.vn",
+		"shop.vn.":                             "shop.vn",
+		"https://shop.vn":                      "shop.vn",
+		"http://user:pw@shop.vn:8080/cart?x=1": "shop.vn",
+		"shop.vn:443":                          "shop.vn",
+		"www.shop.vn":                          "www.shop.vn",
+		"*.Shop.vn":                            "*.shop.vn",
+		"cửahàng.vn":                           "xn--cahng-tqa2694c.vn",
+		"xn--cahng-tqa2694c.vn":                "xn--cahng-tqa2694c.vn",
+	}
+	for in, want := range cases {
+		got, err := Normalizer{}.Normalize(in)
+		if err != nil || got != want {
+			t.Errorf("Normalize(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+}
+
+func TestNormalizeStripWWW(t *testing.T) {
+	n := Normalizer{StripWWW: true}
+	for in, want := range map[string]string{
+		"www.shop.vn":         "shop.vn",
+		"https://WWW.shop.vn": "shop.vn",
+		"www.vn":              "www.vn",
+		"wwwshop.vn":          "wwwshop.vn",
+	} {
+		if got, err := n.Normalize(in); err != nil || got != want {
+			t.Errorf("Normalize(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+}
+
+func TestNormalizeRejectsGarbage(t *testing.T) {
+	for _, in := range []string{"", "   ", "https://", "*.", "shop_vn.com", "a..b"} {
+		if got, err := (Normalizer{}).Normalize(in); err == nil {
+			t.Errorf("Normalize(%q) = %q, want error", in, got)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package mailer
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is a plain-text email
+type Message struct {
+	To      []string
+	Subject string
+	Body    string
+}
+
+// Mailer sends email messages
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// SMTPMailer delivers mail through an SMTP relay.
+// net/smtp upgrades to STARTTLS automatically when the server offers it.
+type SMTPMailer struct {
+	Addr     string // host:port
+	Username string
+	Password string
+	From     string
+}
+
+func NewSMTPMailer(host string, port int, username, password, from string) *SMTPMailer {
+	return &SMTPMailer{Addr: fmt.Sprintf("%s:%d", host, port), Username: username, Password: password, From: from}
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		host := m.Addr
+		if i := strings.LastIndex(host, ":"); i >= 0 {
+			host = host[:i]
+		}
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+	sender, err := envelopeFrom(m.From)
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(m.Addr, auth, sender, msg.To, render(m.From, msg, time.Now()))
+}
+
+// envelopeFrom extracts the bare address for MAIL FROM; the From header keeps
+// the display name ("Subly <no-reply@example.com>")
+func envelopeFrom(from string) (string, error) {
+	addr, err := mail.ParseAddress(from)
+	if err != nil {
+		return "", fmt.Errorf("invalid MAIL_FROM %q: %w", from, err)
+	}
+	return addr.Address, nil
+}
+
+// LogMailer writes messages to a writer (stdout or a file) instead of sending
+// them. It is meant for development and tests.
+type LogMailer struct {
+	mu   sync.Mutex
+	w    io.Writer
+	From string
+}
+
+func NewLogMailer(w io.Writer, from string) *LogMailer {
+	return &LogMailer{w: w, From: from}
+}
+
+func (m *LogMailer) Send(msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := m.w.Write(render(m.From, msg, time.Now())); err != nil {
+		return err
+	}
+	_, err := io.WriteString(m.w, "\r\n.\r\n")
+	return err
+}
+
+// render builds an RFC 5322 message with UTF-8 headers and body
+func render(from string, msg Message, now time.Time) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + strings.Join(msg.To, ", ") + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("UTF-8", msg.Subject) + "\r\n")
+	b.WriteString("Date: " + now.Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
@@ -0,0 +1,44 @@
+package mailer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestLogMailerWritesMessage(t *testing.T) {
+	var buf bytes.Buffer
+	m := NewLogMailer(&buf, "noreply@example.com")
+	err := m.Send(Message{To: []string{"a@example.com"}, Subject: "Đặt lại mật khẩu", Body: "line1\nline2"})
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"From: noreply@example.com\r\n",
+		"To: a@example.com\r\n",
+		"Subject: =?UTF-8?q?",
+		"line1\r\nline2",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in output:\n%s", want, out)
+		}
+	}
+}
+
+func TestEnvelopeFromDropsDisplayName(t *testing.T) {
+	cases := map[string]string{
+		"Subly <no-reply@localhost>":     "no-reply@localhost",
+		"no-reply@example.com":           "no-reply@example.com",
+		`"Subly, Inc" <ops@example.com>`: "ops@example.com",
+	}
+	for in, want := range cases {
+		got, err := envelopeFrom(in)
+		if err != nil || got != want {
+			t.Fatalf("%q: got %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := envelopeFrom("not an address"); err == nil {
+		t.Fatal("expected an error for an invalid sender")
+	}
+}
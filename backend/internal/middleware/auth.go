@@ -11,7 +11,9 @@ import (
 	"metronic/internal/security"
 )
 
-// Auth verifies bearer token and loads user ID into context
+// Auth verifies bearer token and loads user ID into context.
+// Access tokens and API keys are accepted; API keys may also be sent in the
+// X-API-Key header, and their scopes are enforced by RequirePermission.
 func Auth(tokens domain.TokenRepository) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Allow preflight without auth
@@ -20,21 +22,25 @@ func Auth(tokens domain.TokenRepository) gin.HandlerFunc {
 			return
 		}
 
-		auth := c.GetHeader("Authorization")
-		if auth == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
-			return
+		tokenStr := strings.TrimSpace(c.GetHeader("X-API-Key"))
+		if tokenStr == "" {
+			auth := c.GetHeader("Authorization")
+			if auth == "" {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+				return
+			}
+			// Case-insensitive bearer support
+			parts := strings.SplitN(auth, " ", 2)
+			if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+				return
+			}
+			tokenStr = strings.TrimSpace(parts[1])
 		}
-		// Case-insensitive bearer support
-		parts := strings.SplitN(auth, " ", 2)
-		if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
-			return
-		}
-		tokenStr := strings.TrimSpace(parts[1])
 		hash := security.HashToken(tokenStr)
 		tok, err := tokens.FindByHash(hash)
-		if err != nil || tok.Revoked || tok.ExpiresAt.Before(time.Now()) {
+		// Refresh tokens and MFA challenges are only accepted by their own endpoints
+		if err != nil || tok.Revoked || tok.ExpiresAt.Before(time.Now()) || !bearerKind(tok.Kind) {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 			return
 		}
@@ -45,3 +51,8 @@ func Auth(tokens domain.TokenRepository) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// bearerKind reports whether a token kind may authenticate API requests
+func bearerKind(kind string) bool {
+	return kind == domain.TokenKindAccess || kind == domain.TokenKindAPIKey
+}
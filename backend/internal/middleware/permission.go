@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"metronic/internal/domain"
+	"metronic/internal/model"
+)
+
+// RequirePermission allows the request only if the authenticated user holds
+// every given permission code. It must run after Auth. Requests made with an
+// API key additionally need a key scope covering each code, so a key never
+// exceeds either its scopes or its owner's permissions.
+func RequirePermission(perms domain.PermissionRepository, codes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodOptions {
+			c.Next()
+			return
+		}
+		uid := c.GetUint("userID")
+		if uid == 0 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		if tok := currentToken(c); tok != nil && tok.Kind == domain.TokenKindAPIKey {
+			allowed := scopePermissions(tok.Scopes)
+			for _, code := range codes {
+				if !allowed[code] {
+					c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient scope", "permission": code})
+					return
+				}
+			}
+		}
+		granted, err := perms.PermissionCodesByUserID(uid)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		have := make(map[string]bool, len(granted))
+		for _, g := range granted {
+			have[g] = true
+		}
+		for _, code := range codes {
+			if !have[code] {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden", "permission": code})
+				return
+			}
+		}
+		c.Set("permissions", granted)
+		c.Next()
+	}
+}
+
+// SessionOnly rejects API keys on routes meant for interactive logins only
+// (profile, sessions, key management). It must run after Auth.
+func SessionOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if tok := currentToken(c); tok != nil && tok.Kind == domain.TokenKindAPIKey {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "not available for API keys"})
+			return
+		}
+		c.Next()
+	}
+}
+
+func currentToken(c *gin.Context) *domain.Token {
+	v, ok := c.Get("token")
+	if !ok {
+		return nil
+	}
+	tok, _ := v.(*domain.Token)
+	return tok
+}
+
+func scopePermissions(scopes string) map[string]bool {
+	out := map[string]bool{}
+	for _, s := range strings.Fields(scopes) {
+		if code, ok := model.APIKeyScopes[s]; ok {
+			out[code] = true
+		}
+	}
+	return out
+}
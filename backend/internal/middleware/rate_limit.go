@@ -1,11 +1,17 @@
 package middleware
 
 import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/time/rate"
+
+	"metronic/internal/ratelimit"
 )
 
 type visitor struct {
@@ -13,30 +19,53 @@ type visitor struct {
 	lastSeen time.Time
 }
 
-var (
-	visitors = make(map[string]*visitor)
-	mu       sync.Mutex
-)
+// RateLimiter keeps one token bucket per client IP. Idle buckets are swept
+// lazily while serving requests, so no background goroutine is needed.
+type RateLimiter struct {
+	mu        sync.Mutex
+	visitors  map[string]*visitor
+	perMin    int
+	idleTTL   time.Duration
+	lastSweep time.Time
+}
+
+// NewRateLimiter allows perMin requests per minute per IP (burst perMin)
+func NewRateLimiter(perMin int) *RateLimiter {
+	if perMin <= 0 {
+		perMin = 1
+	}
+	return &RateLimiter{
+		visitors: make(map[string]*visitor),
+		perMin:   perMin,
+		idleTTL:  5 * time.Minute,
+	}
+}
 
-func getLimiter(ip string, ratePerMin int) *rate.Limiter {
-	mu.Lock()
-	defer mu.Unlock()
-	v, exists := visitors[ip]
+func (l *RateLimiter) getLimiter(ip string, now time.Time) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.lastSweep) > time.Minute {
+		for k, v := range l.visitors {
+			if now.Sub(v.lastSeen) > l.idleTTL {
+				delete(l.visitors, k)
+			}
+		}
+		l.lastSweep = now
+	}
+	v, exists := l.visitors[ip]
 	if !exists {
-		limiter := rate.NewLimiter(rate.Every(time.Minute/time.Duration(ratePerMin)), ratePerMin)
-		visitors[ip] = &visitor{limiter: limiter, lastSeen: time.Now()}
+		limiter := rate.NewLimiter(rate.Every(time.Minute/time.Duration(l.perMin)), l.perMin)
+		l.visitors[ip] = &visitor{limiter: limiter, lastSeen: now}
 		return limiter
 	}
-	v.lastSeen = time.Now()
+	v.lastSeen = now
 	return v.limiter
 }
 
-// RateLimit limits number of requests per minute per IP
-func RateLimit(r int) gin.HandlerFunc {
-	go cleanupVisitors()
+// Handler returns the gin middleware enforcing this limiter
+func (l *RateLimiter) Handler() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		limiter := getLimiter(c.ClientIP(), r)
-		if !limiter.Allow() {
+		if !l.getLimiter(c.ClientIP(), time.Now()).Allow() {
 			c.AbortWithStatusJSON(429, gin.H{"error": "rate limit exceeded"})
 			return
 		}
@@ -44,15 +73,44 @@ func RateLimit(r int) gin.HandlerFunc {
 	}
 }
 
-func cleanupVisitors() {
-	for {
-		time.Sleep(time.Minute)
-		mu.Lock()
-		for ip, v := range visitors {
-			if time.Since(v.lastSeen) > 5*time.Minute {
-				delete(visitors, ip)
-			}
+// RateLimit limits number of requests per minute per IP.
+// Each call gets its own limiter state.
+func RateLimit(r int) gin.HandlerFunc {
+	return NewRateLimiter(r).Handler()
+}
+
+// Throttle limits requests per client IP with buckets keyed prefix+IP in
+// store. A failing store lets requests through rather than taking the
+// endpoint down.
+func Throttle(store ratelimit.Store, prefix string, l ratelimit.Limit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !l.Enabled() {
+			c.Next()
+			return
+		}
+		ok, wait, err := store.Take(c.Request.Context(), prefix+c.ClientIP(), l)
+		if err != nil {
+			log.Printf("rate limit store: %v", err)
+		} else if !ok {
+			AbortThrottled(c, wait)
+			return
 		}
-		mu.Unlock()
+		c.Next()
+	}
+}
+
+// RetryAfter rounds wait up to whole seconds, at least one
+func RetryAfter(wait time.Duration) int {
+	retry := int(math.Ceil(wait.Seconds()))
+	if retry < 1 {
+		retry = 1
 	}
+	return retry
+}
+
+// AbortThrottled answers 429 with Retry-After in whole seconds
+func AbortThrottled(c *gin.Context, wait time.Duration) {
+	retry := RetryAfter(wait)
+	c.Header("Retry-After", strconv.Itoa(retry))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded", "retry_after": retry})
 }
@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+// Abuse finding kinds
+const (
+    // AbuseDomainMismatch: the shop's UUID was checked from a domain that belongs to another shop
+    AbuseDomainMismatch = "domain_mismatch"
+    // AbuseUnknownDomain: the shop's UUID was checked from a domain no shop owns
+    AbuseUnknownDomain = "unknown_domain"
+    // AbuseIPSpread: the shop was checked from too many distinct IPs in the window
+    AbuseIPSpread = "ip_spread"
+)
+
+// Abuse finding review states
+const (
+    AbuseOpen      = "open"
+    AbuseConfirmed = "confirmed"
+    AbuseDismissed = "dismissed"
+)
+
+// AbuseFinding is one suspicious pattern in the check traffic of a shop.
+// Subject is the offending domain, empty for ip_spread; a pattern seen again
+// updates the same row, so a dismissed finding stays dismissed.
+type AbuseFinding struct {
+    ID          uint       `gorm:"primaryKey" json:"id"`
+    ShopID      uint       `gorm:"uniqueIndex:idx_abuse_subject,priority:1" json:"shop_id"`
+    Kind        string     `gorm:"size:32;uniqueIndex:idx_abuse_subject,priority:2" json:"kind"`
+    Subject     string     `gorm:"size:255;uniqueIndex:idx_abuse_subject,priority:3" json:"subject"`
+    Status      string     `gorm:"size:16;index;default:open" json:"status"`
+    Calls       int64      `json:"calls"`
+    DistinctIPs int64      `json:"distinct_ips"`
+    Evidence    JSON       `gorm:"type:json" json:"evidence"`
+    FirstSeen   time.Time  `json:"first_seen"`
+    LastSeen    time.Time  `json:"last_seen"`
+    Suspended   bool       `json:"suspended"`
+    ReviewedBy  *uint      `json:"reviewed_by,omitempty"`
+    ReviewedAt  *time.Time `json:"reviewed_at,omitempty"`
+    ReviewNote  string     `gorm:"size:500" json:"review_note,omitempty"`
+    CreatedAt   time.Time  `json:"created_at"`
+    UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func (AbuseFinding) TableName() string { return "abuse_findings" }
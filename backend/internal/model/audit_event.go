@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// Entity types recorded in the audit trail
+const (
+    AuditEntityShop     = "shop"
+    AuditEntityCustomer = "customer"
+    AuditEntityUser     = "user"
+    AuditEntityRole     = "role"
+    AuditEntityChannel  = "notification_channel"
+)
+
+// AuditEvent records one administrative change. Before/After hold only the
+// fields that changed (the full object on create/delete).
+type AuditEvent struct {
+    ID         uint      `gorm:"primaryKey" json:"id"`
+    ActorID    uint      `gorm:"index" json:"actor_id"`
+    Action     string    `gorm:"size:64;index" json:"action"`
+    EntityType string    `gorm:"size:32;index:idx_audit_entity,priority:1" json:"entity_type"`
+    EntityID   uint      `gorm:"index:idx_audit_entity,priority:2" json:"entity_id"`
+    Before     JSON      `gorm:"type:json" json:"before"`
+    After      JSON      `gorm:"type:json" json:"after"`
+    IP         string    `gorm:"size:64" json:"ip"`
+    UserAgent  string    `gorm:"size:255" json:"user_agent"`
+    CreatedAt  time.Time `gorm:"index" json:"created_at"`
+}
+
+func (AuditEvent) TableName() string { return "audit_events" }
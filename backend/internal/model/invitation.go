@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// Invitation lets an admin onboard a user by email with a pre-selected role
+type Invitation struct {
+    ID         uint       `gorm:"primaryKey" json:"id"`
+    Email      string     `gorm:"size:255;index" json:"email"`
+    RoleID     uint       `json:"role_id"`
+    Role       *Role      `gorm:"foreignKey:RoleID" json:"role,omitempty"`
+    InvitedBy  uint       `json:"invited_by"`
+    // NonceHash binds the currently valid link; resending replaces it
+    NonceHash  string     `gorm:"size:64" json:"-"`
+    ExpiresAt  time.Time  `json:"expires_at"`
+    AcceptedAt *time.Time `json:"accepted_at"`
+    RevokedAt  *time.Time `json:"revoked_at"`
+    CreatedAt  time.Time  `json:"created_at"`
+    UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+func (Invitation) TableName() string { return "invitations" }
@@ -0,0 +1,43 @@
+package model
+
+import (
+    "database/sql/driver"
+    "fmt"
+)
+
+// JSON holds a raw JSON document. It is stored as text (so MySQL JSON columns
+// accept it) and emitted as-is by encoding/json.
+type JSON []byte
+
+func (j JSON) Value() (driver.Value, error) {
+    if len(j) == 0 {
+        return nil, nil
+    }
+    return string(j), nil
+}
+
+func (j *JSON) Scan(src interface{}) error {
+    switch v := src.(type) {
+    case nil:
+        *j = nil
+    case []byte:
+        *j = append((*j)[:0], v...)
+    case string:
+        *j = JSON(v)
+    default:
+        return fmt.Errorf("model.JSON: cannot scan %T", src)
+    }
+    return nil
+}
+
+func (j JSON) MarshalJSON() ([]byte, error) {
+    if len(j) == 0 {
+        return []byte("null"), nil
+    }
+    return j, nil
+}
+
+func (j *JSON) UnmarshalJSON(b []byte) error {
+    *j = append((*j)[:0], b...)
+    return nil
+}
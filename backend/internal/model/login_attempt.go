@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// LoginAttempt records every login (and 2FA) attempt for throttling and review
+type LoginAttempt struct {
+    ID        uint      `gorm:"primaryKey" json:"id"`
+    UserID    *uint     `gorm:"index" json:"user_id,omitempty"`
+    Email     string    `gorm:"size:255;index:idx_login_attempts_email" json:"email"`
+    IP        string    `gorm:"size:64;index:idx_login_attempts_ip" json:"ip"`
+    UserAgent string    `gorm:"size:255" json:"user_agent"`
+    Success   bool      `json:"success"`
+    // Reason is set for failures and markers: invalid_credentials, mfa, locked, throttled, admin_unlock
+    Reason    string    `gorm:"size:32" json:"reason,omitempty"`
+    CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+func (LoginAttempt) TableName() string { return "login_attempts" }
@@ -0,0 +1,75 @@
+package model
+
+import (
+    "strings"
+    "time"
+
+    "gorm.io/gorm"
+)
+
+// Notification events a channel can subscribe to
+const (
+    NotifyExpiryReport = "shops.expiry_report"
+    NotifyAbuseFinding = "abuse.finding"
+    NotifyTest         = "test"
+)
+
+// NotificationEvents lists every event channels can subscribe to
+var NotificationEvents = []string{NotifyExpiryReport, NotifyAbuseFinding}
+
+// NotificationChannel is one configured destination (Slack, Telegram...).
+// Config holds the kind's settings, secrets included, and is never
+// serialised as is; Settings carries the masked copy shown by the API.
+// Events is a comma-separated subscription list, empty meaning all events;
+// the API reads and writes it as the EventList array.
+type NotificationChannel struct {
+    ID        uint              `gorm:"primaryKey" json:"id"`
+    Name      string            `gorm:"size:100" json:"name"`
+    Kind      string            `gorm:"size:16" json:"kind"`
+    Config    JSON              `gorm:"type:json" json:"-"`
+    Settings  map[string]string `gorm:"-" json:"config"`
+    Events    string            `gorm:"size:255" json:"-"`
+    EventList []string          `gorm:"-" json:"events"`
+    Enabled   bool              `json:"enabled"`
+    CreatedAt time.Time         `json:"created_at"`
+    UpdatedAt time.Time         `json:"updated_at"`
+}
+
+func (NotificationChannel) TableName() string { return "notification_channels" }
+
+// SetEvents stores events as the subscription list
+func (c *NotificationChannel) SetEvents(events []string) {
+    c.Events = strings.Join(events, ",")
+    c.EventList = append([]string{}, events...)
+}
+
+// AfterFind splits the stored subscription list into EventList
+func (c *NotificationChannel) AfterFind(tx *gorm.DB) error {
+    c.EventList = []string{}
+    for _, e := range strings.Split(c.Events, ",") {
+        if e = strings.TrimSpace(e); e != "" {
+            c.EventList = append(c.EventList, e)
+        }
+    }
+    return nil
+}
+
+// Delivery outcomes
+const (
+    DeliverySent   = "sent"
+    DeliveryFailed = "failed"
+)
+
+// NotificationDelivery records one send attempt to one channel
+type NotificationDelivery struct {
+    ID         uint      `gorm:"primaryKey" json:"id"`
+    ChannelID  uint      `gorm:"index" json:"channel_id"`
+    Event      string    `gorm:"size:64;index" json:"event"`
+    Subject    string    `gorm:"size:255" json:"subject"`
+    Status     string    `gorm:"size:16;index" json:"status"`
+    Error      string    `gorm:"size:500" json:"error,omitempty"`
+    DurationMs int64     `json:"duration_ms"`
+    CreatedAt  time.Time `gorm:"index" json:"created_at"`
+}
+
+func (NotificationDelivery) TableName() string { return "notification_deliveries" }
@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// RateLimitBucket is the shared token bucket state of one rate limit key
+type RateLimitBucket struct {
+    Key        string    `gorm:"primaryKey;size:191"`
+    Tokens     float64
+    RefilledAt time.Time `gorm:"type:datetime(6);index"`
+}
+
+func (RateLimitBucket) TableName() string { return "rate_limit_buckets" }
@@ -16,3 +16,50 @@ type Permission struct {
     Name string `gorm:"size:255" json:"name"`
 }
 
+// AdminRoleName is the built-in role that always holds every permission
+const AdminRoleName = "admin"
+
+// Permission codes checked by middleware.RequirePermission
+const (
+    PermShopsView       = "shops.view"
+    PermShopsManage     = "shops.manage"
+    PermShopsDelete     = "shops.delete"
+    PermShopsRenew      = "shops.renew"
+    PermAPILogsView     = "api_logs.view"
+    PermAPILogsManage   = "api_logs.manage"
+    PermCustomersView   = "customers.view"
+    PermCustomersManage = "customers.manage"
+    PermUsersManage     = "users.manage"
+    PermRolesManage     = "roles.manage"
+    PermAuditView       = "audit.view"
+    PermNotifyManage    = "notifications.manage"
+)
+
+// BuiltinPermissions lists every permission known to the backend.
+// Seed keeps the permissions table in sync with this list.
+var BuiltinPermissions = []Permission{
+    {Code: PermShopsView, Name: "View shops"},
+    {Code: PermShopsManage, Name: "Create, update and restore shops"},
+    {Code: PermShopsDelete, Name: "Delete shops (soft and force)"},
+    {Code: PermShopsRenew, Name: "Renew, revoke and set shop expiry"},
+    {Code: PermAPILogsView, Name: "View shop API logs"},
+    {Code: PermAPILogsManage, Name: "Prune and roll up shop API logs"},
+    {Code: PermCustomersView, Name: "View customers"},
+    {Code: PermCustomersManage, Name: "Delete customers and assign them to shops"},
+    {Code: PermUsersManage, Name: "Manage users"},
+    {Code: PermRolesManage, Name: "Manage roles and permissions"},
+    {Code: PermAuditView, Name: "View the audit trail"},
+    {Code: PermNotifyManage, Name: "Manage notification channels"},
+}
+
+// APIKeyScopes maps each API key scope to the permission it unlocks.
+// User and role administration are intentionally not grantable to keys.
+var APIKeyScopes = map[string]string{
+    "shops:read":      PermShopsView,
+    "shops:write":     PermShopsManage,
+    "shops:delete":    PermShopsDelete,
+    "shops:renew":     PermShopsRenew,
+    "api_logs:read":   PermAPILogsView,
+    "customers:read":  PermCustomersView,
+    "customers:write": PermCustomersManage,
+}
@@ -18,6 +18,11 @@ type Shop struct {
 	ExpiredAt     *time.Time     `json:"expired_at"`
 	PricePerCycle int            `gorm:"default:2000000" json:"price_per_cycle"`
 	CycleMonths   int            `gorm:"default:12" json:"cycle_months"`
+	GraceDays     *int           `json:"grace_days"`  // overrides the global grace period when set
+	MaxDomains    *int           `json:"max_domains"` // overrides the global domain limit when set
+	SuspendedAt   *time.Time     `json:"suspended_at"`
+	VerifiedAt    *time.Time     `json:"verified_at"`
+	Notice        string         `gorm:"size:500" json:"notice,omitempty"` // customer-facing message while suspended
 	CreatedAt     *time.Time     `json:"-"`
 	UpdatedAt     *time.Time     `json:"-"`
 	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
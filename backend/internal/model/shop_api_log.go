@@ -14,7 +14,7 @@ type ShopAPILog struct {
     ClientIP     string    `gorm:"size:64" json:"client_ip"`
     UserAgent    string    `gorm:"size:500" json:"user_agent"`
     Status       string    `gorm:"size:32" json:"status"`
-    CreatedAt    time.Time `json:"created_at"`
+    CreatedAt    time.Time `gorm:"index" json:"created_at"`
 }
 
 func (ShopAPILog) TableName() string { return "shop_api_logs" }
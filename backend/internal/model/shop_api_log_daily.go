@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// ShopAPILogDaily aggregates shop_api_logs per shop, day and status. Raw rows
+// are rolled up here before retention deletes them, so long-range charts
+// survive pruning.
+type ShopAPILogDaily struct {
+    ID          uint      `gorm:"primaryKey" json:"id"`
+    ShopID      uint      `gorm:"uniqueIndex:idx_api_log_daily,priority:1" json:"shop_id"`
+    Day         time.Time `gorm:"type:date;uniqueIndex:idx_api_log_daily,priority:2;index" json:"day"`
+    Status      string    `gorm:"size:32;uniqueIndex:idx_api_log_daily,priority:3" json:"status"`
+    Calls       int64     `json:"calls"`
+    DistinctIPs int64     `json:"distinct_ips"`
+}
+
+func (ShopAPILogDaily) TableName() string { return "shop_api_log_daily" }
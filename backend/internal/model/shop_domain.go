@@ -0,0 +1,80 @@
+package model
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Shop domain kinds. Every shop has exactly one primary domain, mirrored from Shop.Domain.
+const (
+	ShopDomainPrimary = "primary"
+	ShopDomainAlias   = "alias"
+	ShopDomainStaging = "staging"
+)
+
+// ShopDomain is a host name that resolves to a shop. Domain is unique across
+// all shops; a leading "*." makes it a wildcard for every subdomain.
+type ShopDomain struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ShopID    uint      `gorm:"index" json:"shop_id"`
+	Domain    string    `gorm:"uniqueIndex;size:255" json:"domain"`
+	Kind      string    `gorm:"size:16;index" json:"kind"`
+	CreatedAt time.Time `json:"created_at"`
+	// Ownership verification: the token must be published in DNS or on the site
+	VerifyToken  string     `gorm:"size:64" json:"verify_token"`
+	VerifiedAt   *time.Time `gorm:"index" json:"verified_at"`
+	VerifyMethod string     `gorm:"size:8" json:"verify_method,omitempty"`
+	CheckedAt    *time.Time `json:"checked_at"`
+	CheckError   string     `gorm:"size:255" json:"check_error,omitempty"`
+}
+
+func (ShopDomain) TableName() string { return "shop_domains" }
+
+// BeforeCreate issues the verification token
+func (d *ShopDomain) BeforeCreate(tx *gorm.DB) error {
+	if d.VerifyToken == "" {
+		d.VerifyToken = NewVerifyToken()
+	}
+	return nil
+}
+
+// NewVerifyToken returns a random token for domain ownership checks
+func NewVerifyToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Wildcard reports whether d matches subdomains rather than one host
+func (d ShopDomain) Wildcard() bool { return strings.HasPrefix(d.Domain, "*.") }
+
+var domainPattern = regexp.MustCompile(`^(\*\.)?([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// ValidDomainPattern reports whether d is a lowercase host name, optionally
+// prefixed with "*.". Wildcards need at least two labels after the star so
+// "*.vn" cannot claim a whole TLD.
+func ValidDomainPattern(d string) bool {
+	if len(d) > 255 || !domainPattern.MatchString(d) {
+		return false
+	}
+	if rest, ok := strings.CutPrefix(d, "*."); ok {
+		return strings.Contains(rest, ".")
+	}
+	return true
+}
+
+// DomainWildcards lists the wildcard entries that would match host, most
+// specific first: a.b.shop.vn -> *.b.shop.vn, *.shop.vn
+func DomainWildcards(host string) []string {
+	var out []string
+	labels := strings.Split(host, ".")
+	for i := 1; len(labels)-i >= 2; i++ {
+		out = append(out, "*."+strings.Join(labels[i:], "."))
+	}
+	return out
+}
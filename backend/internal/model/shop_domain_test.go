@@ -0,0 +1,35 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDomainWildcards(t *testing.T) {
+	got := DomainWildcards("a.b.shop.vn")
+	want := []string{"*.b.shop.vn", "*.shop.vn"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if got := DomainWildcards("shop.vn"); len(got) != 0 {
+		t.Fatalf("apex must not match a wildcard, got %v", got)
+	}
+}
+
+func TestValidDomainPattern(t *testing.T) {
+	for d, want := range map[string]bool{
+		"shop.vn":       true,
+		"www.shop.vn":   true,
+		"*.shop.vn":     true,
+		"*.vn":          false,
+		"shop":          false,
+		"a.*.shop.vn":   false,
+		"Shop.vn":       false,
+		"-bad.shop.vn":  false,
+		"staging-1.a.b": true,
+	} {
+		if got := ValidDomainPattern(d); got != want {
+			t.Errorf("%q: got %v, want %v", d, got, want)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// ShopIPRange is one CIDR allowed to call /shops/check for a shop. A shop
+// without ranges may be checked from anywhere.
+type ShopIPRange struct {
+    ID        uint      `gorm:"primaryKey" json:"id"`
+    ShopID    uint      `gorm:"uniqueIndex:idx_shop_ip_range,priority:1" json:"shop_id"`
+    CIDR      string    `gorm:"size:64;uniqueIndex:idx_shop_ip_range,priority:2" json:"cidr"`
+    Note      string    `gorm:"size:255" json:"note,omitempty"`
+    CreatedBy uint      `json:"created_by"`
+    CreatedAt time.Time `json:"created_at"`
+}
+
+func (ShopIPRange) TableName() string { return "shop_ip_ranges" }
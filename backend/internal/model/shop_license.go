@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// ShopLicense is a signed, offline-verifiable license document issued for a shop.
+// A new row is issued whenever the shop's expiry changes; the newest one is current.
+type ShopLicense struct {
+    ID         uint       `gorm:"primaryKey" json:"id"`
+    ShopID     uint       `gorm:"index" json:"shop_id"`
+    Serial     uint       `json:"serial"`
+    KID        string     `gorm:"size:64" json:"kid"`
+    ExpiredAt  *time.Time `json:"expired_at"`
+    GraceUntil *time.Time `json:"grace_until"`
+    Document   string     `gorm:"type:text" json:"-"`
+    IssuedAt   time.Time  `json:"issued_at"`
+}
+
+func (ShopLicense) TableName() string { return "shop_licenses" }
@@ -0,0 +1,54 @@
+package model
+
+import "time"
+
+// Shop lifecycle states, in order
+const (
+	ShopStateActive       = "active"
+	ShopStateExpiringSoon = "expiring_soon"
+	ShopStateGrace        = "grace"
+	ShopStateSuspended    = "suspended"
+)
+
+// ShopStates lists every lifecycle state
+var ShopStates = []string{ShopStateActive, ShopStateExpiringSoon, ShopStateGrace, ShopStateSuspended}
+
+// ExpiryPolicy holds the windows that split shops into lifecycle states
+type ExpiryPolicy struct {
+	// GraceDays after ExpiredAt during which a shop keeps working; Shop.GraceDays overrides it
+	GraceDays int
+	// ExpiringSoonDays before ExpiredAt during which a shop is expiring_soon
+	ExpiringSoonDays int
+}
+
+// GraceDaysFor returns the grace period that applies to s
+func (p ExpiryPolicy) GraceDaysFor(s *Shop) int {
+	if s.GraceDays != nil {
+		return *s.GraceDays
+	}
+	return p.GraceDays
+}
+
+// State returns the lifecycle state of s at now and, for shops with an
+// expiry, the moment the grace period ends.
+func (p ExpiryPolicy) State(s *Shop, now time.Time) (string, *time.Time) {
+	if s.ExpiredAt == nil {
+		if !s.Active {
+			return ShopStateSuspended, nil
+		}
+		return ShopStateActive, nil
+	}
+	graceUntil := s.ExpiredAt.AddDate(0, 0, p.GraceDaysFor(s))
+	switch {
+	case !s.Active:
+		return ShopStateSuspended, &graceUntil
+	case now.After(graceUntil):
+		return ShopStateSuspended, &graceUntil
+	case now.After(*s.ExpiredAt):
+		return ShopStateGrace, &graceUntil
+	case !s.ExpiredAt.After(now.AddDate(0, 0, p.ExpiringSoonDays)):
+		return ShopStateExpiringSoon, &graceUntil
+	default:
+		return ShopStateActive, &graceUntil
+	}
+}
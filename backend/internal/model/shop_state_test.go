@@ -0,0 +1,34 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpiryPolicyState(t *testing.T) {
+	now := time.Date(2026, 5, 10, 12, 0, 0, 0, time.UTC)
+	at := func(days int) *time.Time { v := now.AddDate(0, 0, days); return &v }
+	five := 5
+	p := ExpiryPolicy{GraceDays: 7, ExpiringSoonDays: 30}
+	cases := []struct {
+		name string
+		shop Shop
+		want string
+	}{
+		{"unlimited", Shop{Active: true}, ShopStateActive},
+		{"far expiry", Shop{Active: true, ExpiredAt: at(60)}, ShopStateActive},
+		{"within soon window", Shop{Active: true, ExpiredAt: at(10)}, ShopStateExpiringSoon},
+		{"just expired", Shop{Active: true, ExpiredAt: at(-1)}, ShopStateGrace},
+		{"past grace", Shop{Active: true, ExpiredAt: at(-8)}, ShopStateSuspended},
+		{"shop override extends grace", Shop{Active: true, ExpiredAt: at(-8), GraceDays: intPtr(10)}, ShopStateGrace},
+		{"shop override shortens grace", Shop{Active: true, ExpiredAt: at(-6), GraceDays: &five}, ShopStateSuspended},
+		{"inactive", Shop{Active: false, ExpiredAt: at(60)}, ShopStateSuspended},
+	}
+	for _, tc := range cases {
+		if got, _ := p.State(&tc.shop, now); got != tc.want {
+			t.Errorf("%s: got %s, want %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func intPtr(v int) *int { return &v }
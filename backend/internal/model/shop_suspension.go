@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// Suspension history actions
+const (
+    ShopSuspend = "suspend"
+    ShopResume  = "resume"
+)
+
+// ShopSuspension records a manual suspend or resume of a shop
+type ShopSuspension struct {
+    ID          uint      `gorm:"primaryKey" json:"id"`
+    ShopID      uint      `gorm:"index" json:"shop_id"`
+    Action      string    `gorm:"size:16" json:"action"`
+    Reason      string    `gorm:"size:500" json:"reason"`
+    Message     string    `gorm:"size:500" json:"message,omitempty"`
+    PerformedBy uint      `json:"performed_by"`
+    CreatedAt   time.Time `json:"created_at"`
+}
+
+func (ShopSuspension) TableName() string { return "shop_suspensions" }
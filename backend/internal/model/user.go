@@ -9,6 +9,14 @@ type User struct {
     Email     string `gorm:"uniqueIndex;size:255" json:"email"`
     Password  string `json:"-"`
     Roles     []Role `gorm:"many2many:user_roles" json:"roles,omitempty"`
+    // TOTP two-factor state. The secret is set on setup and only trusted
+    // once TOTPEnabled is true (after the first code is confirmed).
+    TOTPSecret   *string `gorm:"size:64" json:"-"`
+    TOTPEnabled  bool    `gorm:"default:false" json:"totp_enabled"`
+    TOTPLastStep int64   `json:"-"`
+    // Lockout state maintained by the login guard
+    FailedLoginCount int        `gorm:"default:0" json:"-"`
+    LockedUntil      *time.Time `json:"locked_until,omitempty"`
     CreatedAt time.Time
     UpdatedAt time.Time
 }
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// UserRecoveryCode is a hashed one-time 2FA recovery code
+type UserRecoveryCode struct {
+    ID        uint       `gorm:"primaryKey" json:"id"`
+    UserID    uint       `gorm:"index" json:"user_id"`
+    CodeHash  string     `gorm:"size:64;index" json:"-"`
+    UsedAt    *time.Time `json:"used_at"`
+    CreatedAt time.Time  `json:"created_at"`
+}
+
+func (UserRecoveryCode) TableName() string { return "user_recovery_codes" }
@@ -0,0 +1,116 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"metronic/internal/mailer"
+)
+
+// Slack posts to an incoming webhook
+type Slack struct {
+	URL    string
+	Client *http.Client
+}
+
+func (s *Slack) Send(ctx context.Context, msg Message) error {
+	return postJSON(ctx, s.Client, s.URL, map[string]string{"text": msg.body()})
+}
+
+// discordMaxLen is the content limit of a Discord message
+const discordMaxLen = 2000
+
+// Discord posts to a channel webhook
+type Discord struct {
+	URL    string
+	Client *http.Client
+}
+
+func (d *Discord) Send(ctx context.Context, msg Message) error {
+	return postJSON(ctx, d.Client, d.URL, map[string]string{"content": truncateRunes(msg.body(), discordMaxLen)})
+}
+
+// telegramMaxLen is the text limit of a Telegram message
+const telegramMaxLen = 4096
+
+// Telegram sends through a bot to one chat. BaseURL defaults to the public
+// Bot API.
+type Telegram struct {
+	Token   string
+	ChatID  string
+	BaseURL string
+	Client  *http.Client
+}
+
+func (t *Telegram) Send(ctx context.Context, msg Message) error {
+	base := strings.TrimRight(t.BaseURL, "/")
+	if base == "" {
+		base = "https://api.telegram.org"
+	}
+	payload := map[string]interface{}{
+		"chat_id":                  t.ChatID,
+		"text":                     truncateRunes(msg.body(), telegramMaxLen),
+		"disable_web_page_preview": true,
+	}
+	err := postJSON(ctx, t.Client, base+"/bot"+t.Token+"/sendMessage", payload)
+	if err != nil && t.Token != "" {
+		// transport errors quote the URL, which embeds the bot token
+		return errors.New(strings.ReplaceAll(err.Error(), t.Token, "***"))
+	}
+	return err
+}
+
+// Email mails the message through the configured mailer
+type Email struct {
+	Mailer mailer.Mailer
+	To     []string
+}
+
+func (e *Email) Send(_ context.Context, msg Message) error {
+	subject := msg.Subject
+	if subject == "" {
+		subject = "Subly: " + msg.Event
+	}
+	return e.Mailer.Send(mailer.Message{To: e.To, Subject: subject, Body: msg.Text})
+}
+
+// Webhook posts {event, subject, text, sent_at} to any URL. With a Secret,
+// X-Subly-Signature carries "sha256=" + hex HMAC-SHA256 of the body.
+type Webhook struct {
+	URL    string
+	Secret string
+	Client *http.Client
+}
+
+type webhookPayload struct {
+	Event   string    `json:"event"`
+	Subject string    `json:"subject"`
+	Text    string    `json:"text"`
+	SentAt  time.Time `json:"sent_at"`
+	secret  string
+}
+
+func (p webhookPayload) sign(req *http.Request, body []byte) {
+	if p.secret == "" {
+		return
+	}
+	req.Header.Set("X-Subly-Signature", "sha256="+Signature(p.secret, body))
+}
+
+// Signature is the hex HMAC-SHA256 of body, as receivers should recompute it
+func Signature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (w *Webhook) Send(ctx context.Context, msg Message) error {
+	p := webhookPayload{Event: msg.Event, Subject: msg.Subject, Text: msg.Text, SentAt: time.Now().UTC(), secret: w.Secret}
+	return postJSON(ctx, w.Client, w.URL, p)
+}
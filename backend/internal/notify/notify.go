@@ -0,0 +1,154 @@
+// Package notify delivers operator notifications to chat, email and webhook
+// channels behind one Notifier interface.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"metronic/internal/mailer"
+)
+
+// Channel kinds
+const (
+	KindSlack    = "slack"
+	KindTelegram = "telegram"
+	KindDiscord  = "discord"
+	KindEmail    = "email"
+	KindWebhook  = "webhook"
+)
+
+// Kinds lists every supported channel kind
+var Kinds = []string{KindSlack, KindTelegram, KindDiscord, KindEmail, KindWebhook}
+
+// Message is one notification. Chat channels send Subject as the first line.
+type Message struct {
+	Event   string
+	Subject string
+	Text    string
+}
+
+// body is the subject and text as one block of plain text
+func (m Message) body() string {
+	if m.Subject == "" {
+		return m.Text
+	}
+	return m.Subject + "\n" + m.Text
+}
+
+// Notifier sends a message to one channel
+type Notifier interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Config is the per-channel settings map stored with the channel
+type Config map[string]string
+
+func (c Config) require(keys ...string) error {
+	for _, k := range keys {
+		if strings.TrimSpace(c[k]) == "" {
+			return fmt.Errorf("%s is required", k)
+		}
+	}
+	return nil
+}
+
+// SecretKeys are config keys whose values are masked when shown back
+var SecretKeys = map[string]bool{"url": true, "token": true, "secret": true}
+
+// EndpointKeys are config keys naming the host a channel sends to; changing
+// one requires the channel's secrets to be entered again
+var EndpointKeys = map[string]bool{"url": true, "base_url": true}
+
+// New builds the notifier for a channel of kind. mail is used by email
+// channels; client defaults to a 10s-timeout http.Client.
+func New(kind string, cfg Config, mail mailer.Mailer, client *http.Client) (Notifier, error) {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	switch kind {
+	case KindSlack:
+		if err := cfg.require("url"); err != nil {
+			return nil, err
+		}
+		return &Slack{URL: cfg["url"], Client: client}, nil
+	case KindDiscord:
+		if err := cfg.require("url"); err != nil {
+			return nil, err
+		}
+		return &Discord{URL: cfg["url"], Client: client}, nil
+	case KindTelegram:
+		if err := cfg.require("token", "chat_id"); err != nil {
+			return nil, err
+		}
+		return &Telegram{Token: cfg["token"], ChatID: cfg["chat_id"], BaseURL: cfg["base_url"], Client: client}, nil
+	case KindEmail:
+		if err := cfg.require("to"); err != nil {
+			return nil, err
+		}
+		if mail == nil {
+			return nil, fmt.Errorf("no mailer configured")
+		}
+		var to []string
+		for _, a := range strings.Split(cfg["to"], ",") {
+			if a = strings.TrimSpace(a); a != "" {
+				to = append(to, a)
+			}
+		}
+		return &Email{Mailer: mail, To: to}, nil
+	case KindWebhook:
+		if err := cfg.require("url"); err != nil {
+			return nil, err
+		}
+		return &Webhook{URL: cfg["url"], Secret: cfg["secret"], Client: client}, nil
+	default:
+		return nil, fmt.Errorf("unknown channel kind %q", kind)
+	}
+}
+
+// postJSON posts payload as JSON and fails on any non-2xx answer, quoting
+// the start of the response body
+func postJSON(ctx context.Context, client *http.Client, url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if sign, ok := payload.(signer); ok {
+		sign.sign(req, body)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+		return fmt.Errorf("http status %d: %s", resp.StatusCode, strings.TrimSpace(string(snippet)))
+	}
+	return nil
+}
+
+// signer lets a payload add headers computed over the exact body sent
+type signer interface {
+	sign(req *http.Request, body []byte)
+}
+
+// truncateRunes cuts s to at most n runes, marking the cut
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
@@ -0,0 +1,150 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"metronic/internal/mailer"
+)
+
+// capture is an httptest stand-in recording the last request
+type capture struct {
+	path   string
+	header http.Header
+	body   []byte
+	status int
+}
+
+func (c *capture) server(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.path, c.header = r.URL.Path, r.Header
+		c.body, _ = io.ReadAll(r.Body)
+		if c.status != 0 {
+			w.WriteHeader(c.status)
+			io.WriteString(w, "nope")
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func (c *capture) json(t *testing.T) map[string]interface{} {
+	var m map[string]interface{}
+	if err := json.Unmarshal(c.body, &m); err != nil {
+		t.Fatalf("body %q: %v", c.body, err)
+	}
+	return m
+}
+
+var testMsg = Message{Event: "test", Subject: "Hello", Text: "line 1\nline 2"}
+
+func TestSlackPostsText(t *testing.T) {
+	var c capture
+	n, err := New(KindSlack, Config{"url": c.server(t).URL + "/hook"}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Send(context.Background(), testMsg); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.json(t)["text"]; got != "Hello\nline 1\nline 2" || c.path != "/hook" {
+		t.Fatalf("path %s text %q", c.path, got)
+	}
+}
+
+func TestDiscordTruncatesContent(t *testing.T) {
+	var c capture
+	n, _ := New(KindDiscord, Config{"url": c.server(t).URL}, nil, nil)
+	if err := n.Send(context.Background(), Message{Text: strings.Repeat("ă", 3000)}); err != nil {
+		t.Fatal(err)
+	}
+	if got := []rune(c.json(t)["content"].(string)); len(got) != discordMaxLen {
+		t.Fatalf("content has %d runes, want %d", len(got), discordMaxLen)
+	}
+}
+
+func TestTelegramSendMessage(t *testing.T) {
+	var c capture
+	n, err := New(KindTelegram, Config{"token": "123:abc", "chat_id": "-100", "base_url": c.server(t).URL}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Send(context.Background(), testMsg); err != nil {
+		t.Fatal(err)
+	}
+	m := c.json(t)
+	if c.path != "/bot123:abc/sendMessage" || m["chat_id"] != "-100" || m["text"] != "Hello\nline 1\nline 2" {
+		t.Fatalf("path %s body %v", c.path, m)
+	}
+}
+
+func TestTelegramErrorHidesToken(t *testing.T) {
+	n, _ := New(KindTelegram, Config{"token": "123:secret", "chat_id": "1", "base_url": "http://127.0.0.1:1"}, nil, nil)
+	err := n.Send(context.Background(), testMsg)
+	if err == nil || strings.Contains(err.Error(), "123:secret") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestWebhookSignsBody(t *testing.T) {
+	var c capture
+	n, _ := New(KindWebhook, Config{"url": c.server(t).URL, "secret": "s3"}, nil, nil)
+	if err := n.Send(context.Background(), testMsg); err != nil {
+		t.Fatal(err)
+	}
+	m := c.json(t)
+	if m["event"] != "test" || m["subject"] != "Hello" || m["text"] != testMsg.Text || m["sent_at"] == nil {
+		t.Fatalf("body %v", m)
+	}
+	if got, want := c.header.Get("X-Subly-Signature"), "sha256="+Signature("s3", c.body); got != want {
+		t.Fatalf("signature %q, want %q", got, want)
+	}
+}
+
+func TestNon2xxIsAnError(t *testing.T) {
+	c := capture{status: http.StatusForbidden}
+	n, _ := New(KindSlack, Config{"url": c.server(t).URL}, nil, nil)
+	err := n.Send(context.Background(), testMsg)
+	if err == nil || !strings.Contains(err.Error(), "403") || !strings.Contains(err.Error(), "nope") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+type fakeMailer struct{ sent []mailer.Message }
+
+func (f *fakeMailer) Send(m mailer.Message) error {
+	f.sent = append(f.sent, m)
+	return nil
+}
+
+func TestEmailUsesMailer(t *testing.T) {
+	var mail fakeMailer
+	n, err := New(KindEmail, Config{"to": "a@x.vn, b@x.vn"}, &mail, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Send(context.Background(), testMsg); err != nil {
+		t.Fatal(err)
+	}
+	if len(mail.sent) != 1 || len(mail.sent[0].To) != 2 || mail.sent[0].Subject != "Hello" || mail.sent[0].Body != testMsg.Text {
+		t.Fatalf("sent %+v", mail.sent)
+	}
+}
+
+func TestNewValidatesConfig(t *testing.T) {
+	for kind, cfg := range map[string]Config{
+		KindSlack:    {},
+		KindTelegram: {"token": "x"},
+		KindEmail:    {"to": " "},
+		"pager":      {"url": "http://x"},
+	} {
+		if _, err := New(kind, cfg, &fakeMailer{}, nil); err == nil {
+			t.Errorf("%s %v: expected an error", kind, cfg)
+		}
+	}
+}
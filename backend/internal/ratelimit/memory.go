@@ -0,0 +1,56 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Memory keeps buckets in process; each replica enforces its own limit.
+// Idle buckets are swept lazily while serving requests.
+type Memory struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type memoryBucket struct {
+	Bucket
+	idle time.Duration
+}
+
+func NewMemory() *Memory {
+	return &Memory{buckets: map[string]*memoryBucket{}, now: time.Now}
+}
+
+func (m *Memory) Take(_ context.Context, key string, l Limit) (bool, time.Duration, error) {
+	if !l.Enabled() {
+		return true, 0, nil
+	}
+	now := m.now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if now.Sub(m.lastSweep) > time.Minute {
+		for k, b := range m.buckets {
+			if now.Sub(b.Updated) > b.idle {
+				delete(m.buckets, k)
+			}
+		}
+		m.lastSweep = now
+	}
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &memoryBucket{idle: l.Idle()}
+		m.buckets[key] = b
+	}
+	ok, wait := l.Take(&b.Bucket, now)
+	return ok, wait, nil
+}
+
+// Len is the number of live buckets
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.buckets)
+}
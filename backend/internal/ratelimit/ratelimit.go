@@ -0,0 +1,66 @@
+// Package ratelimit implements token buckets whose state lives behind a Store,
+// so several replicas can share one limit.
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Limit allows PerMin requests per minute on average and Burst at once.
+// A zero PerMin disables the limit.
+type Limit struct {
+	PerMin int
+	Burst  int
+}
+
+// Enabled reports whether l limits anything
+func (l Limit) Enabled() bool { return l.PerMin > 0 }
+
+// Capacity is the number of tokens in a full bucket
+func (l Limit) Capacity() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return float64(l.PerMin)
+}
+
+// Bucket is the stored state of one key
+type Bucket struct {
+	Tokens  float64
+	Updated time.Time
+}
+
+// Take refills b up to now and takes one token. When the bucket is empty it
+// reports how long until a token is available; b is updated either way.
+// A zero Bucket is full.
+func (l Limit) Take(b *Bucket, now time.Time) (bool, time.Duration) {
+	perSec := float64(l.PerMin) / 60
+	if b.Updated.IsZero() {
+		b.Tokens = l.Capacity()
+	} else if elapsed := now.Sub(b.Updated).Seconds(); elapsed > 0 {
+		b.Tokens += elapsed * perSec
+		if b.Tokens > l.Capacity() {
+			b.Tokens = l.Capacity()
+		}
+	}
+	b.Updated = now
+	if b.Tokens >= 1 {
+		b.Tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.Tokens) / perSec * float64(time.Second))
+	return false, wait
+}
+
+// Idle is how long a bucket takes to refill completely; state older than
+// that is equivalent to no state and can be dropped
+func (l Limit) Idle() time.Duration {
+	return time.Duration(l.Capacity() / float64(l.PerMin) * float64(time.Minute))
+}
+
+// Store holds buckets by key. Take returns whether the request may proceed
+// and, if not, how long the caller should wait.
+type Store interface {
+	Take(ctx context.Context, key string, l Limit) (bool, time.Duration, error)
+}
@@ -0,0 +1,71 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTakeBurstThenRefill(t *testing.T) {
+	l := Limit{PerMin: 60, Burst: 3}
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var b Bucket
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Take(&b, t0); !ok {
+			t.Fatalf("request %d refused inside burst", i)
+		}
+	}
+	ok, wait := l.Take(&b, t0)
+	if ok || wait != time.Second {
+		t.Fatalf("4th request: ok=%v wait=%v, want refused with 1s", ok, wait)
+	}
+	if ok, _ := l.Take(&b, t0.Add(500*time.Millisecond)); ok {
+		t.Fatal("allowed before a token refilled")
+	}
+	if ok, _ := l.Take(&b, t0.Add(1500*time.Millisecond)); !ok {
+		t.Fatal("refused after a token refilled")
+	}
+	// a long pause refills only up to the burst
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Take(&b, t0.Add(time.Hour)); !ok {
+			t.Fatalf("request %d after pause refused", i)
+		}
+	}
+	if ok, _ := l.Take(&b, t0.Add(time.Hour)); ok {
+		t.Fatal("burst exceeded after pause")
+	}
+}
+
+func TestMemoryKeysAreIndependentAndSwept(t *testing.T) {
+	m := NewMemory()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+	l := Limit{PerMin: 1}
+	ctx := context.Background()
+	if ok, _, _ := m.Take(ctx, "a", l); !ok {
+		t.Fatal("first request for a refused")
+	}
+	if ok, wait, _ := m.Take(ctx, "a", l); ok || wait != time.Minute {
+		t.Fatalf("second request for a: ok=%v wait=%v", ok, wait)
+	}
+	if ok, _, _ := m.Take(ctx, "b", l); !ok {
+		t.Fatal("b shares a's bucket")
+	}
+	now = now.Add(2 * time.Minute)
+	m.Take(ctx, "c", l)
+	if n := m.Len(); n != 1 {
+		t.Fatalf("%d buckets after sweep, want 1", n)
+	}
+}
+
+func TestDisabledLimitAllowsEverything(t *testing.T) {
+	m := NewMemory()
+	for i := 0; i < 100; i++ {
+		if ok, _, _ := m.Take(context.Background(), "k", Limit{}); !ok {
+			t.Fatal("disabled limit refused a request")
+		}
+	}
+	if m.Len() != 0 {
+		t.Fatal("disabled limit kept state")
+	}
+}
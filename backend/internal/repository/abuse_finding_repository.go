@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"metronic/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type AbuseFindingRepository struct {
+	db *gorm.DB
+}
+
+func NewAbuseFindingRepository(db *gorm.DB) *AbuseFindingRepository {
+	return &AbuseFindingRepository{db: db}
+}
+
+// WithTx returns a copy bound to tx; a nil tx keeps the current handle
+func (r *AbuseFindingRepository) WithTx(tx *gorm.DB) *AbuseFindingRepository {
+	if tx == nil {
+		return r
+	}
+	return &AbuseFindingRepository{db: tx}
+}
+
+// FindSubject returns the finding for one (shop, kind, subject) or gorm.ErrRecordNotFound
+func (r *AbuseFindingRepository) FindSubject(shopID uint, kind, subject string) (*model.AbuseFinding, error) {
+	var f model.AbuseFinding
+	if err := r.db.Where("shop_id = ? AND kind = ? AND subject = ?", shopID, kind, subject).First(&f).Error; err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (r *AbuseFindingRepository) FindByID(id uint) (*model.AbuseFinding, error) {
+	var f model.AbuseFinding
+	if err := r.db.First(&f, id).Error; err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+func (r *AbuseFindingRepository) Create(f *model.AbuseFinding) error {
+	return r.db.Create(f).Error
+}
+
+func (r *AbuseFindingRepository) Save(f *model.AbuseFinding) error {
+	return r.db.Save(f).Error
+}
+
+// ListPaged lists findings, most recently seen first; empty filters match everything
+func (r *AbuseFindingRepository) ListPaged(page, limit int, shopID *uint, kind, status string) ([]model.AbuseFinding, int64, error) {
+	q := r.db.Model(&model.AbuseFinding{})
+	if shopID != nil {
+		q = q.Where("shop_id = ?", *shopID)
+	}
+	if kind != "" {
+		q = q.Where("kind = ?", kind)
+	}
+	if status != "" {
+		q = q.Where("status = ?", status)
+	}
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	items := []model.AbuseFinding{}
+	if err := q.Order("last_seen DESC, id DESC").Offset((page - 1) * limit).Limit(limit).Find(&items).Error; err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
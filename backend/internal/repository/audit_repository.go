@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"time"
+
+	"metronic/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// AuditRepository persists audit events
+type AuditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// WithTx returns a copy bound to tx; a nil tx keeps the current handle
+func (r *AuditRepository) WithTx(tx *gorm.DB) *AuditRepository {
+	if tx == nil {
+		return r
+	}
+	return &AuditRepository{db: tx}
+}
+
+// Transaction runs fn in a database transaction
+func (r *AuditRepository) Transaction(fn func(tx *gorm.DB) error) error {
+	return r.db.Transaction(fn)
+}
+
+func (r *AuditRepository) Create(e *model.AuditEvent) error {
+	return r.db.Create(e).Error
+}
+
+// AuditFilter narrows ListPaged; zero values are ignored
+type AuditFilter struct {
+	ActorID    uint
+	Action     string
+	EntityType string
+	EntityID   uint
+	From       *time.Time
+	To         *time.Time
+}
+
+// ListPaged returns matching events newest first plus the total count
+func (r *AuditRepository) ListPaged(f AuditFilter, page, limit int) ([]model.AuditEvent, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit <= 0 {
+		limit = 50
+	}
+	q := r.db.Model(&model.AuditEvent{})
+	if f.ActorID != 0 {
+		q = q.Where("actor_id = ?", f.ActorID)
+	}
+	if f.Action != "" {
+		q = q.Where("action = ?", f.Action)
+	}
+	if f.EntityType != "" {
+		q = q.Where("entity_type = ?", f.EntityType)
+	}
+	if f.EntityID != 0 {
+		q = q.Where("entity_id = ?", f.EntityID)
+	}
+	if f.From != nil {
+		q = q.Where("created_at >= ?", *f.From)
+	}
+	if f.To != nil {
+		q = q.Where("created_at < ?", *f.To)
+	}
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var items []model.AuditEvent
+	if err := q.Order("id DESC").Limit(limit).Offset((page - 1) * limit).Find(&items).Error; err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
@@ -14,6 +14,14 @@ func NewCustomerRepository(db *gorm.DB) *CustomerRepository {
     return &CustomerRepository{db: db}
 }
 
+// WithTx returns a copy bound to tx; a nil tx keeps the current handle
+func (r *CustomerRepository) WithTx(tx *gorm.DB) *CustomerRepository {
+    if tx == nil {
+        return r
+    }
+    return &CustomerRepository{db: tx}
+}
+
 func (r *CustomerRepository) List() ([]model.Customer, error) {
     var items []model.Customer
     if err := r.db.Model(&model.Customer{}).Order("id DESC").Find(&items).Error; err != nil {
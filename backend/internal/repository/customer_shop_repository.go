@@ -14,6 +14,14 @@ func NewCustomerShopRepository(db *gorm.DB) *CustomerShopRepository {
     return &CustomerShopRepository{db: db}
 }
 
+// WithTx returns a copy bound to tx; a nil tx keeps the current handle
+func (r *CustomerShopRepository) WithTx(tx *gorm.DB) *CustomerShopRepository {
+    if tx == nil {
+        return r
+    }
+    return &CustomerShopRepository{db: tx}
+}
+
 func (r *CustomerShopRepository) Add(shopUUID string, customerID uint, role string, isOwner bool) error {
     rec := &model.CustomerShop{ShopUUID: shopUUID, CustomerID: customerID, Role: role, IsOwner: isOwner}
     return r.db.Create(rec).Error
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"os"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testDB opens the MySQL database named by TEST_DATABASE_DSN (with
+// parseTime=true) and migrates models into it. Tests that need real SQL skip
+// when it is unset. Point it at a throwaway database: the tables passed in are
+// emptied first.
+func testDB(t *testing.T, models ...interface{}) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN not set")
+	}
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range models {
+		if err := db.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(m).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"time"
+
+	"metronic/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// InvitationRepository handles user invitations
+type InvitationRepository struct {
+	db *gorm.DB
+}
+
+func NewInvitationRepository(db *gorm.DB) *InvitationRepository {
+	return &InvitationRepository{db: db}
+}
+
+func (r *InvitationRepository) Create(inv *model.Invitation) error {
+	return r.db.Create(inv).Error
+}
+
+func (r *InvitationRepository) Update(inv *model.Invitation) error {
+	return r.db.Omit("Role").Save(inv).Error
+}
+
+func (r *InvitationRepository) FindByID(id uint) (*model.Invitation, error) {
+	var inv model.Invitation
+	if err := r.db.Preload("Role").First(&inv, id).Error; err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+// FindPendingByEmail returns an open invitation for the email, if any
+func (r *InvitationRepository) FindPendingByEmail(email string, now time.Time) (*model.Invitation, error) {
+	var inv model.Invitation
+	err := r.db.Where("email = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", email, now).
+		First(&inv).Error
+	if err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+// List returns invitations filtered by status: pending, accepted, revoked, expired or all
+func (r *InvitationRepository) List(status string, now time.Time) ([]model.Invitation, error) {
+	q := r.db.Preload("Role").Model(&model.Invitation{})
+	switch status {
+	case "pending":
+		q = q.Where("accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", now)
+	case "accepted":
+		q = q.Where("accepted_at IS NOT NULL")
+	case "revoked":
+		q = q.Where("revoked_at IS NOT NULL")
+	case "expired":
+		q = q.Where("accepted_at IS NULL AND revoked_at IS NULL AND expires_at <= ?", now)
+	}
+	var items []model.Invitation
+	if err := q.Order("id DESC").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// Accept atomically claims the invitation and creates the user with the invited role.
+// It returns gorm.ErrRecordNotFound when the invitation was already used, revoked or resent.
+func (r *InvitationRepository) Accept(inv *model.Invitation, user *model.User, now time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&model.Invitation{}).
+			Where("id = ? AND nonce_hash = ? AND accepted_at IS NULL AND revoked_at IS NULL", inv.ID, inv.NonceHash).
+			Update("accepted_at", now)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		return tx.Table("user_roles").Create(map[string]interface{}{"user_id": user.ID, "role_id": inv.RoleID}).Error
+	})
+}
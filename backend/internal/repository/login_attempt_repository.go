@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"time"
+
+	"metronic/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// LoginAttemptRepository stores login attempts and derives failure streaks
+type LoginAttemptRepository struct {
+	db *gorm.DB
+}
+
+func NewLoginAttemptRepository(db *gorm.DB) *LoginAttemptRepository {
+	return &LoginAttemptRepository{db: db}
+}
+
+// WithTx returns a copy bound to tx; a nil tx keeps the current handle
+func (r *LoginAttemptRepository) WithTx(tx *gorm.DB) *LoginAttemptRepository {
+	if tx == nil {
+		return r
+	}
+	return &LoginAttemptRepository{db: tx}
+}
+
+func (r *LoginAttemptRepository) Create(a *model.LoginAttempt) error {
+	return r.db.Create(a).Error
+}
+
+// LastSuccess returns the time of the latest successful attempt (or unlock
+// marker) for email at or after since, nil if there is none
+func (r *LoginAttemptRepository) LastSuccess(email string, since time.Time) (*time.Time, error) {
+	var out struct{ T *time.Time }
+	err := r.db.Model(&model.LoginAttempt{}).
+		Select("MAX(created_at) AS t").
+		Where("email = ? AND success = ? AND created_at >= ?", email, true, since).
+		Scan(&out).Error
+	return out.T, err
+}
+
+// Failures counts failed attempts for email or IP after since and returns the
+// time of the latest one. column must be "email" or "ip".
+func (r *LoginAttemptRepository) Failures(column, value string, since time.Time) (int64, *time.Time, error) {
+	if column != "email" && column != "ip" {
+		column = "email"
+	}
+	var out struct {
+		N int64
+		T *time.Time
+	}
+	if err := r.db.Model(&model.LoginAttempt{}).
+		Select("COUNT(*) AS n, MAX(created_at) AS t").
+		Where(column+" = ? AND success = ? AND created_at > ?", value, false, since).
+		Scan(&out).Error; err != nil {
+		return 0, nil, err
+	}
+	return out.N, out.T, nil
+}
+
+// ListByEmailPaged returns attempts for an email, newest first
+func (r *LoginAttemptRepository) ListByEmailPaged(email string, page, limit int) ([]model.LoginAttempt, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	q := r.db.Model(&model.LoginAttempt{}).Where("email = ?", email)
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var items []model.LoginAttempt
+	if err := q.Order("id DESC").Limit(limit).Offset((page - 1) * limit).Find(&items).Error; err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
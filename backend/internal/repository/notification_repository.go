@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"metronic/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// NotificationRepository stores notification channels and their delivery log
+type NotificationRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// WithTx returns a copy bound to tx; a nil tx keeps the current handle
+func (r *NotificationRepository) WithTx(tx *gorm.DB) *NotificationRepository {
+	if tx == nil {
+		return r
+	}
+	return &NotificationRepository{db: tx}
+}
+
+func (r *NotificationRepository) ListChannels() ([]model.NotificationChannel, error) {
+	items := []model.NotificationChannel{}
+	if err := r.db.Order("id ASC").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *NotificationRepository) ListEnabled() ([]model.NotificationChannel, error) {
+	var items []model.NotificationChannel
+	if err := r.db.Where("enabled = ?", true).Order("id ASC").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *NotificationRepository) CountChannels() (int64, error) {
+	var n int64
+	err := r.db.Model(&model.NotificationChannel{}).Count(&n).Error
+	return n, err
+}
+
+func (r *NotificationRepository) FindChannel(id uint) (*model.NotificationChannel, error) {
+	var ch model.NotificationChannel
+	if err := r.db.First(&ch, id).Error; err != nil {
+		return nil, err
+	}
+	return &ch, nil
+}
+
+func (r *NotificationRepository) CreateChannel(ch *model.NotificationChannel) error {
+	return r.db.Create(ch).Error
+}
+
+// SaveChannel updates every column, including Enabled going false
+func (r *NotificationRepository) SaveChannel(ch *model.NotificationChannel) error {
+	return r.db.Save(ch).Error
+}
+
+func (r *NotificationRepository) DeleteChannel(id uint) error {
+	return r.db.Delete(&model.NotificationChannel{}, id).Error
+}
+
+func (r *NotificationRepository) CreateDelivery(d *model.NotificationDelivery) error {
+	return r.db.Create(d).Error
+}
+
+// ListDeliveries returns deliveries newest first; zero/empty filters match everything
+func (r *NotificationRepository) ListDeliveries(channelID uint, status string, page, limit int) ([]model.NotificationDelivery, int64, error) {
+	q := r.db.Model(&model.NotificationDelivery{})
+	if channelID != 0 {
+		q = q.Where("channel_id = ?", channelID)
+	}
+	if status != "" {
+		q = q.Where("status = ?", status)
+	}
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	items := []model.NotificationDelivery{}
+	if err := q.Order("id DESC").Offset((page - 1) * limit).Limit(limit).Find(&items).Error; err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"metronic/internal/model"
+	"metronic/internal/ratelimit"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RateLimitRepository is a ratelimit.Store on the shared database, so every
+// replica draws from the same buckets. Each Take is one short transaction
+// holding a row lock on the key.
+type RateLimitRepository struct {
+	db        *gorm.DB
+	mu        sync.Mutex
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewRateLimitRepository(db *gorm.DB) *RateLimitRepository {
+	return &RateLimitRepository{db: db, now: time.Now}
+}
+
+func (r *RateLimitRepository) Take(ctx context.Context, key string, l ratelimit.Limit) (bool, time.Duration, error) {
+	if !l.Enabled() {
+		return true, 0, nil
+	}
+	now := r.now()
+	r.sweep(ctx, now)
+	var ok bool
+	var wait time.Duration
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// make sure the row exists so it can be locked; a new bucket starts full
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&model.RateLimitBucket{Key: key, Tokens: l.Capacity(), RefilledAt: now}).Error; err != nil {
+			return err
+		}
+		var row model.RateLimitBucket
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("`key` = ?", key).First(&row).Error; err != nil {
+			return err
+		}
+		b := ratelimit.Bucket{Tokens: row.Tokens, Updated: row.RefilledAt}
+		ok, wait = l.Take(&b, now)
+		return tx.Model(&model.RateLimitBucket{}).Where("`key` = ?", key).
+			Updates(map[string]interface{}{"tokens": b.Tokens, "refilled_at": b.Updated}).Error
+	})
+	return ok, wait, err
+}
+
+// rateLimitIdle is how long an untouched bucket is kept; it must exceed the
+// refill time of every configured limit, or buckets restart full too early
+const rateLimitIdle = time.Hour
+
+// sweep deletes idle buckets, at most once a minute
+func (r *RateLimitRepository) sweep(ctx context.Context, now time.Time) {
+	r.mu.Lock()
+	due := now.Sub(r.lastSweep) > time.Minute
+	if due {
+		r.lastSweep = now
+	}
+	r.mu.Unlock()
+	if due {
+		r.db.WithContext(ctx).Where("refilled_at < ?", now.Add(-rateLimitIdle)).Delete(&model.RateLimitBucket{})
+	}
+}
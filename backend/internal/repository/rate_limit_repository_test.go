@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"metronic/internal/model"
+	"metronic/internal/ratelimit"
+)
+
+func TestRateLimitRepositoryConcurrentTake(t *testing.T) {
+	db := testDB(t, &model.RateLimitBucket{})
+	r := NewRateLimitRepository(db)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+	l := ratelimit.Limit{PerMin: 60, Burst: 5}
+
+	// take from n goroutines at once and count the allowed calls
+	take := func(n int) (allowed int, waits []time.Duration) {
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ok, wait, err := r.Take(context.Background(), "test:shop", l)
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					t.Error(err)
+				} else if ok {
+					allowed++
+				} else {
+					waits = append(waits, wait)
+				}
+			}()
+		}
+		wg.Wait()
+		return allowed, waits
+	}
+
+	allowed, waits := take(20)
+	if allowed != 5 {
+		t.Fatalf("allowed %d of 20 concurrent calls, want the burst of 5", allowed)
+	}
+	for _, w := range waits {
+		if w != time.Second {
+			t.Fatalf("refused call waits %v, want 1s", w)
+		}
+	}
+
+	now = now.Add(2500 * time.Millisecond) // 2.5 tokens refilled
+	if allowed, _ := take(10); allowed != 2 {
+		t.Fatalf("allowed %d after refilling 2.5 tokens, want 2", allowed)
+	}
+	if allowed, _ := take(1); allowed != 0 {
+		t.Fatal("the half token left was taken as a whole one")
+	}
+
+	now = now.Add(time.Hour) // a long pause refills only up to the burst
+	if allowed, _ := take(10); allowed != 5 {
+		t.Fatalf("allowed %d after a long pause, want 5", allowed)
+	}
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"time"
+
+	"metronic/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// RecoveryCodeRepository stores hashed 2FA recovery codes
+type RecoveryCodeRepository struct {
+	db *gorm.DB
+}
+
+func NewRecoveryCodeRepository(db *gorm.DB) *RecoveryCodeRepository {
+	return &RecoveryCodeRepository{db: db}
+}
+
+// ReplaceForUser drops any previous codes of the user and stores the new hashes
+func (r *RecoveryCodeRepository) ReplaceForUser(userID uint, hashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&model.UserRecoveryCode{}).Error; err != nil {
+			return err
+		}
+		if len(hashes) == 0 {
+			return nil
+		}
+		recs := make([]model.UserRecoveryCode, 0, len(hashes))
+		for _, h := range hashes {
+			recs = append(recs, model.UserRecoveryCode{UserID: userID, CodeHash: h})
+		}
+		return tx.Create(&recs).Error
+	})
+}
+
+// Consume marks an unused code as used and reports whether one matched
+func (r *RecoveryCodeRepository) Consume(userID uint, hash string, t time.Time) (bool, error) {
+	res := r.db.Model(&model.UserRecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", t)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+func (r *RecoveryCodeRepository) CountUnused(userID uint) (int64, error) {
+	var n int64
+	err := r.db.Model(&model.UserRecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&n).Error
+	return n, err
+}
+
+func (r *RecoveryCodeRepository) DeleteByUserID(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&model.UserRecoveryCode{}).Error
+}
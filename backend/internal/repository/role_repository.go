@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"metronic/internal/domain"
+	"metronic/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// RoleRepository handles roles, permissions and their assignments
+type RoleRepository struct {
+	db *gorm.DB
+}
+
+var _ domain.PermissionRepository = (*RoleRepository)(nil)
+
+func NewRoleRepository(db *gorm.DB) *RoleRepository {
+	return &RoleRepository{db: db}
+}
+
+// WithTx returns a copy bound to tx; a nil tx keeps the current handle
+func (r *RoleRepository) WithTx(tx *gorm.DB) *RoleRepository {
+	if tx == nil {
+		return r
+	}
+	return &RoleRepository{db: tx}
+}
+
+func (r *RoleRepository) Create(role *model.Role) error {
+	return r.db.Create(role).Error
+}
+
+func (r *RoleRepository) List() ([]model.Role, error) {
+	var items []model.Role
+	if err := r.db.Preload("Permissions").Order("id ASC").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *RoleRepository) FindByID(id uint) (*model.Role, error) {
+	var role model.Role
+	if err := r.db.Preload("Permissions").First(&role, id).Error; err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (r *RoleRepository) FindByIDs(ids []uint) ([]model.Role, error) {
+	var items []model.Role
+	if len(ids) == 0 {
+		return items, nil
+	}
+	if err := r.db.Where("id IN ?", ids).Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *RoleRepository) FindByName(name string) (*model.Role, error) {
+	var role model.Role
+	if err := r.db.Preload("Permissions").Where("name = ?", name).First(&role).Error; err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// DeleteByID removes a role together with its permission and user links
+func (r *RoleRepository) DeleteByID(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		role := &model.Role{ID: id}
+		if err := tx.Model(role).Association("Permissions").Clear(); err != nil {
+			return err
+		}
+		if err := tx.Model(role).Association("Users").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(&model.Role{}, id).Error
+	})
+}
+
+func (r *RoleRepository) ListPermissions() ([]model.Permission, error) {
+	var items []model.Permission
+	if err := r.db.Order("code ASC").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *RoleRepository) FindPermissionsByCodes(codes []string) ([]model.Permission, error) {
+	var items []model.Permission
+	if len(codes) == 0 {
+		return items, nil
+	}
+	if err := r.db.Where("code IN ?", codes).Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *RoleRepository) AttachPermissions(role *model.Role, perms []model.Permission) error {
+	if len(perms) == 0 {
+		return nil
+	}
+	return r.db.Model(role).Association("Permissions").Append(perms)
+}
+
+func (r *RoleRepository) DetachPermissions(role *model.Role, perms []model.Permission) error {
+	if len(perms) == 0 {
+		return nil
+	}
+	return r.db.Model(role).Association("Permissions").Delete(perms)
+}
+
+// ReplaceUserRoles sets the exact role list of a user
+func (r *RoleRepository) ReplaceUserRoles(user *model.User, roles []model.Role) error {
+	return r.db.Model(user).Association("Roles").Replace(roles)
+}
+
+// GrantByName adds the role called name to the user's roles
+func (r *RoleRepository) GrantByName(user *model.User, name string) error {
+	role, err := r.FindByName(name)
+	if err != nil {
+		return err
+	}
+	return r.db.Model(user).Association("Roles").Append(role)
+}
+
+// CountUsersWithRole returns how many users hold the given role
+func (r *RoleRepository) CountUsersWithRole(roleID uint) (int64, error) {
+	var n int64
+	err := r.db.Table("user_roles").Where("role_id = ?", roleID).Count(&n).Error
+	return n, err
+}
+
+// PermissionCodesByUserID returns distinct permission codes granted via the user's roles
+func (r *RoleRepository) PermissionCodesByUserID(userID uint) ([]string, error) {
+	var codes []string
+	err := r.db.Table("permissions p").
+		Joins("JOIN role_permissions rp ON rp.permission_id = p.id").
+		Joins("JOIN user_roles ur ON ur.role_id = rp.role_id").
+		Where("ur.user_id = ?", userID).
+		Distinct().
+		Pluck("p.code", &codes).Error
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
@@ -1,6 +1,8 @@
 package repository
 
 import (
+    "errors"
+    "sort"
     "time"
 
     "metronic/internal/model"
@@ -9,17 +11,35 @@ import (
 )
 
 type ShopAPILogRepository struct {
-    db *gorm.DB
+    db  *gorm.DB
+    loc *time.Location
 }
 
 func NewShopAPILogRepository(db *gorm.DB) *ShopAPILogRepository {
-    return &ShopAPILogRepository{db: db}
+    return &ShopAPILogRepository{db: db, loc: time.Local}
+}
+
+// WithLocation sets the location whose calendar days the analytics buckets
+// and rolled-up days follow (APP_TIMEZONE)
+func (r *ShopAPILogRepository) WithLocation(loc *time.Location) *ShopAPILogRepository {
+    if loc != nil {
+        r.loc = loc
+    }
+    return r
 }
 
 func (r *ShopAPILogRepository) Create(rec *model.ShopAPILog) error {
     return r.db.Create(rec).Error
 }
 
+// CreateBatch inserts many log rows with one statement per 500 rows
+func (r *ShopAPILogRepository) CreateBatch(recs []model.ShopAPILog) error {
+    if len(recs) == 0 {
+        return nil
+    }
+    return r.db.CreateInBatches(recs, 500).Error
+}
+
 func (r *ShopAPILogRepository) ListByShopID(shopID uint, limit int) ([]model.ShopAPILog, error) {
     if limit <= 0 || limit > 200 {
         limit = 50
@@ -79,3 +99,372 @@ func (r *ShopAPILogRepository) ListAllPaged(page, limit int, domainParam, uuidPa
     }
     return items, total, nil
 }
+
+// OldestBefore returns the creation time of the oldest row older than t, or nil
+func (r *ShopAPILogRepository) OldestBefore(t time.Time) (*time.Time, error) {
+    var rec model.ShopAPILog
+    err := r.db.Select("id", "created_at").Where("created_at < ?", t).Order("created_at ASC").First(&rec).Error
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return nil, nil
+    }
+    if err != nil {
+        return nil, err
+    }
+    return &rec.CreatedAt, nil
+}
+
+// dateOnly is the DATE literal for the calendar day of t in its own location.
+// Binding t itself would convert it to the connection's zone first, which
+// moves a local midnight east of UTC onto the previous day.
+func dateOnly(t time.Time) string {
+    return t.Format("2006-01-02")
+}
+
+// HasRollup reports whether day (its date in its own location) was already
+// aggregated into shop_api_log_daily
+func (r *ShopAPILogRepository) HasRollup(day time.Time) (bool, error) {
+    var n int64
+    err := r.db.Model(&model.ShopAPILogDaily{}).Where("day = ?", dateOnly(day)).Limit(1).Count(&n).Error
+    return n > 0, err
+}
+
+// Rollup aggregates the raw rows in [from, to) into shop_api_log_daily under
+// the date of day in its own location and returns how many raw rows it covered. Re-running it for the same
+// day replaces the totals.
+func (r *ShopAPILogRepository) Rollup(day, from, to time.Time) (int64, error) {
+    var rows int64
+    if err := r.db.Model(&model.ShopAPILog{}).Where("created_at >= ? AND created_at < ?", from, to).Count(&rows).Error; err != nil {
+        return 0, err
+    }
+    if rows == 0 {
+        return 0, nil
+    }
+    err := r.db.Exec(`INSERT INTO shop_api_log_daily (shop_id, day, status, calls, distinct_ips)
+SELECT shop_id, ?, status, COUNT(*), COUNT(DISTINCT client_ip) FROM shop_api_logs
+WHERE created_at >= ? AND created_at < ?
+GROUP BY shop_id, status
+ON DUPLICATE KEY UPDATE calls = VALUES(calls), distinct_ips = VALUES(distinct_ips)`, dateOnly(day), from, to).Error
+    return rows, err
+}
+
+// DeleteBefore removes up to limit rows older than t and returns how many went
+func (r *ShopAPILogRepository) DeleteBefore(t time.Time, limit int) (int64, error) {
+    res := r.db.Exec("DELETE FROM shop_api_logs WHERE created_at < ? LIMIT ?", t, limit)
+    return res.RowsAffected, res.Error
+}
+
+// APILogRange scopes the analytics queries; nil fields are unbounded
+type APILogRange struct {
+    ShopID *uint
+    From   *time.Time
+    To     *time.Time
+}
+
+func (f APILogRange) apply(q *gorm.DB, column string) *gorm.DB {
+    if f.ShopID != nil {
+        q = q.Where("shop_id = ?", *f.ShopID)
+    }
+    if f.From != nil {
+        q = q.Where(column+" >= ?", *f.From)
+    }
+    if f.To != nil {
+        q = q.Where(column+" < ?", *f.To)
+    }
+    return q
+}
+
+// APILogBucket is the number of calls with one status in one hour or day
+type APILogBucket struct {
+    Bucket string `json:"bucket"`
+    Status string `json:"status"`
+    Calls  int64  `json:"calls"`
+}
+
+// apiLogBucketLayouts maps a bucket size to the Go layout of its label
+var apiLogBucketLayouts = map[string]string{
+    "hour": "2006-01-02 15:00",
+    "day":  "2006-01-02",
+}
+
+// apiLogSlot is the raw call count of one status in one quarter hour of the
+// stored created_at values. Quarter hours line up with every real UTC offset,
+// so slots can be re-labelled in any location without splitting them.
+type apiLogSlot struct {
+    At     time.Time
+    Status string
+    Calls  int64
+}
+
+// rolledUp splits f at the end of the last day already rolled up: days up to
+// it come from shop_api_log_daily (daily scoped to the days f overlaps, in
+// r.loc), calls after it from the raw rows (raw). daily is nil when none of
+// f was rolled up.
+func (r *ShopAPILogRepository) rolledUp(f APILogRange) (daily *gorm.DB, raw APILogRange, err error) {
+    raw = f
+    scope := func() *gorm.DB {
+        q := r.db.Model(&model.ShopAPILogDaily{})
+        if f.ShopID != nil {
+            q = q.Where("shop_id = ?", *f.ShopID)
+        }
+        if f.From != nil {
+            q = q.Where("day >= ?", dateOnly(f.From.In(r.loc)))
+        }
+        if f.To != nil {
+            q = q.Where("day <= ?", dateOnly(f.To.Add(-time.Nanosecond).In(r.loc)))
+        }
+        return q
+    }
+    var last *string
+    if err := scope().Select("DATE_FORMAT(MAX(day), '%Y-%m-%d')").Scan(&last).Error; err != nil {
+        return nil, raw, err
+    }
+    if last == nil {
+        return nil, raw, nil
+    }
+    day, err := time.ParseInLocation("2006-01-02", *last, r.loc)
+    if err != nil {
+        return nil, raw, err
+    }
+    next := day.AddDate(0, 0, 1)
+    if raw.From == nil || raw.From.Before(next) {
+        raw.From = &next
+    }
+    return scope(), raw, nil
+}
+
+// Series counts calls per bucket ("hour" or "day", labelled in the location
+// set by WithLocation) and status. Daily series also cover days already
+// pruned into shop_api_log_daily; raw rows are only counted after the last
+// rolled-up day, so a day is never counted twice.
+func (r *ShopAPILogRepository) Series(f APILogRange, bucket string) ([]APILogBucket, error) {
+    layout, ok := apiLogBucketLayouts[bucket]
+    if !ok {
+        return nil, errors.New("bucket must be hour or day")
+    }
+    raw := f
+    var daily []APILogBucket
+    if bucket == "day" {
+        days, rest, err := r.rolledUp(f)
+        if err != nil {
+            return nil, err
+        }
+        if days != nil {
+            if err := days.Select("DATE_FORMAT(day, '%Y-%m-%d') AS bucket, status, SUM(calls) AS calls").
+                Group("bucket, status").Scan(&daily).Error; err != nil {
+                return nil, err
+            }
+        }
+        raw = rest
+    }
+    var slots []apiLogSlot
+    if err := raw.apply(r.db.Model(&model.ShopAPILog{}), "created_at").
+        Select("MIN(created_at) AS at, status, COUNT(*) AS calls").
+        Group("DATE_FORMAT(created_at, '%Y-%m-%d %H'), FLOOR(MINUTE(created_at) / 15), status").
+        Scan(&slots).Error; err != nil {
+        return nil, err
+    }
+    return mergeBuckets(daily, slots, layout, r.loc), nil
+}
+
+// mergeBuckets adds the raw slots, labelled with layout in loc, to the daily
+// buckets and returns them ordered by bucket and status
+func mergeBuckets(daily []APILogBucket, slots []apiLogSlot, layout string, loc *time.Location) []APILogBucket {
+    type key struct{ bucket, status string }
+    sums := map[key]int64{}
+    for _, b := range daily {
+        sums[key{b.Bucket, b.Status}] += b.Calls
+    }
+    for _, s := range slots {
+        sums[key{s.At.In(loc).Format(layout), s.Status}] += s.Calls
+    }
+    out := make([]APILogBucket, 0, len(sums))
+    for k, n := range sums {
+        out = append(out, APILogBucket{Bucket: k.bucket, Status: k.status, Calls: n})
+    }
+    sort.Slice(out, func(i, j int) bool {
+        if out[i].Bucket != out[j].Bucket {
+            return out[i].Bucket < out[j].Bucket
+        }
+        return out[i].Status < out[j].Status
+    })
+    return out
+}
+
+// APILogTotals summarises the calls in a range. Calls and NotFound include
+// rolled-up days; the distinct counts only cover raw rows still kept.
+type APILogTotals struct {
+    Calls              int64 `json:"calls"`
+    DistinctIPs        int64 `json:"distinct_ips"`
+    DistinctUserAgents int64 `json:"distinct_user_agents"`
+    NotFound           int64 `json:"not_found"`
+}
+
+func (r *ShopAPILogRepository) Totals(f APILogRange) (APILogTotals, error) {
+    var t APILogTotals
+    days, raw, err := r.rolledUp(f)
+    if err != nil {
+        return t, err
+    }
+    if err := raw.apply(r.db.Model(&model.ShopAPILog{}), "created_at").
+        Select("COUNT(*) AS calls, COUNT(DISTINCT client_ip) AS distinct_ips, " +
+            "COUNT(DISTINCT user_agent) AS distinct_user_agents, " +
+            "COALESCE(SUM(status = 'not_found'), 0) AS not_found").
+        Scan(&t).Error; err != nil {
+        return t, err
+    }
+    if days != nil {
+        var d struct {
+            Calls    int64
+            NotFound int64
+        }
+        if err := days.Select("COALESCE(SUM(calls), 0) AS calls, " +
+            "COALESCE(SUM(CASE WHEN status = 'not_found' THEN calls ELSE 0 END), 0) AS not_found").
+            Scan(&d).Error; err != nil {
+            return t, err
+        }
+        t.Calls += d.Calls
+        t.NotFound += d.NotFound
+    }
+    return t, nil
+}
+
+// APILogTop is one row of a top-N ranking
+type APILogTop struct {
+    Value    string    `json:"value"`
+    Calls    int64     `json:"calls"`
+    LastSeen time.Time `json:"last_seen"`
+}
+
+// Top ranks the values of column (domain_param, uuid_param or user_agent)
+// by call volume; onlyStatus narrows it to one status such as not_found
+func (r *ShopAPILogRepository) Top(f APILogRange, column, onlyStatus string, limit int) ([]APILogTop, error) {
+    switch column {
+    case "domain_param", "uuid_param", "user_agent":
+    default:
+        return nil, errors.New("unsupported column")
+    }
+    q := f.apply(r.db.Model(&model.ShopAPILog{}), "created_at").
+        Where(column + " IS NOT NULL AND " + column + " <> ''")
+    if onlyStatus != "" {
+        q = q.Where("status = ?", onlyStatus)
+    }
+    var out []APILogTop
+    err := q.Select(column + " AS value, COUNT(*) AS calls, MAX(created_at) AS last_seen").
+        Group(column).Order("calls DESC").Limit(limit).Scan(&out).Error
+    return out, err
+}
+
+// APILogShopActivity is the call volume and first/last call of one shop
+type APILogShopActivity struct {
+    ShopID    uint       `json:"shop_id"`
+    Domain    *string    `json:"domain"`
+    Calls     int64      `json:"calls"`
+    FirstSeen *time.Time `json:"first_seen"`
+    LastSeen  *time.Time `json:"last_seen"`
+}
+
+// ShopActivity lists shops by call volume in the range. FirstSeen also looks
+// at rolled-up days in the range, so it survives pruning; such a day counts
+// from its midnight, or from the start of the range if that is later.
+func (r *ShopAPILogRepository) ShopActivity(f APILogRange, limit int) ([]APILogShopActivity, error) {
+    var out []APILogShopActivity
+    q := f.apply(r.db.Model(&model.ShopAPILog{}), "shop_api_logs.created_at").
+        Joins("LEFT JOIN shops ON shops.id = shop_api_logs.shop_id").
+        Where("shop_api_logs.shop_id <> 0")
+    if err := q.Select("shop_api_logs.shop_id, MAX(shops.domain) AS domain, COUNT(*) AS calls, " +
+        "MIN(shop_api_logs.created_at) AS first_seen, MAX(shop_api_logs.created_at) AS last_seen").
+        Group("shop_api_logs.shop_id").Order("calls DESC").Limit(limit).Scan(&out).Error; err != nil {
+        return nil, err
+    }
+    if len(out) == 0 {
+        return out, nil
+    }
+    days, _, err := r.rolledUp(f)
+    if err != nil || days == nil {
+        return out, err
+    }
+    ids := make([]uint, len(out))
+    for i, a := range out {
+        ids[i] = a.ShopID
+    }
+    var firsts []struct {
+        ShopID uint
+        Day    string
+    }
+    if err := days.Select("shop_id, DATE_FORMAT(MIN(day), '%Y-%m-%d') AS day").
+        Where("shop_id IN ?", ids).Group("shop_id").Scan(&firsts).Error; err != nil {
+        return nil, err
+    }
+    for _, fs := range firsts {
+        day, err := time.ParseInLocation("2006-01-02", fs.Day, r.loc)
+        if err != nil {
+            return nil, err
+        }
+        if f.From != nil && day.Before(*f.From) {
+            day = *f.From
+        }
+        for i := range out {
+            if out[i].ShopID == fs.ShopID && (out[i].FirstSeen == nil || day.Before(*out[i].FirstSeen)) {
+                d := day
+                out[i].FirstSeen = &d
+            }
+        }
+    }
+    return out, nil
+}
+
+// APILogUsage aggregates the calls of one shop, optionally per domain_param
+type APILogUsage struct {
+    ShopID      uint
+    Subject     string
+    Calls       int64
+    DistinctIPs int64
+    FirstSeen   time.Time
+    LastSeen    time.Time
+}
+
+// UUIDDomainUsage groups calls that resolved a shop by UUID and also sent a
+// domain, per (shop, domain_param); the caller decides which domains are foreign
+func (r *ShopAPILogRepository) UUIDDomainUsage(from, to time.Time) ([]APILogUsage, error) {
+    var out []APILogUsage
+    err := r.db.Model(&model.ShopAPILog{}).
+        Select("shop_id, domain_param AS subject, COUNT(*) AS calls, COUNT(DISTINCT client_ip) AS distinct_ips, "+
+            "MIN(created_at) AS first_seen, MAX(created_at) AS last_seen").
+        Where("created_at >= ? AND created_at < ?", from, to).
+        Where("shop_id <> 0 AND uuid_param IS NOT NULL AND domain_param IS NOT NULL AND domain_param <> ''").
+        Group("shop_id, domain_param").Scan(&out).Error
+    return out, err
+}
+
+// IPSpread lists shops checked from more than maxIPs distinct client IPs
+func (r *ShopAPILogRepository) IPSpread(from, to time.Time, maxIPs int) ([]APILogUsage, error) {
+    var out []APILogUsage
+    err := r.db.Model(&model.ShopAPILog{}).
+        Select("shop_id, COUNT(*) AS calls, COUNT(DISTINCT client_ip) AS distinct_ips, "+
+            "MIN(created_at) AS first_seen, MAX(created_at) AS last_seen").
+        Where("created_at >= ? AND created_at < ?", from, to).
+        Where("shop_id <> 0").
+        Group("shop_id").Having("COUNT(DISTINCT client_ip) > ?", maxIPs).Scan(&out).Error
+    return out, err
+}
+
+// SampleClients returns up to n distinct client IPs and user agents of a
+// shop's calls, narrowed to the given raw domain params when any are passed
+func (r *ShopAPILogRepository) SampleClients(shopID uint, domains []string, from, to time.Time, n int) ([]string, []string, error) {
+    q := func() *gorm.DB {
+        q := r.db.Model(&model.ShopAPILog{}).
+            Where("shop_id = ? AND created_at >= ? AND created_at < ?", shopID, from, to)
+        if len(domains) > 0 {
+            q = q.Where("domain_param IN ?", domains)
+        }
+        return q
+    }
+    var ips, agents []string
+    if err := q().Distinct("client_ip").Limit(n).Pluck("client_ip", &ips).Error; err != nil {
+        return nil, nil, err
+    }
+    if err := q().Where("user_agent <> ''").Distinct("user_agent").Limit(n).Pluck("user_agent", &agents).Error; err != nil {
+        return nil, nil, err
+    }
+    return ips, agents, nil
+}
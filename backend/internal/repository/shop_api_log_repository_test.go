@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"metronic/internal/model"
+)
+
+var ict = time.FixedZone("ICT", 7*3600)
+
+func TestDateOnlyKeepsLocalDay(t *testing.T) {
+	// local midnight east of UTC is still the previous day in UTC
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, ict)
+	if got := dateOnly(day); got != "2026-03-01" {
+		t.Fatalf("dateOnly = %q, want 2026-03-01", got)
+	}
+	if got := dateOnly(day.UTC()); got != "2026-02-28" {
+		t.Fatalf("dateOnly(UTC) = %q, the check above would not catch the shift", got)
+	}
+}
+
+func TestRollupStoresLocalDay(t *testing.T) {
+	db := testDB(t, &model.ShopAPILog{}, &model.ShopAPILogDaily{})
+	r := NewShopAPILogRepository(db)
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, ict)
+	for _, at := range []time.Time{day.Add(-time.Minute), day, day.Add(23 * time.Hour)} {
+		if err := r.Create(&model.ShopAPILog{ShopID: 1, ClientIP: "203.0.113.1", Status: "valid", CreatedAt: at}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if done, err := r.HasRollup(day); err != nil || done {
+		t.Fatalf("HasRollup before rollup = %v, %v", done, err)
+	}
+	n, err := r.Rollup(day, day, day.AddDate(0, 0, 1))
+	if err != nil || n != 2 {
+		t.Fatalf("Rollup = %d, %v; want 2 rows", n, err)
+	}
+	if done, err := r.HasRollup(day); err != nil || !done {
+		t.Fatalf("HasRollup after rollup = %v, %v", done, err)
+	}
+	if done, _ := r.HasRollup(day.AddDate(0, 0, -1)); done {
+		t.Fatal("the rollup landed on the previous day")
+	}
+	var stored string
+	if err := db.Raw("SELECT DATE_FORMAT(day, '%Y-%m-%d') FROM shop_api_log_daily").Scan(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored != "2026-03-01" {
+		t.Fatalf("stored day %q, want 2026-03-01", stored)
+	}
+}
+
+func TestMergeBucketsLabelsRawCallsInLocation(t *testing.T) {
+	daily := []APILogBucket{
+		{Bucket: "2026-03-01", Status: "valid", Calls: 10},
+		{Bucket: "2026-03-01", Status: "not_found", Calls: 2},
+	}
+	slots := []apiLogSlot{
+		{At: time.Date(2026, 3, 1, 16, 45, 0, 0, time.UTC), Status: "valid", Calls: 3},   // 03-01 23:45 ICT
+		{At: time.Date(2026, 3, 1, 17, 0, 0, 0, time.UTC), Status: "valid", Calls: 4},    // 03-02 00:00 ICT
+		{At: time.Date(2026, 3, 2, 16, 59, 0, 0, time.UTC), Status: "expired", Calls: 1}, // 03-02 23:59 ICT
+	}
+	got := mergeBuckets(daily, slots, apiLogBucketLayouts["day"], ict)
+	want := []APILogBucket{
+		{Bucket: "2026-03-01", Status: "not_found", Calls: 2},
+		{Bucket: "2026-03-01", Status: "valid", Calls: 13},
+		{Bucket: "2026-03-02", Status: "expired", Calls: 1},
+		{Bucket: "2026-03-02", Status: "valid", Calls: 4},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("bucket %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	hourly := mergeBuckets(nil, slots[:2], apiLogBucketLayouts["hour"], ict)
+	if len(hourly) != 2 || hourly[0].Bucket != "2026-03-01 23:00" || hourly[1].Bucket != "2026-03-02 00:00" {
+		t.Fatalf("hourly = %+v", hourly)
+	}
+}
+
+func TestAnalyticsMergeRolledUpAndRawDays(t *testing.T) {
+	db := testDB(t, &model.ShopAPILog{}, &model.ShopAPILogDaily{})
+	r := NewShopAPILogRepository(db).WithLocation(ict)
+	day1 := time.Date(2026, 3, 1, 0, 0, 0, 0, ict)
+	day2 := day1.AddDate(0, 0, 1)
+	day3 := day2.AddDate(0, 0, 1)
+	logAt := func(shopID uint, status string, at time.Time) {
+		t.Helper()
+		if err := r.Create(&model.ShopAPILog{ShopID: shopID, ClientIP: "203.0.113.1", Status: status, CreatedAt: at}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// day 1 is rolled up and pruned; a stray raw row from it must not count twice
+	logAt(1, "valid", day1.Add(time.Hour))
+	logAt(1, "not_found", day1.Add(2*time.Hour))
+	if _, err := r.Rollup(day1, day1, day2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.DeleteBefore(day1.Add(90*time.Minute), 10); err != nil {
+		t.Fatal(err)
+	}
+	// days 2 and 3 are raw; 00:30 ICT is still the previous day in UTC
+	logAt(1, "valid", day2.Add(30*time.Minute))
+	logAt(1, "valid", day3.Add(30*time.Minute))
+
+	to := day3.AddDate(0, 0, 1)
+	f := APILogRange{From: &day1, To: &to}
+	series, err := r.Series(f, "day")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []APILogBucket{
+		{Bucket: "2026-03-01", Status: "not_found", Calls: 1},
+		{Bucket: "2026-03-01", Status: "valid", Calls: 1},
+		{Bucket: "2026-03-02", Status: "valid", Calls: 1},
+		{Bucket: "2026-03-03", Status: "valid", Calls: 1},
+	}
+	if len(series) != len(want) {
+		t.Fatalf("series %+v, want %+v", series, want)
+	}
+	for i := range want {
+		if series[i] != want[i] {
+			t.Fatalf("series[%d] = %+v, want %+v", i, series[i], want[i])
+		}
+	}
+
+	totals, err := r.Totals(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if totals.Calls != 4 || totals.NotFound != 1 {
+		t.Fatalf("totals %+v, want 4 calls with 1 not_found", totals)
+	}
+
+	activity, err := r.ShopActivity(f, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(activity) != 1 || activity[0].FirstSeen == nil || !activity[0].FirstSeen.Equal(day1) {
+		t.Fatalf("activity %+v, want first_seen %v", activity, day1)
+	}
+	from := day2
+	activity, err = r.ShopActivity(APILogRange{From: &from, To: &to}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(activity) != 1 || activity[0].FirstSeen == nil || !activity[0].FirstSeen.Equal(day2.Add(30*time.Minute)) {
+		t.Fatalf("activity %+v, want first_seen inside the range", activity)
+	}
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"time"
+
+	"metronic/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// ShopDomainRepository manages the domains and aliases of shops
+type ShopDomainRepository struct {
+	db *gorm.DB
+}
+
+func NewShopDomainRepository(db *gorm.DB) *ShopDomainRepository {
+	return &ShopDomainRepository{db: db}
+}
+
+// WithTx returns a copy bound to tx; a nil tx keeps the current handle
+func (r *ShopDomainRepository) WithTx(tx *gorm.DB) *ShopDomainRepository {
+	if tx == nil {
+		return r
+	}
+	return &ShopDomainRepository{db: tx}
+}
+
+func (r *ShopDomainRepository) Create(d *model.ShopDomain) error {
+	return r.db.Create(d).Error
+}
+
+// FindByDomain matches an entry exactly, whichever shop owns it
+func (r *ShopDomainRepository) FindByDomain(domain string) (*model.ShopDomain, error) {
+	var d model.ShopDomain
+	if err := r.db.Where("domain = ?", domain).First(&d).Error; err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// FindForShop returns one domain entry of a shop
+func (r *ShopDomainRepository) FindForShop(shopID, id uint) (*model.ShopDomain, error) {
+	var d model.ShopDomain
+	if err := r.db.Where("shop_id = ?", shopID).First(&d, id).Error; err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// ListByShopID returns a shop's domains, primary first
+func (r *ShopDomainRepository) ListByShopID(shopID uint) ([]model.ShopDomain, error) {
+	var items []model.ShopDomain
+	if err := r.db.Where("shop_id = ?", shopID).Order("kind = 'primary' DESC").Order("id ASC").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *ShopDomainRepository) CountByShopID(shopID uint) (int64, error) {
+	var n int64
+	err := r.db.Model(&model.ShopDomain{}).Where("shop_id = ?", shopID).Count(&n).Error
+	return n, err
+}
+
+func (r *ShopDomainRepository) Save(d *model.ShopDomain) error {
+	return r.db.Save(d).Error
+}
+
+// ListUnverified returns entries added after createdAfter that still await
+// verification and were not checked since checkedBefore, least recently checked first
+func (r *ShopDomainRepository) ListUnverified(createdAfter, checkedBefore time.Time, limit int) ([]model.ShopDomain, error) {
+	var items []model.ShopDomain
+	err := r.db.Where("verified_at IS NULL AND created_at > ? AND (checked_at IS NULL OR checked_at < ?)", createdAfter, checkedBefore).
+		Order("checked_at ASC").Order("id ASC").Limit(limit).Find(&items).Error
+	return items, err
+}
+
+func (r *ShopDomainRepository) Delete(id uint) error {
+	return r.db.Delete(&model.ShopDomain{}, id).Error
+}
+
+// BackfillPrimary adds the primary row for shops created before shop_domains existed
+func (r *ShopDomainRepository) BackfillPrimary() (int64, error) {
+	res := r.db.Exec(`INSERT INTO shop_domains (shop_id, domain, kind, created_at)
+SELECT s.id, s.domain, ?, NOW() FROM shops s
+LEFT JOIN shop_domains d ON d.shop_id = s.id AND d.kind = ?
+WHERE d.id IS NULL`, model.ShopDomainPrimary, model.ShopDomainPrimary)
+	return res.RowsAffected, res.Error
+}
+
+// BackfillVerifyTokens issues a verification token to every entry without
+// one: rows from before verification existed and those added by BackfillPrimary
+func (r *ShopDomainRepository) BackfillVerifyTokens() (int64, error) {
+	var ids []uint
+	if err := r.db.Model(&model.ShopDomain{}).Where("verify_token = '' OR verify_token IS NULL").Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	var n int64
+	for _, id := range ids {
+		res := r.db.Model(&model.ShopDomain{}).Where("id = ? AND (verify_token = '' OR verify_token IS NULL)", id).
+			Update("verify_token", model.NewVerifyToken())
+		if res.Error != nil {
+			return n, res.Error
+		}
+		n += res.RowsAffected
+	}
+	return n, nil
+}
+
+// All returns every domain entry, including those of soft-deleted shops
+func (r *ShopDomainRepository) All() ([]model.ShopDomain, error) {
+	var items []model.ShopDomain
+	if err := r.db.Order("id ASC").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// Rename changes one entry; a primary entry also updates shops.domain
+func (r *ShopDomainRepository) Rename(d *model.ShopDomain, to string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.ShopDomain{}).Where("id = ?", d.ID).Update("domain", to).Error; err != nil {
+			return err
+		}
+		if d.Kind != model.ShopDomainPrimary {
+			return nil
+		}
+		return tx.Unscoped().Model(&model.Shop{}).Where("id = ?", d.ShopID).Update("domain", to).Error
+	})
+}
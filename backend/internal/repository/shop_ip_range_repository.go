@@ -0,0 +1,48 @@
+package repository
+
+import (
+    "metronic/internal/model"
+
+    "gorm.io/gorm"
+)
+
+type ShopIPRangeRepository struct {
+    db *gorm.DB
+}
+
+func NewShopIPRangeRepository(db *gorm.DB) *ShopIPRangeRepository {
+    return &ShopIPRangeRepository{db: db}
+}
+
+// WithTx returns a copy bound to tx; a nil tx keeps the current handle
+func (r *ShopIPRangeRepository) WithTx(tx *gorm.DB) *ShopIPRangeRepository {
+    if tx == nil {
+        return r
+    }
+    return &ShopIPRangeRepository{db: tx}
+}
+
+func (r *ShopIPRangeRepository) Create(rec *model.ShopIPRange) error {
+    return r.db.Create(rec).Error
+}
+
+func (r *ShopIPRangeRepository) ListByShopID(shopID uint) ([]model.ShopIPRange, error) {
+    items := []model.ShopIPRange{}
+    if err := r.db.Where("shop_id = ?", shopID).Order("id ASC").Find(&items).Error; err != nil {
+        return nil, err
+    }
+    return items, nil
+}
+
+// FindForShop returns range id only if it belongs to shopID
+func (r *ShopIPRangeRepository) FindForShop(shopID, id uint) (*model.ShopIPRange, error) {
+    var rec model.ShopIPRange
+    if err := r.db.Where("shop_id = ? AND id = ?", shopID, id).First(&rec).Error; err != nil {
+        return nil, err
+    }
+    return &rec, nil
+}
+
+func (r *ShopIPRangeRepository) Delete(id uint) error {
+    return r.db.Delete(&model.ShopIPRange{}, id).Error
+}
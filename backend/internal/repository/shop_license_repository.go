@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"metronic/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// ShopLicenseRepository persists issued license documents
+type ShopLicenseRepository struct {
+	db *gorm.DB
+}
+
+func NewShopLicenseRepository(db *gorm.DB) *ShopLicenseRepository {
+	return &ShopLicenseRepository{db: db}
+}
+
+// WithTx returns a copy bound to tx; a nil tx keeps the current handle
+func (r *ShopLicenseRepository) WithTx(tx *gorm.DB) *ShopLicenseRepository {
+	if tx == nil {
+		return r
+	}
+	return &ShopLicenseRepository{db: tx}
+}
+
+func (r *ShopLicenseRepository) Create(l *model.ShopLicense) error {
+	return r.db.Create(l).Error
+}
+
+// Latest returns the current license of a shop
+func (r *ShopLicenseRepository) Latest(shopID uint) (*model.ShopLicense, error) {
+	var l model.ShopLicense
+	if err := r.db.Where("shop_id = ?", shopID).Order("id DESC").First(&l).Error; err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// MaxSerial returns the highest serial issued for a shop (0 if none)
+func (r *ShopLicenseRepository) MaxSerial(shopID uint) (uint, error) {
+	var n uint
+	err := r.db.Model(&model.ShopLicense{}).Where("shop_id = ?", shopID).
+		Select("COALESCE(MAX(serial), 0)").Scan(&n).Error
+	return n, err
+}
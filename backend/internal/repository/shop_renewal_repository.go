@@ -14,6 +14,14 @@ func NewShopRenewalRepository(db *gorm.DB) *ShopRenewalRepository {
     return &ShopRenewalRepository{db: db}
 }
 
+// WithTx returns a copy bound to tx; a nil tx keeps the current handle
+func (r *ShopRenewalRepository) WithTx(tx *gorm.DB) *ShopRenewalRepository {
+    if tx == nil {
+        return r
+    }
+    return &ShopRenewalRepository{db: tx}
+}
+
 func (r *ShopRenewalRepository) Create(rec *model.ShopRenewal) error {
     return r.db.Create(rec).Error
 }
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"errors"
 	"time"
 
 	"metronic/internal/model"
@@ -17,8 +18,34 @@ func NewShopRepository(db *gorm.DB) *ShopRepository {
 	return &ShopRepository{db: db}
 }
 
+// WithTx returns a copy bound to tx; a nil tx keeps the current handle
+func (r *ShopRepository) WithTx(tx *gorm.DB) *ShopRepository {
+	if tx == nil {
+		return r
+	}
+	return &ShopRepository{db: tx}
+}
+
+// Create inserts the shop together with its primary shop_domains row
 func (r *ShopRepository) Create(s *model.Shop) error {
-	return r.db.Create(s).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return createShop(tx, s)
+	})
+}
+
+func createShop(tx *gorm.DB, s *model.Shop) error {
+	// active defaults to true: gorm binds the default in place of a false
+	// zero value and copies it back into s, so write false explicitly
+	inactive := !s.Active
+	if err := tx.Create(s).Error; err != nil {
+		return err
+	}
+	if inactive {
+		if err := tx.Model(s).Update("active", false).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Create(&model.ShopDomain{ShopID: s.ID, Domain: s.Domain, Kind: model.ShopDomainPrimary}).Error
 }
 
 func (r *ShopRepository) List() ([]model.Shop, error) {
@@ -50,8 +77,9 @@ func (r *ShopRepository) ListPaged(page, limit int) ([]model.Shop, int64, error)
 }
 
 // ListPagedFiltered applies a filter and ordering similar to UI expectations
-// filter values: "all", "valid", "expired", "notOver1y", "expiring"
-func (r *ShopRepository) ListPagedFiltered(page, limit int, filter string, query string, now time.Time) ([]model.Shop, int64, error) {
+// filter values: "all", "valid", "expired", "notOver1y", "expiring", "trashed"
+// and the lifecycle states "active", "expiring_soon", "grace", "suspended"
+func (r *ShopRepository) ListPagedFiltered(page, limit int, filter string, query string, now time.Time, policy model.ExpiryPolicy) ([]model.Shop, int64, error) {
 	if page < 1 {
 		page = 1
 	}
@@ -75,6 +103,8 @@ func (r *ShopRepository) ListPagedFiltered(page, limit int, filter string, query
 		q = q.Where("expired_at IS NOT NULL AND expired_at >= ? AND expired_at <= ?", now, upper)
 	case "trashed":
 		q = r.db.Unscoped().Model(&model.Shop{}).Where("deleted_at IS NOT NULL")
+	case model.ShopStateActive, model.ShopStateExpiringSoon, model.ShopStateGrace, model.ShopStateSuspended:
+		q = whereState(q, filter, now, policy)
 	default:
 		// all: no where
 	}
@@ -99,8 +129,10 @@ func (r *ShopRepository) ListPagedFiltered(page, limit int, filter string, query
 		q = q.Order("expired_at ASC")
 	case "trashed":
 		q = q.Order("deleted_at DESC")
-	case "notOver1y":
+	case "notOver1y", model.ShopStateExpiringSoon, model.ShopStateGrace:
 		q = q.Order("expired_at ASC")
+	case model.ShopStateSuspended:
+		q = q.Order("expired_at DESC")
 	default:
 		q = q.Order("id DESC")
 	}
@@ -112,6 +144,24 @@ func (r *ShopRepository) ListPagedFiltered(page, limit int, filter string, query
 	return items, total, nil
 }
 
+// graceEndSQL is the end of a shop's grace period; the placeholder is the global grace days
+const graceEndSQL = "DATE_ADD(expired_at, INTERVAL COALESCE(grace_days, ?) DAY)"
+
+// whereState restricts q to shops in a lifecycle state (see model.ExpiryPolicy.State)
+func whereState(q *gorm.DB, state string, now time.Time, p model.ExpiryPolicy) *gorm.DB {
+	soon := now.AddDate(0, 0, p.ExpiringSoonDays)
+	switch state {
+	case model.ShopStateActive:
+		return q.Where("active = ? AND (expired_at IS NULL OR expired_at > ?)", true, soon)
+	case model.ShopStateExpiringSoon:
+		return q.Where("active = ? AND expired_at IS NOT NULL AND expired_at >= ? AND expired_at <= ?", true, now, soon)
+	case model.ShopStateGrace:
+		return q.Where("active = ? AND expired_at IS NOT NULL AND expired_at < ? AND "+graceEndSQL+" >= ?", true, now, p.GraceDays, now)
+	default: // suspended
+		return q.Where("active = ? OR (expired_at IS NOT NULL AND "+graceEndSQL+" < ?)", false, p.GraceDays, now)
+	}
+}
+
 // ShopStats holds counts for sidebar badges
 type ShopStats struct {
 	All       int64
@@ -120,10 +170,15 @@ type ShopStats struct {
 	NotOver1y int64
 	Expiring  int64
 	Trashed   int64
+	// lifecycle states
+	Active       int64
+	ExpiringSoon int64
+	Grace        int64
+	Suspended    int64
 }
 
 // Stats computes counts for different categories
-func (r *ShopRepository) Stats(now time.Time) (ShopStats, error) {
+func (r *ShopRepository) Stats(now time.Time, policy model.ExpiryPolicy) (ShopStats, error) {
 	var out ShopStats
 	if err := r.db.Model(&model.Shop{}).Count(&out.All).Error; err != nil {
 		return out, err
@@ -158,6 +213,16 @@ func (r *ShopRepository) Stats(now time.Time) (ShopStats, error) {
 		Count(&out.Trashed).Error; err != nil {
 		return out, err
 	}
+	for state, dst := range map[string]*int64{
+		model.ShopStateActive:       &out.Active,
+		model.ShopStateExpiringSoon: &out.ExpiringSoon,
+		model.ShopStateGrace:        &out.Grace,
+		model.ShopStateSuspended:    &out.Suspended,
+	} {
+		if err := whereState(r.db.Model(&model.Shop{}), state, now, policy).Count(dst).Error; err != nil {
+			return out, err
+		}
+	}
 	return out, nil
 }
 
@@ -166,9 +231,17 @@ func (r *ShopRepository) RestoreByID(id uint) error {
 	return r.db.Unscoped().Model(&model.Shop{}).Where("id = ?", id).Update("deleted_at", nil).Error
 }
 
-// ForceDeleteByID permanently deletes a shop
+// ForceDeleteByID permanently deletes a shop, releases its domains and drops its IP allowlist
 func (r *ShopRepository) ForceDeleteByID(id uint) error {
-	return r.db.Unscoped().Delete(&model.Shop{}, id).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("shop_id = ?", id).Delete(&model.ShopDomain{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("shop_id = ?", id).Delete(&model.ShopIPRange{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&model.Shop{}, id).Error
+	})
 }
 
 func (r *ShopRepository) FindByID(id uint) (*model.Shop, error) {
@@ -179,14 +252,98 @@ func (r *ShopRepository) FindByID(id uint) (*model.Shop, error) {
 	return &s, nil
 }
 
+// FindByIDWithTrashed also returns soft-deleted shops
+func (r *ShopRepository) FindByIDWithTrashed(id uint) (*model.Shop, error) {
+	var s model.Shop
+	if err := r.db.Unscoped().First(&s, id).Error; err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// FindByDomain resolves a host through shop_domains: an exact entry wins,
+// otherwise the most specific wildcard (*.shop.vn matches a.b.shop.vn)
 func (r *ShopRepository) FindByDomain(domain string) (*model.Shop, error) {
 	var s model.Shop
-	if err := r.db.Where("domain = ?", domain).First(&s).Error; err != nil {
+	err := r.db.Joins("JOIN shop_domains ON shop_domains.shop_id = shops.id").
+		Where("shop_domains.domain = ?", domain).First(&s).Error
+	if err == nil {
+		return &s, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	wildcards := model.DomainWildcards(domain)
+	if len(wildcards) == 0 {
+		return nil, err
+	}
+	if err := r.db.Joins("JOIN shop_domains ON shop_domains.shop_id = shops.id").
+		Where("shop_domains.domain IN ?", wildcards).
+		Order("CHAR_LENGTH(shop_domains.domain) DESC").First(&s).Error; err != nil {
 		return nil, err
 	}
 	return &s, nil
 }
 
+// FindMany resolves many UUIDs and host names with one query. Hosts map to
+// their shop through an exact entry or, failing that, the most specific
+// wildcard; keys without a match are absent from the result.
+func (r *ShopRepository) FindMany(uuids, domains []string) (map[string]*model.Shop, map[string]*model.Shop, error) {
+	byUUID := map[string]*model.Shop{}
+	byDomain := map[string]*model.Shop{}
+	if len(uuids) == 0 && len(domains) == 0 {
+		return byUUID, byDomain, nil
+	}
+	candidates := make([]string, 0, len(domains)*3)
+	for _, d := range domains {
+		candidates = append(candidates, d)
+		candidates = append(candidates, model.DomainWildcards(d)...)
+	}
+	// IN () is invalid SQL; an impossible value keeps the query shape fixed
+	if len(uuids) == 0 {
+		uuids = []string{""}
+	}
+	if len(candidates) == 0 {
+		candidates = []string{""}
+	}
+	var rows []struct {
+		model.Shop
+		MatchedDomain string
+	}
+	if err := r.db.Model(&model.Shop{}).
+		Select("shops.*, shop_domains.domain AS matched_domain").
+		Joins("JOIN shop_domains ON shop_domains.shop_id = shops.id").
+		Where("shops.uuid IN ? OR shop_domains.domain IN ?", uuids, candidates).
+		Scan(&rows).Error; err != nil {
+		return nil, nil, err
+	}
+	entries := map[string]*model.Shop{}
+	for i := range rows {
+		m := &rows[i].Shop
+		if _, ok := byUUID[m.UUID]; !ok {
+			byUUID[m.UUID] = m
+		}
+		entries[rows[i].MatchedDomain] = m
+	}
+	for _, d := range domains {
+		for _, key := range append([]string{d}, model.DomainWildcards(d)...) {
+			if m, ok := entries[key]; ok {
+				byDomain[d] = m
+				break
+			}
+		}
+	}
+	return byUUID, byDomain, nil
+}
+
+// DomainNames lists every domain of a shop, primary first
+func (r *ShopRepository) DomainNames(shopID uint) ([]string, error) {
+	var out []string
+	err := r.db.Model(&model.ShopDomain{}).Where("shop_id = ?", shopID).
+		Order("kind = 'primary' DESC").Order("id ASC").Pluck("domain", &out).Error
+	return out, err
+}
+
 func (r *ShopRepository) FindByUUID(uuid string) (*model.Shop, error) {
 	var s model.Shop
 	if err := r.db.Where("uuid = ?", uuid).First(&s).Error; err != nil {
@@ -195,8 +352,17 @@ func (r *ShopRepository) FindByUUID(uuid string) (*model.Shop, error) {
 	return &s, nil
 }
 
+// Update saves the shop and keeps its primary shop_domains row in sync
 func (r *ShopRepository) Update(s *model.Shop) error {
-	return r.db.Omit("created_at").Save(s).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("created_at").Save(s).Error; err != nil {
+			return err
+		}
+		// a new primary domain has to be verified again
+		return tx.Model(&model.ShopDomain{}).
+			Where("shop_id = ? AND kind = ? AND domain <> ?", s.ID, model.ShopDomainPrimary, s.Domain).
+			Updates(map[string]interface{}{"domain": s.Domain, "verified_at": nil, "verify_method": "", "checked_at": nil, "check_error": ""}).Error
+	})
 }
 
 func (r *ShopRepository) DeleteByID(id uint) error {
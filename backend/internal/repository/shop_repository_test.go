@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"metronic/internal/model"
+)
+
+// dryRunDB builds statements without a server; each executed statement is
+// appended to stmts with its bound values
+func dryRunDB(t *testing.T, stmts *[]string) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(mysql.New(mysql.Config{DSN: "dry:run@tcp(127.0.0.1:1)/dry", SkipInitializeWithVersion: true}),
+		&gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true, Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := func(tx *gorm.DB) {
+		*stmts = append(*stmts, fmt.Sprintf("%s %v", tx.Statement.SQL.String(), tx.Statement.Vars))
+	}
+	db.Callback().Create().After("gorm:create").Register("test:record", record)
+	db.Callback().Update().After("gorm:update").Register("test:record", record)
+	return db
+}
+
+func TestCreateShopWritesInactive(t *testing.T) {
+	var stmts []string
+	db := dryRunDB(t, &stmts)
+	// the id stands in for the auto-increment value a dry run cannot return
+	sh := &model.Shop{ID: 9, UUID: "u", Domain: "shop.example", Active: false}
+	if err := createShop(db, sh); err != nil {
+		t.Fatal(err)
+	}
+	if sh.Active {
+		t.Fatal("the column default was left in the returned shop")
+	}
+	if len(stmts) != 3 || !strings.HasPrefix(stmts[1], "UPDATE `shops` SET `active`=?") || !strings.Contains(stmts[1], "[false") {
+		t.Fatalf("statements:\n%s", strings.Join(stmts, "\n"))
+	}
+
+	stmts = nil
+	if err := createShop(db, &model.Shop{ID: 10, UUID: "v", Domain: "other.example", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	if len(stmts) != 2 || strings.HasPrefix(stmts[1], "UPDATE") {
+		t.Fatalf("an active shop needs no update:\n%s", strings.Join(stmts, "\n"))
+	}
+}
+
+func TestShopCreateKeepsInactive(t *testing.T) {
+	db := testDB(t, &model.Shop{}, &model.ShopDomain{})
+	repo := NewShopRepository(db)
+	for _, active := range []bool{false, true} {
+		sh := &model.Shop{Domain: fmt.Sprintf("active-%v.example", active), Active: active}
+		if err := repo.Create(sh); err != nil {
+			t.Fatal(err)
+		}
+		got, err := repo.FindByID(sh.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Active != active {
+			t.Fatalf("created with active=%v, stored %v", active, got.Active)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package repository
+
+import (
+    "metronic/internal/model"
+
+    "gorm.io/gorm"
+)
+
+type ShopSuspensionRepository struct {
+    db *gorm.DB
+}
+
+func NewShopSuspensionRepository(db *gorm.DB) *ShopSuspensionRepository {
+    return &ShopSuspensionRepository{db: db}
+}
+
+// WithTx returns a copy bound to tx; a nil tx keeps the current handle
+func (r *ShopSuspensionRepository) WithTx(tx *gorm.DB) *ShopSuspensionRepository {
+    if tx == nil {
+        return r
+    }
+    return &ShopSuspensionRepository{db: tx}
+}
+
+func (r *ShopSuspensionRepository) Create(rec *model.ShopSuspension) error {
+    return r.db.Create(rec).Error
+}
+
+func (r *ShopSuspensionRepository) ListByShopID(shopID uint) ([]model.ShopSuspension, error) {
+    var items []model.ShopSuspension
+    if err := r.db.Where("shop_id = ?", shopID).Order("id DESC").Find(&items).Error; err != nil {
+        return nil, err
+    }
+    return items, nil
+}
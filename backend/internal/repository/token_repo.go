@@ -23,6 +23,37 @@ func (r *TokenRepository) Create(t *domain.Token) error {
 	return r.db.Create(t).Error
 }
 
+func (r *TokenRepository) FindByID(id uint) (*domain.Token, error) {
+	var tok domain.Token
+	if err := r.db.First(&tok, id).Error; err != nil {
+		return nil, err
+	}
+	return &tok, nil
+}
+
+func (r *TokenRepository) activeByUser(userID uint, kinds []string, now time.Time) *gorm.DB {
+	return r.db.Model(&domain.Token{}).
+		Where("user_id = ? AND revoked = ? AND expires_at > ?", userID, false, now).
+		Where("kind IN ?", kinds)
+}
+
+func (r *TokenRepository) ListActiveByUserID(userID uint, kinds []string, now time.Time) ([]domain.Token, error) {
+	var items []domain.Token
+	if err := r.activeByUser(userID, kinds, now).Order("id DESC").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *TokenRepository) CountActiveByUserID(userID uint, kinds []string, now time.Time) (int64, error) {
+	var n int64
+	// Tokens issued before families existed count as their own session
+	err := r.activeByUser(userID, kinds, now).
+		Select("COUNT(DISTINCT CASE WHEN family_id = '' OR family_id IS NULL THEN CONCAT('id:', id) ELSE family_id END)").
+		Scan(&n).Error
+	return n, err
+}
+
 func (r *TokenRepository) FindByHash(hash string) (*domain.Token, error) {
 	var tok domain.Token
 	if err := r.db.Where("token_hash = ?", hash).First(&tok).Error; err != nil {
@@ -35,10 +66,29 @@ func (r *TokenRepository) RevokeByID(id uint) error {
 	return r.db.Model(&domain.Token{}).Where("id = ?", id).Update("revoked", true).Error
 }
 
+func (r *TokenRepository) RevokeIfActive(id uint) (bool, error) {
+	res := r.db.Model(&domain.Token{}).Where("id = ? AND revoked = ?", id, false).Update("revoked", true)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func (r *TokenRepository) RevokeFamily(familyID string) error {
+	if familyID == "" {
+		return nil
+	}
+	return r.db.Model(&domain.Token{}).Where("family_id = ?", familyID).Update("revoked", true).Error
+}
+
 func (r *TokenRepository) RevokeAllByUserID(userID uint) error {
 	return r.db.Model(&domain.Token{}).Where("user_id = ?", userID).Update("revoked", true).Error
 }
 
+func (r *TokenRepository) RevokeAllByUserIDAndKind(userID uint, kind string) error {
+	return r.db.Model(&domain.Token{}).Where("user_id = ? AND kind = ?", userID, kind).Update("revoked", true).Error
+}
+
 func (r *TokenRepository) UpdateUsage(id uint, ip, ua string, t time.Time) error {
 	updates := map[string]interface{}{"last_used_at": t}
 	if ip != "" {
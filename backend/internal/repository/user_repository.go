@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"metronic/internal/model"
 
 	"gorm.io/gorm"
@@ -15,6 +17,14 @@ func NewUserRepository(db *gorm.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
+// WithTx returns a copy bound to tx; a nil tx keeps the current handle
+func (r *UserRepository) WithTx(tx *gorm.DB) *UserRepository {
+    if tx == nil {
+        return r
+    }
+    return &UserRepository{db: tx}
+}
+
 func (r *UserRepository) Create(user *model.User) error {
     return r.db.Create(user).Error
 }
@@ -27,6 +37,13 @@ func (r *UserRepository) List() ([]model.User, error) {
     return users, nil
 }
 
+// Count returns the number of users
+func (r *UserRepository) Count() (int64, error) {
+    var n int64
+    err := r.db.Model(&model.User{}).Count(&n).Error
+    return n, err
+}
+
 func (r *UserRepository) FindByEmail(email string) (*model.User, error) {
     var user model.User
     if err := r.db.Preload("Roles").Where("email = ?", email).First(&user).Error; err != nil {
@@ -47,6 +64,40 @@ func (r *UserRepository) Update(user *model.User) error {
     return r.db.Save(user).Error
 }
 
+// AdvanceTOTPStep records step as the last used TOTP step only if it is newer,
+// so a code can never be accepted twice even by concurrent requests
+func (r *UserRepository) AdvanceTOTPStep(id uint, step int64) (bool, error) {
+    res := r.db.Model(&model.User{}).Where("id = ? AND totp_last_step < ?", id, step).Update("totp_last_step", step)
+    if res.Error != nil {
+        return false, res.Error
+    }
+    return res.RowsAffected == 1, nil
+}
+
+// IncrementFailedLogins bumps the failure counter atomically and returns the new value
+func (r *UserRepository) IncrementFailedLogins(id uint) (int, error) {
+    if err := r.db.Model(&model.User{}).Where("id = ?", id).
+        UpdateColumn("failed_login_count", gorm.Expr("failed_login_count + 1")).Error; err != nil {
+        return 0, err
+    }
+    var n int
+    err := r.db.Model(&model.User{}).Where("id = ?", id).Pluck("failed_login_count", &n).Error
+    return n, err
+}
+
+// LockAccount locks the account until t and restarts the failure counter, so
+// the account gets a full LockAfter attempts once the lock expires
+func (r *UserRepository) LockAccount(id uint, until time.Time) error {
+    return r.db.Model(&model.User{}).Where("id = ?", id).
+        UpdateColumns(map[string]interface{}{"failed_login_count": 0, "locked_until": until}).Error
+}
+
+// ResetLoginFailures clears the failure counter and any lock
+func (r *UserRepository) ResetLoginFailures(id uint) error {
+    return r.db.Model(&model.User{}).Where("id = ?", id).
+        UpdateColumns(map[string]interface{}{"failed_login_count": 0, "locked_until": nil}).Error
+}
+
 func (r *UserRepository) DeleteByID(id uint) error {
     return r.db.Delete(&model.User{}, id).Error
 }
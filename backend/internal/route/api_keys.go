@@ -0,0 +1,21 @@
+package route
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"metronic/internal/domain"
+	"metronic/internal/handler"
+	"metronic/internal/middleware"
+)
+
+// APIKeysRouter mounts personal API key management routes.
+// Keys cannot manage keys, so these routes require an interactive login.
+func APIKeysRouter(r *gin.RouterGroup, h *handler.APIKeyHandler, tokens domain.TokenRepository) {
+	auth := r.Group("/")
+	auth.Use(middleware.Auth(tokens), middleware.SessionOnly())
+
+	auth.GET("/auth/api-keys", h.ListAPIKeys)
+	auth.GET("/auth/api-keys/scopes", h.ListScopes)
+	auth.POST("/auth/api-keys", h.CreateAPIKey)
+	auth.DELETE("/auth/api-keys/:id", h.RevokeAPIKey)
+}
@@ -0,0 +1,20 @@
+package route
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"metronic/internal/domain"
+	"metronic/internal/handler"
+	"metronic/internal/middleware"
+	"metronic/internal/model"
+)
+
+// AuditRouter mounts the audit trail routes
+func AuditRouter(r *gin.RouterGroup, h *handler.AuditHandler, tokens domain.TokenRepository, perms domain.PermissionRepository) {
+	auth := r.Group("/")
+	auth.Use(middleware.Auth(tokens))
+	can := func(code string) gin.HandlerFunc { return middleware.RequirePermission(perms, code) }
+
+	auth.GET("/audit-events", can(model.PermAuditView), h.ListAuditEvents)
+	auth.GET("/shops/:id/timeline", can(model.PermShopsView), h.ShopTimeline)
+}
@@ -9,16 +9,21 @@ import (
 )
 
 // AuthRouter mounts authentication routes under the given router group.
-func AuthRouter(r *gin.RouterGroup, h *handler.AuthHandler, tokens domain.TokenRepository) {
+// rateLimit is the per-IP requests/minute allowed on the public auth endpoints.
+func AuthRouter(r *gin.RouterGroup, h *handler.AuthHandler, tokens domain.TokenRepository, rateLimit int) {
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
 
-	r.POST("/auth/register", h.Register)
-	r.POST("/auth/login", h.Login)
+	public := r.Group("/")
+	public.Use(middleware.RateLimit(rateLimit))
+	public.POST("/auth/register", h.Register)
+	public.POST("/auth/login", h.Login)
+	public.POST("/auth/refresh", h.Refresh)
+	public.POST("/auth/mfa/verify", h.VerifyMFA)
 
 	auth := r.Group("/")
-	auth.Use(middleware.Auth(tokens))
+	auth.Use(middleware.Auth(tokens), middleware.SessionOnly())
 	auth.POST("/auth/logout", h.Logout)
 	auth.GET("/auth/me", h.Me)
 }
@@ -5,13 +5,16 @@ import (
     "metronic/internal/domain"
     "metronic/internal/handler"
     "metronic/internal/middleware"
+    "metronic/internal/model"
 )
 
 // CustomersRouter mounts customer routes
-func CustomersRouter(r *gin.RouterGroup, h *handler.CustomerHandler, tokens domain.TokenRepository) {
+func CustomersRouter(r *gin.RouterGroup, h *handler.CustomerHandler, tokens domain.TokenRepository, perms domain.PermissionRepository) {
     auth := r.Group("/")
     auth.Use(middleware.Auth(tokens))
-    auth.GET("/customers", h.ListCustomers)
-    auth.GET("/customers/:id", h.GetCustomer)
-    auth.DELETE("/customers/:id", h.DeleteCustomer)
+    can := func(code string) gin.HandlerFunc { return middleware.RequirePermission(perms, code) }
+
+    auth.GET("/customers", can(model.PermCustomersView), h.ListCustomers)
+    auth.GET("/customers/:id", can(model.PermCustomersView), h.GetCustomer)
+    auth.DELETE("/customers/:id", can(model.PermCustomersManage), h.DeleteCustomer)
 }
@@ -0,0 +1,25 @@
+package route
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"metronic/internal/domain"
+	"metronic/internal/handler"
+	"metronic/internal/middleware"
+	"metronic/internal/model"
+)
+
+// InvitationsRouter mounts invitation management and the public accept routes
+func InvitationsRouter(r *gin.RouterGroup, h *handler.InvitationHandler, tokens domain.TokenRepository, perms domain.PermissionRepository, rateLimit int) {
+	public := r.Group("/")
+	public.Use(middleware.RateLimit(rateLimit))
+	public.GET("/auth/invitations", h.GetInvitation)
+	public.POST("/auth/invitations/accept", h.AcceptInvitation)
+
+	auth := r.Group("/")
+	auth.Use(middleware.Auth(tokens), middleware.RequirePermission(perms, model.PermUsersManage))
+	auth.GET("/invitations", h.ListInvitations)
+	auth.POST("/invitations", h.CreateInvitation)
+	auth.POST("/invitations/:id/resend", h.ResendInvitation)
+	auth.DELETE("/invitations/:id", h.RevokeInvitation)
+}
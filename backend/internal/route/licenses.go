@@ -0,0 +1,19 @@
+package route
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"metronic/internal/domain"
+	"metronic/internal/handler"
+	"metronic/internal/middleware"
+	"metronic/internal/model"
+)
+
+// LicensesRouter mounts the license download and public refresh routes
+func LicensesRouter(r *gin.RouterGroup, h *handler.LicenseHandler, tokens domain.TokenRepository, perms domain.PermissionRepository) {
+	r.GET("/licenses/:shop_uuid", h.RefreshLicense)
+
+	auth := r.Group("/")
+	auth.Use(middleware.Auth(tokens), middleware.RequirePermission(perms, model.PermShopsView))
+	auth.GET("/shops/:id/license", h.DownloadLicense)
+}
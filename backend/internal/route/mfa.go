@@ -0,0 +1,24 @@
+package route
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"metronic/internal/domain"
+	"metronic/internal/handler"
+	"metronic/internal/middleware"
+	"metronic/internal/model"
+)
+
+// MFARouter mounts two-factor enrolment routes and the admin reset
+func MFARouter(r *gin.RouterGroup, h *handler.MFAHandler, tokens domain.TokenRepository, perms domain.PermissionRepository) {
+	auth := r.Group("/")
+	auth.Use(middleware.Auth(tokens), middleware.SessionOnly())
+
+	auth.GET("/auth/mfa", h.Status)
+	auth.POST("/auth/mfa/totp/setup", h.SetupTOTP)
+	auth.POST("/auth/mfa/totp/confirm", h.ConfirmTOTP)
+	auth.POST("/auth/mfa/totp/disable", h.DisableTOTP)
+	auth.POST("/auth/mfa/recovery-codes", h.RegenerateRecoveryCodes)
+
+	auth.DELETE("/users/:id/mfa", middleware.RequirePermission(perms, model.PermUsersManage), h.ResetUserMFA)
+}
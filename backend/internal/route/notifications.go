@@ -0,0 +1,24 @@
+package route
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"metronic/internal/domain"
+	"metronic/internal/handler"
+	"metronic/internal/middleware"
+	"metronic/internal/model"
+)
+
+// NotificationsRouter mounts notification channel management
+func NotificationsRouter(r *gin.RouterGroup, h *handler.NotificationHandler, tokens domain.TokenRepository, perms domain.PermissionRepository) {
+	auth := r.Group("/")
+	auth.Use(middleware.Auth(tokens))
+	auth.Use(middleware.RequirePermission(perms, model.PermNotifyManage))
+
+	auth.GET("/notification-channels", h.ListChannels)
+	auth.POST("/notification-channels", h.CreateChannel)
+	auth.POST("/notification-channels/:id", h.UpdateChannel)
+	auth.DELETE("/notification-channels/:id", h.DeleteChannel)
+	auth.POST("/notification-channels/:id/test", h.TestChannel)
+	auth.GET("/notification-deliveries", h.ListDeliveries)
+}
@@ -0,0 +1,16 @@
+package route
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"metronic/internal/handler"
+	"metronic/internal/middleware"
+)
+
+// PasswordResetRouter mounts the public forgot/reset password routes
+func PasswordResetRouter(r *gin.RouterGroup, h *handler.PasswordResetHandler, rateLimit int) {
+	public := r.Group("/")
+	public.Use(middleware.RateLimit(rateLimit))
+	public.POST("/auth/forgot-password", h.ForgotPassword)
+	public.POST("/auth/reset-password", h.ResetPassword)
+}
@@ -0,0 +1,25 @@
+package route
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"metronic/internal/domain"
+	"metronic/internal/handler"
+	"metronic/internal/middleware"
+	"metronic/internal/model"
+)
+
+// RolesRouter mounts RBAC administration routes
+func RolesRouter(r *gin.RouterGroup, h *handler.RoleHandler, tokens domain.TokenRepository, perms domain.PermissionRepository) {
+	auth := r.Group("/")
+	auth.Use(middleware.Auth(tokens), middleware.RequirePermission(perms, model.PermRolesManage))
+
+	auth.GET("/permissions", h.ListPermissions)
+	auth.GET("/roles", h.ListRoles)
+	auth.POST("/roles", h.CreateRole)
+	auth.GET("/roles/:id", h.GetRole)
+	auth.DELETE("/roles/:id", h.DeleteRole)
+	auth.POST("/roles/:id/permissions", h.AttachPermissions)
+	auth.DELETE("/roles/:id/permissions/:code", h.DetachPermission)
+	auth.POST("/users/:id/roles", h.AssignUserRoles)
+}
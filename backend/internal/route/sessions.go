@@ -0,0 +1,27 @@
+package route
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"metronic/internal/domain"
+	"metronic/internal/handler"
+	"metronic/internal/middleware"
+	"metronic/internal/model"
+)
+
+// SessionsRouter mounts session listing and revocation routes
+func SessionsRouter(r *gin.RouterGroup, h *handler.SessionHandler, tokens domain.TokenRepository, perms domain.PermissionRepository) {
+	auth := r.Group("/")
+	auth.Use(middleware.Auth(tokens), middleware.SessionOnly())
+
+	// own sessions
+	auth.GET("/auth/sessions", h.ListMySessions)
+	auth.DELETE("/auth/sessions/:id", h.RevokeMySession)
+
+	// admin: any user's sessions
+	admin := auth.Group("/")
+	admin.Use(middleware.RequirePermission(perms, model.PermUsersManage))
+	admin.GET("/users/:id/sessions", h.ListUserSessions)
+	admin.DELETE("/users/:id/sessions", h.RevokeAllUserSessions)
+	admin.DELETE("/users/:id/sessions/:session_id", h.RevokeUserSession)
+}
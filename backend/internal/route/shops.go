@@ -6,36 +6,65 @@ import (
     "metronic/internal/domain"
     "metronic/internal/handler"
     "metronic/internal/middleware"
+    "metronic/internal/model"
 )
 
 // ShopsRouter mounts shop CRUD routes
-func ShopsRouter(r *gin.RouterGroup, h *handler.ShopHandler, tokens domain.TokenRepository, shopCustH *handler.ShopCustomerHandler) {
-    // Public (no-auth) health/check endpoint for shop expiry
-    r.GET("/shops/check", h.CheckStatus)
+func ShopsRouter(r *gin.RouterGroup, h *handler.ShopHandler, tokens domain.TokenRepository, perms domain.PermissionRepository, shopCustH *handler.ShopCustomerHandler) {
+    // Public (no-auth) health/check endpoint for shop expiry, limited per client IP
+    throttle := h.CheckThrottle()
+    r.GET("/shops/check", throttle, h.CheckStatus)
+    r.GET("/shops/check/keys", h.CheckKeys)
+    r.POST("/shops/check/batch", throttle, h.CheckBatch)
 
     auth := r.Group("/")
     auth.Use(middleware.Auth(tokens))
+    can := func(code string) gin.HandlerFunc { return middleware.RequirePermission(perms, code) }
 
-    auth.GET("/shops", h.ListShops)
-    auth.GET("/shops/stats", h.ShopStats)
-    auth.POST("/shops", h.CreateShop)
-    auth.GET("/shops/:id", h.GetShop)
-    auth.POST("/shops/:id", h.UpdateShop)
-    auth.DELETE("/shops/:id", h.DeleteShop)
-    auth.DELETE("/shops/:id/force", h.ForceDeleteShop)
-    auth.POST("/shops/:id/restore", h.RestoreShop)
+    auth.GET("/shops", can(model.PermShopsView), h.ListShops)
+    auth.GET("/shops/stats", can(model.PermShopsView), h.ShopStats)
+    auth.POST("/shops", can(model.PermShopsManage), h.CreateShop)
+    auth.GET("/shops/:id", can(model.PermShopsView), h.GetShop)
+    auth.POST("/shops/:id", can(model.PermShopsManage), h.UpdateShop)
+    auth.DELETE("/shops/:id", can(model.PermShopsDelete), h.DeleteShop)
+    auth.DELETE("/shops/:id/force", can(model.PermShopsDelete), h.ForceDeleteShop)
+    auth.POST("/shops/:id/restore", can(model.PermShopsManage), h.RestoreShop)
     // renewals
-    auth.POST("/shops/:id/renew", h.RenewShop)
-    auth.GET("/shops/:id/renewals", h.ListRenewals)
-    auth.POST("/shops/:id/revoke", h.RevokeShop)
-    auth.POST("/shops/:id/expired-at", h.SetExpiredAt)
-    auth.POST("/shops/notify/not-over-1m", h.NotifyNotOver1m)
-    auth.GET("/shops/:id/api-logs", h.ListAPILogs)
+    auth.POST("/shops/:id/renew", can(model.PermShopsRenew), h.RenewShop)
+    auth.GET("/shops/:id/renewals", can(model.PermShopsView), h.ListRenewals)
+    auth.POST("/shops/:id/revoke", can(model.PermShopsRenew), h.RevokeShop)
+    auth.POST("/shops/:id/expired-at", can(model.PermShopsRenew), h.SetExpiredAt)
+    auth.POST("/shops/:id/grace", can(model.PermShopsRenew), h.SetGracePeriod)
+    // domains and aliases
+    auth.GET("/shops/:id/domains", can(model.PermShopsView), h.ListDomains)
+    auth.POST("/shops/:id/domains", can(model.PermShopsManage), h.AddDomain)
+    auth.DELETE("/shops/:id/domains/:domain_id", can(model.PermShopsManage), h.RemoveDomain)
+    auth.POST("/shops/:id/domains/:domain_id/verify", can(model.PermShopsManage), h.VerifyDomain)
+    auth.POST("/shops/:id/domain-limit", can(model.PermShopsManage), h.SetDomainLimit)
+    // IP allowlist for /shops/check
+    auth.GET("/shops/:id/ip-ranges", can(model.PermShopsView), h.ListIPRanges)
+    auth.POST("/shops/:id/ip-ranges", can(model.PermShopsManage), h.AddIPRange)
+    auth.DELETE("/shops/:id/ip-ranges/:range_id", can(model.PermShopsManage), h.RemoveIPRange)
+    // manual suspension, independent of expiry
+    auth.POST("/shops/:id/suspend", can(model.PermShopsRenew), h.SuspendShop)
+    auth.POST("/shops/:id/resume", can(model.PermShopsRenew), h.ResumeShop)
+    auth.GET("/shops/:id/suspensions", can(model.PermShopsView), h.ListSuspensions)
+    auth.POST("/shops/notify/not-over-1m", can(model.PermNotifyManage), h.NotifyNotOver1m)
+    auth.GET("/shops/:id/api-logs", can(model.PermAPILogsView), h.ListAPILogs)
+    auth.GET("/shops/:id/api-logs/analytics", can(model.PermAPILogsView), h.ShopAPILogAnalytics)
     // global api logs
-    auth.GET("/api-logs", h.ListAllAPILogs)
+    auth.GET("/api-logs", can(model.PermAPILogsView), h.ListAllAPILogs)
+    auth.GET("/api-logs/analytics", can(model.PermAPILogsView), h.APILogAnalytics)
+    auth.GET("/api-logs/stats", can(model.PermAPILogsView), h.APILogStats)
+    auth.POST("/api-logs/prune", can(model.PermAPILogsManage), h.PruneAPILogs)
+    // licence abuse findings from check traffic
+    auth.GET("/abuse-findings", can(model.PermAPILogsView), h.ListAbuseFindings)
+    auth.GET("/shops/:id/abuse-findings", can(model.PermAPILogsView), h.ListShopAbuseFindings)
+    auth.POST("/abuse-findings/scan", can(model.PermAPILogsManage), h.ScanAbuse)
+    auth.POST("/abuse-findings/:id/review", can(model.PermAPILogsManage), h.ReviewAbuseFinding)
 
     if shopCustH != nil {
-        auth.POST("/shops/:id/customers", shopCustH.Assign)
-        auth.DELETE("/shops/:id/customers/:customer_id", shopCustH.Remove)
+        auth.POST("/shops/:id/customers", can(model.PermCustomersManage), shopCustH.Assign)
+        auth.DELETE("/shops/:id/customers/:customer_id", can(model.PermCustomersManage), shopCustH.Remove)
     }
 }
@@ -6,16 +6,18 @@ import (
     "metronic/internal/domain"
     "metronic/internal/handler"
     "metronic/internal/middleware"
+    "metronic/internal/model"
 )
 
 // UsersRouter mounts user CRUD routes
-func UsersRouter(r *gin.RouterGroup, h *handler.UserHandler, tokens domain.TokenRepository) {
+func UsersRouter(r *gin.RouterGroup, h *handler.UserHandler, tokens domain.TokenRepository, perms domain.PermissionRepository) {
     auth := r.Group("/")
-    auth.Use(middleware.Auth(tokens))
+    auth.Use(middleware.Auth(tokens), middleware.RequirePermission(perms, model.PermUsersManage))
 
     auth.GET("/users", h.ListUsers)
     auth.POST("/users", h.CreateUser)
     auth.GET("/users/:id", h.GetUser)
     auth.POST("/users/:id", h.UpdateUser)
     auth.DELETE("/users/:id", h.DeleteUser)
+    auth.POST("/users/:id/unlock", h.UnlockUser)
 }
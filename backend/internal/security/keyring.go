@@ -0,0 +1,152 @@
+package security
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// KeyRing holds the Ed25519 keys used to sign public responses. One key is
+// active for signing; the others are still published so clients can verify
+// documents signed before a rotation.
+type KeyRing struct {
+	active string
+	keys   map[string]ed25519.PrivateKey
+}
+
+// PublicKey is the published form of a ring key
+type PublicKey struct {
+	KID       string `json:"kid"`
+	Alg       string `json:"alg"`
+	PublicKey string `json:"public_key"`
+	Active    bool   `json:"active"`
+}
+
+// ParseKeyRing parses "kid:base64seed,kid2:base64seed" (32-byte Ed25519 seeds).
+// activeKID selects the signing key; empty means the first one listed.
+func ParseKeyRing(spec, activeKID string) (*KeyRing, error) {
+	ring := &KeyRing{keys: map[string]ed25519.PrivateKey{}}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		kid, enc, ok := strings.Cut(item, ":")
+		if !ok || kid == "" {
+			return nil, fmt.Errorf("signing key %q: expected kid:base64seed", item)
+		}
+		seed, err := base64.StdEncoding.DecodeString(enc)
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return nil, fmt.Errorf("signing key %q: seed must be %d base64 bytes", kid, ed25519.SeedSize)
+		}
+		if _, dup := ring.keys[kid]; dup {
+			return nil, fmt.Errorf("signing key %q listed twice", kid)
+		}
+		ring.keys[kid] = ed25519.NewKeyFromSeed(seed)
+		if ring.active == "" {
+			ring.active = kid
+		}
+	}
+	if len(ring.keys) == 0 {
+		return nil, errors.New("no signing keys configured")
+	}
+	if activeKID != "" {
+		if _, ok := ring.keys[activeKID]; !ok {
+			return nil, fmt.Errorf("active signing key %q is not in the key ring", activeKID)
+		}
+		ring.active = activeKID
+	}
+	return ring, nil
+}
+
+// NewEphemeralKeyRing returns a ring with one random key, for setups without
+// configured keys. Signatures stop verifying after a restart.
+func NewEphemeralKeyRing() (*KeyRing, error) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &KeyRing{active: "ephemeral", keys: map[string]ed25519.PrivateKey{"ephemeral": priv}}, nil
+}
+
+// Sign signs msg with the active key and returns the key ID and raw signature
+func (k *KeyRing) Sign(msg []byte) (string, []byte) {
+	return k.active, ed25519.Sign(k.keys[k.active], msg)
+}
+
+// ActiveKID is the ID of the key Sign and Seal use
+func (k *KeyRing) ActiveKID() string {
+	return k.active
+}
+
+// Envelope is a signed JSON document: Sig signs the exact Payload bytes.
+// Both are base64url without padding; verifiers read fields from Payload only.
+type Envelope struct {
+	KID     string `json:"kid"`
+	Alg     string `json:"alg"`
+	Payload string `json:"payload"`
+	Sig     string `json:"sig"`
+}
+
+// Seal encodes v as JSON and signs it with the active key. Pass a struct so
+// the field order, and with it the signed bytes, is fixed.
+func (k *KeyRing) Seal(v interface{}) (*Envelope, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	kid, sig := k.Sign(body)
+	return &Envelope{
+		KID:     kid,
+		Alg:     "Ed25519",
+		Payload: base64.RawURLEncoding.EncodeToString(body),
+		Sig:     base64.RawURLEncoding.EncodeToString(sig),
+	}, nil
+}
+
+// Open verifies e and returns its payload bytes
+func (k *KeyRing) Open(e *Envelope) ([]byte, error) {
+	body, err := base64.RawURLEncoding.DecodeString(e.Payload)
+	if err != nil {
+		return nil, ErrBadSignature
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(e.Sig)
+	if err != nil || e.Alg != "Ed25519" || !k.Verify(e.KID, body, sig) {
+		return nil, ErrBadSignature
+	}
+	return body, nil
+}
+
+// Verify checks sig over msg with the key kid
+func (k *KeyRing) Verify(kid string, msg, sig []byte) bool {
+	priv, ok := k.keys[kid]
+	if !ok {
+		return false
+	}
+	return ed25519.Verify(priv.Public().(ed25519.PublicKey), msg, sig)
+}
+
+// PublicKeys lists every ring key, active first
+func (k *KeyRing) PublicKeys() []PublicKey {
+	out := make([]PublicKey, 0, len(k.keys))
+	for kid, priv := range k.keys {
+		out = append(out, PublicKey{
+			KID:       kid,
+			Alg:       "Ed25519",
+			PublicKey: base64.StdEncoding.EncodeToString(priv.Public().(ed25519.PublicKey)),
+			Active:    kid == k.active,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Active != out[j].Active {
+			return out[i].Active
+		}
+		return out[i].KID < out[j].KID
+	})
+	return out
+}
@@ -0,0 +1,74 @@
+package security
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func seed(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(rune(b)), 32)))
+}
+
+func TestKeyRingRotation(t *testing.T) {
+	old, err := ParseKeyRing("k1:"+seed('a'), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kid, sig := old.Sign([]byte("payload"))
+	if kid != "k1" {
+		t.Fatalf("kid = %q", kid)
+	}
+
+	// after rotation k2 signs, k1 is still published and verifiable
+	ring, err := ParseKeyRing("k1:"+seed('a')+",k2:"+seed('b'), "k2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kid, _ := ring.Sign([]byte("x")); kid != "k2" || ring.ActiveKID() != "k2" {
+		t.Fatalf("active kid = %q, ActiveKID = %q", kid, ring.ActiveKID())
+	}
+	if !ring.Verify("k1", []byte("payload"), sig) {
+		t.Fatal("old signature should verify")
+	}
+	if ring.Verify("k2", []byte("payload"), sig) {
+		t.Fatal("signature must not verify under another key")
+	}
+	keys := ring.PublicKeys()
+	if len(keys) != 2 || keys[0].KID != "k2" || !keys[0].Active {
+		t.Fatalf("public keys = %+v", keys)
+	}
+}
+
+func TestParseKeyRingRejectsBadInput(t *testing.T) {
+	for _, spec := range []string{"", "nokid", "k1:short", "k1:" + seed('a') + ",k1:" + seed('b')} {
+		if _, err := ParseKeyRing(spec, ""); err == nil {
+			t.Fatalf("expected error for %q", spec)
+		}
+	}
+	if _, err := ParseKeyRing("k1:"+seed('a'), "k9"); err == nil {
+		t.Fatal("expected error for unknown active kid")
+	}
+}
+
+func TestSealOpen(t *testing.T) {
+	ring, err := ParseKeyRing("k1:"+seed('a'), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := ring.Seal(struct {
+		Status string `json:"status"`
+	}{"valid"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := ring.Open(env)
+	if err != nil || string(body) != `{"status":"valid"}` {
+		t.Fatalf("open = %s, %v", body, err)
+	}
+	forged := *env
+	forged.Payload = base64.RawURLEncoding.EncodeToString([]byte(`{"status":"expired"}`))
+	if _, err := ring.Open(&forged); err == nil {
+		t.Fatal("expected forged payload to fail")
+	}
+}
@@ -10,25 +10,66 @@ import (
 
 // AccessTokenTTL defines how long an access token is valid.
 // It defaults to 2 hours but can be overridden by ACCESS_TOKEN_TTL env var.
-var AccessTokenTTL = func() time.Duration {
-	if v := os.Getenv("ACCESS_TOKEN_TTL"); v != "" {
+var AccessTokenTTL = durationFromEnv("ACCESS_TOKEN_TTL", 2*time.Hour)
+
+// RefreshTokenTTL defines how long a refresh token is valid.
+// It defaults to 30 days but can be overridden by REFRESH_TOKEN_TTL env var.
+var RefreshTokenTTL = durationFromEnv("REFRESH_TOKEN_TTL", 30*24*time.Hour)
+
+// MFAChallengeTTL bounds the time between a password login and its 2FA code.
+var MFAChallengeTTL = durationFromEnv("MFA_CHALLENGE_TTL", 5*time.Minute)
+
+// PasswordResetTTL bounds how long an emailed reset link stays usable.
+var PasswordResetTTL = durationFromEnv("PASSWORD_RESET_TTL", time.Hour)
+
+// InviteTTL bounds how long an invitation link stays usable.
+var InviteTTL = durationFromEnv("INVITE_TTL", 7*24*time.Hour)
+
+func durationFromEnv(key string, def time.Duration) time.Duration {
+	if v := os.Getenv(key); v != "" {
 		if d, err := time.ParseDuration(v); err == nil {
 			return d
 		}
 	}
-	return 2 * time.Hour
-}()
+	return def
+}
 
 // GenerateToken returns the plain token, its sha256 hash, and expiry time.
 func GenerateToken() (string, string, time.Time) {
+	return GenerateTokenWithTTL(AccessTokenTTL)
+}
+
+// GenerateTokenWithTTL is GenerateToken with a caller-chosen lifetime.
+func GenerateTokenWithTTL(ttl time.Duration) (string, string, time.Time) {
 	b := make([]byte, 32)
 	_, _ = rand.Read(b)
 	plain := hex.EncodeToString(b)
 	hash := HashToken(plain)
-	expiresAt := time.Now().Add(AccessTokenTTL)
+	expiresAt := time.Now().Add(ttl)
 	return plain, hash, expiresAt
 }
 
+// APIKeyPrefix marks API keys so they are recognisable in logs and secret scanners.
+const APIKeyPrefix = "sbk_"
+
+// apiKeyDisplayLen is how much of a key (prefix included) is kept in clear for display.
+const apiKeyDisplayLen = len(APIKeyPrefix) + 8
+
+// GenerateAPIKey returns a new plain API key, its sha256 hash and its display prefix.
+func GenerateAPIKey() (string, string, string) {
+	b := make([]byte, 24)
+	_, _ = rand.Read(b)
+	plain := APIKeyPrefix + hex.EncodeToString(b)
+	return plain, HashToken(plain), plain[:apiKeyDisplayLen]
+}
+
+// NewFamilyID returns a random identifier for a token family.
+func NewFamilyID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
 // HashToken returns the sha256 hash of a token in hex encoding.
 func HashToken(plain string) string {
 	sum := sha256.Sum256([]byte(plain))
@@ -0,0 +1,23 @@
+package security
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateAPIKey(t *testing.T) {
+	plain, hash, prefix := GenerateAPIKey()
+	if !strings.HasPrefix(plain, APIKeyPrefix) {
+		t.Fatalf("expected key to start with %q, got %q", APIKeyPrefix, plain)
+	}
+	if !strings.HasPrefix(plain, prefix) || len(prefix) != len(APIKeyPrefix)+8 {
+		t.Fatalf("unexpected display prefix %q for %q", prefix, plain)
+	}
+	if hash != HashToken(plain) {
+		t.Fatal("expected hash to match HashToken of plain key")
+	}
+	other, _, _ := GenerateAPIKey()
+	if other == plain {
+		t.Fatal("expected distinct keys")
+	}
+}
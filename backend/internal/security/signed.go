@@ -0,0 +1,43 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// ErrBadSignature is returned for tokens that were tampered with or malformed
+var ErrBadSignature = errors.New("invalid token signature")
+
+var b64 = base64.RawURLEncoding
+
+// SignToken returns payload and its HMAC-SHA256 as "<payload>.<mac>", both base64url.
+func SignToken(secret []byte, payload string) string {
+	p := b64.EncodeToString([]byte(payload))
+	return p + "." + b64.EncodeToString(mac(secret, p))
+}
+
+// VerifySignedToken checks the MAC of a SignToken value and returns its payload.
+func VerifySignedToken(secret []byte, token string) (string, error) {
+	p, sig, ok := strings.Cut(strings.TrimSpace(token), ".")
+	if !ok {
+		return "", ErrBadSignature
+	}
+	got, err := b64.DecodeString(sig)
+	if err != nil || !hmac.Equal(got, mac(secret, p)) {
+		return "", ErrBadSignature
+	}
+	payload, err := b64.DecodeString(p)
+	if err != nil {
+		return "", ErrBadSignature
+	}
+	return string(payload), nil
+}
+
+func mac(secret []byte, msg string) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(msg))
+	return h.Sum(nil)
+}
@@ -0,0 +1,18 @@
+package security
+
+import "testing"
+
+func TestSignedTokenRoundTrip(t *testing.T) {
+	secret := []byte("test-secret")
+	tok := SignToken(secret, "42.1700000000.abc")
+	got, err := VerifySignedToken(secret, tok)
+	if err != nil || got != "42.1700000000.abc" {
+		t.Fatalf("got %q, %v", got, err)
+	}
+	if _, err := VerifySignedToken([]byte("other"), tok); err == nil {
+		t.Fatal("expected wrong secret to fail")
+	}
+	if _, err := VerifySignedToken(secret, "x"+tok); err == nil {
+		t.Fatal("expected tampered payload to fail")
+	}
+}
@@ -0,0 +1,67 @@
+package security
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+// RFC 6238 appendix B vectors for the SHA1 key "12345678901234567890"
+func TestHOTPMatchesRFC6238Vectors(t *testing.T) {
+	key := []byte("12345678901234567890")
+	cases := []struct {
+		unix int64
+		want string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+	for _, tc := range cases {
+		got := hotp(key, uint64(TOTPStep(time.Unix(tc.unix, 0))), 8)
+		if got != tc.want {
+			t.Fatalf("t=%d: got %s want %s", tc.unix, got, tc.want)
+		}
+	}
+}
+
+func TestVerifyTOTPWithFixedClock(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	now := time.Unix(1111111111, 0)
+	code, err := TOTPCode(secret, now)
+	if err != nil {
+		t.Fatalf("code: %v", err)
+	}
+	if code != "050471" {
+		t.Fatalf("got %s want 050471", code)
+	}
+	step, ok := VerifyTOTP(secret, code, now, 1)
+	if !ok || step != TOTPStep(now) {
+		t.Fatalf("expected code to verify at current step, got %d %v", step, ok)
+	}
+	// one step of drift is tolerated, two are not
+	if _, ok := VerifyTOTP(secret, code, now.Add(TOTPPeriod), 1); !ok {
+		t.Fatal("expected code to verify one step later")
+	}
+	if _, ok := VerifyTOTP(secret, code, now.Add(2*TOTPPeriod), 1); ok {
+		t.Fatal("expected code to fail two steps later")
+	}
+	if _, ok := VerifyTOTP(secret, "000000", now, 1); ok {
+		t.Fatal("expected wrong code to fail")
+	}
+}
+
+func TestGenerateTOTPSecretRoundTrip(t *testing.T) {
+	secret := GenerateTOTPSecret()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	code, err := TOTPCode(secret, now)
+	if err != nil {
+		t.Fatalf("code: %v", err)
+	}
+	if _, ok := VerifyTOTP(secret, code, now, 0); !ok {
+		t.Fatal("expected generated secret to verify its own code")
+	}
+}
@@ -0,0 +1,379 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"metronic/internal/hostname"
+	"metronic/internal/model"
+	"metronic/internal/notify"
+	"metronic/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrAbuseScanRunning is returned when a scan is requested while one is in progress
+	ErrAbuseScanRunning = errors.New("abuse scan is already running")
+	// ErrAbuseStatus is returned for a review status other than open, confirmed or dismissed
+	ErrAbuseStatus = errors.New("status must be open, confirmed or dismissed")
+)
+
+// abuseSampleSize caps the IPs and user agents kept as evidence per finding
+const abuseSampleSize = 10
+
+// AbuseConfig tunes the detector. MaxIPs <= 0 turns the ip_spread rule off.
+// IgnoreDomains (and their subdomains) never count as foreign, e.g. localhost
+// for developers running a copy of the site on their machine.
+type AbuseConfig struct {
+	Window        time.Duration
+	MaxIPs        int
+	AutoSuspend   bool
+	IgnoreDomains []string
+}
+
+// AbuseReport tells what one scan found
+type AbuseReport struct {
+	From      time.Time            `json:"from"`
+	To        time.Time            `json:"to"`
+	New       []model.AbuseFinding `json:"new"`
+	Updated   int                  `json:"updated"`
+	Suspended int                  `json:"suspended"`
+}
+
+// AbuseLogSource is the part of the API log repository the detector reads;
+// ShopAPILogRepository satisfies it
+type AbuseLogSource interface {
+	UUIDDomainUsage(from, to time.Time) ([]repository.APILogUsage, error)
+	IPSpread(from, to time.Time, maxIPs int) ([]repository.APILogUsage, error)
+	SampleClients(shopID uint, domains []string, from, to time.Time, n int) ([]string, []string, error)
+}
+
+// AbuseFindingStore keeps the findings; AbuseFindingRepository satisfies it
+type AbuseFindingStore interface {
+	FindSubject(shopID uint, kind, subject string) (*model.AbuseFinding, error)
+	FindByID(id uint) (*model.AbuseFinding, error)
+	Create(f *model.AbuseFinding) error
+	Save(f *model.AbuseFinding) error
+	ListPaged(page, limit int, shopID *uint, kind, status string) ([]model.AbuseFinding, int64, error)
+}
+
+// AbuseDetector scans recent /shops/check traffic for signs of a shop UUID
+// being reused on sites that are not the shop's own
+type AbuseDetector struct {
+	logs     AbuseLogSource
+	findings AbuseFindingStore
+	shops    *ShopService
+	audit    *AuditService
+	cfg      AbuseConfig
+	notices  *NotificationService
+	now      func() time.Time
+	running  sync.Mutex
+}
+
+func NewAbuseDetector(logs AbuseLogSource, findings AbuseFindingStore, shops *ShopService, cfg AbuseConfig) *AbuseDetector {
+	if cfg.Window <= 0 {
+		cfg.Window = 24 * time.Hour
+	}
+	return &AbuseDetector{logs: logs, findings: findings, shops: shops, cfg: cfg, now: time.Now}
+}
+
+// WithClock replaces the time source of scans and reviews (tests)
+func (d *AbuseDetector) WithClock(now func() time.Time) *AbuseDetector {
+	d.now = now
+	return d
+}
+
+// WithAudit records finding reviews in the shop's audit trail
+func (d *AbuseDetector) WithAudit(a *AuditService) *AbuseDetector {
+	d.audit = a
+	return d
+}
+
+// WithNotifications sends a summary of every scan that produced new findings
+// to the channels subscribed to abuse.finding
+func (d *AbuseDetector) WithNotifications(n *NotificationService) *AbuseDetector {
+	d.notices = n
+	return d
+}
+
+// abuseHit collects the usage behind one finding before it is stored
+type abuseHit struct {
+	shopID, owner uint
+	kind, subject string
+	params        []string
+	usage         repository.APILogUsage
+}
+
+// Run scans the last Window of check calls and stores what it finds. A
+// pattern already on record is refreshed in place and never re-notified.
+func (d *AbuseDetector) Run(ctx context.Context) (AbuseReport, error) {
+	if !d.running.TryLock() {
+		return AbuseReport{}, ErrAbuseScanRunning
+	}
+	defer d.running.Unlock()
+
+	now := d.now()
+	rep := AbuseReport{From: now.Add(-d.cfg.Window), To: now, New: []model.AbuseFinding{}}
+	hits, err := d.domainHits(ctx, rep.From, rep.To)
+	if err != nil {
+		return rep, err
+	}
+	if d.cfg.MaxIPs > 0 {
+		spread, err := d.logs.IPSpread(rep.From, rep.To, d.cfg.MaxIPs)
+		if err != nil {
+			return rep, err
+		}
+		for _, u := range spread {
+			hits = append(hits, &abuseHit{shopID: u.ShopID, kind: model.AbuseIPSpread, usage: u})
+		}
+	}
+	for _, h := range hits {
+		if err := ctx.Err(); err != nil {
+			return rep, err
+		}
+		if err := d.record(h, &rep); err != nil {
+			return rep, err
+		}
+	}
+	if len(rep.New) > 0 && d.notices != nil {
+		_, err := d.notices.Notify(ctx, model.NotifyAbuseFinding, abuseSummary(rep))
+		if err != nil && !errors.Is(err, ErrNoChannels) {
+			return rep, fmt.Errorf("notify: %w", err)
+		}
+	}
+	return rep, nil
+}
+
+// domainHits classifies every domain sent alongside a shop UUID. Raw params
+// that normalize to the same host are merged into one hit.
+func (d *AbuseDetector) domainHits(ctx context.Context, from, to time.Time) ([]*abuseHit, error) {
+	usage, err := d.logs.UUIDDomainUsage(from, to)
+	if err != nil {
+		return nil, err
+	}
+	owners := map[string]uint{}
+	byKey := map[string]*abuseHit{}
+	var hits []*abuseHit
+	for _, u := range usage {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		kind, owner := model.AbuseUnknownDomain, uint(0)
+		host, err := d.shops.NormalizeDomain(u.Subject)
+		if err != nil {
+			host = truncate(u.Subject, 255)
+		} else {
+			if d.ignored(host) {
+				continue
+			}
+			id, seen := owners[host]
+			if !seen {
+				if id, err = d.ownerOf(host); err != nil {
+					return nil, err
+				}
+				owners[host] = id
+			}
+			if id == u.ShopID {
+				continue
+			}
+			if id != 0 {
+				kind, owner = model.AbuseDomainMismatch, id
+			}
+		}
+		key := fmt.Sprintf("%d|%s", u.ShopID, host)
+		h, ok := byKey[key]
+		if !ok {
+			h = &abuseHit{shopID: u.ShopID, owner: owner, kind: kind, subject: host, usage: u}
+			byKey[key] = h
+			hits = append(hits, h)
+		} else {
+			mergeUsage(&h.usage, u)
+		}
+		h.params = append(h.params, u.Subject)
+	}
+	return hits, nil
+}
+
+// ownerOf returns the shop holding host, or 0. When host itself is not
+// registered its www. twin is tried, so a shop seen under the bare or www
+// form of its own domain is not taken for a stranger.
+func (d *AbuseDetector) ownerOf(host string) (uint, error) {
+	bare, err := hostname.Normalizer{StripWWW: true}.Normalize(host)
+	if err != nil {
+		bare = host
+	}
+	candidates := []string{host}
+	for _, h := range []string{bare, "www." + bare} {
+		if h != host {
+			candidates = append(candidates, h)
+		}
+	}
+	for _, h := range candidates {
+		m, err := d.shops.FindByDomain(h)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, err
+		}
+		if m != nil {
+			return m.ID, nil
+		}
+	}
+	return 0, nil
+}
+
+// mergeUsage adds u to into. Distinct IPs cannot be summed exactly, so the
+// larger count is kept as a lower bound.
+func mergeUsage(into *repository.APILogUsage, u repository.APILogUsage) {
+	into.Calls += u.Calls
+	if u.DistinctIPs > into.DistinctIPs {
+		into.DistinctIPs = u.DistinctIPs
+	}
+	if u.FirstSeen.Before(into.FirstSeen) {
+		into.FirstSeen = u.FirstSeen
+	}
+	if u.LastSeen.After(into.LastSeen) {
+		into.LastSeen = u.LastSeen
+	}
+}
+
+// ignored reports whether host is, or is under, one of IgnoreDomains
+func (d *AbuseDetector) ignored(host string) bool {
+	for _, ig := range d.cfg.IgnoreDomains {
+		if host == ig || strings.HasSuffix(host, "."+ig) {
+			return true
+		}
+	}
+	return false
+}
+
+// record stores one hit, suspending the shop for new findings when enabled
+func (d *AbuseDetector) record(h *abuseHit, rep *AbuseReport) error {
+	ips, agents, err := d.logs.SampleClients(h.shopID, h.params, rep.From, rep.To, abuseSampleSize)
+	if err != nil {
+		return err
+	}
+	evidence := map[string]interface{}{
+		"window_from": rep.From,
+		"window_to":   rep.To,
+		"sample_ips":  ips,
+		"user_agents": agents,
+	}
+	if len(h.params) > 0 {
+		evidence["domain_params"] = h.params
+	}
+	if h.owner != 0 {
+		evidence["owner_shop_id"] = h.owner
+	}
+	raw, err := json.Marshal(evidence)
+	if err != nil {
+		return err
+	}
+
+	f, err := d.findings.FindSubject(h.shopID, h.kind, h.subject)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if f != nil {
+		f.Calls, f.DistinctIPs, f.Evidence = h.usage.Calls, h.usage.DistinctIPs, raw
+		if h.usage.LastSeen.After(f.LastSeen) {
+			f.LastSeen = h.usage.LastSeen
+		}
+		rep.Updated++
+		return d.findings.Save(f)
+	}
+	f = &model.AbuseFinding{
+		ShopID:      h.shopID,
+		Kind:        h.kind,
+		Subject:     h.subject,
+		Status:      model.AbuseOpen,
+		Calls:       h.usage.Calls,
+		DistinctIPs: h.usage.DistinctIPs,
+		Evidence:    raw,
+		FirstSeen:   h.usage.FirstSeen,
+		LastSeen:    h.usage.LastSeen,
+	}
+	// the finding is stored before the shop is suspended, so a suspension
+	// always has a finding explaining it
+	if err := d.findings.Create(f); err != nil {
+		return err
+	}
+	rep.New = append(rep.New, *f)
+	if !d.cfg.AutoSuspend {
+		return nil
+	}
+	reason := strings.TrimSpace("abuse: " + h.kind + " " + h.subject)
+	_, err = d.shops.Suspend(Actor{}, h.shopID, reason, "")
+	switch {
+	case errors.Is(err, ErrShopSuspended):
+		return nil
+	case err != nil:
+		return err
+	}
+	f.Suspended = true
+	rep.Suspended++
+	rep.New[len(rep.New)-1].Suspended = true
+	return d.findings.Save(f)
+}
+
+// abuseSummary is the notification for a scan
+func abuseSummary(rep AbuseReport) notify.Message {
+	var b strings.Builder
+	for _, f := range rep.New {
+		fmt.Fprintf(&b, "• shop #%d — %s", f.ShopID, f.Kind)
+		if f.Subject != "" {
+			fmt.Fprintf(&b, " %s", f.Subject)
+		}
+		fmt.Fprintf(&b, " (%d lượt, %d IP)", f.Calls, f.DistinctIPs)
+		if f.Suspended {
+			b.WriteString(" — đã tạm ngưng")
+		}
+		b.WriteString("\n")
+	}
+	return notify.Message{
+		Subject: fmt.Sprintf("Phát hiện %d dấu hiệu lạm dụng license (%s – %s)",
+			len(rep.New), rep.From.Format("2006-01-02 15:04"), rep.To.Format("2006-01-02 15:04")),
+		Text: strings.TrimRight(b.String(), "\n"),
+	}
+}
+
+// List returns findings, most recently seen first
+func (d *AbuseDetector) List(page, limit int, shopID *uint, kind, status string) ([]model.AbuseFinding, int64, error) {
+	return d.findings.ListPaged(page, limit, shopID, kind, status)
+}
+
+// Review sets the status of a finding after a human looked at it
+func (d *AbuseDetector) Review(actor Actor, id uint, status, note string) (*model.AbuseFinding, error) {
+	switch status {
+	case model.AbuseOpen, model.AbuseConfirmed, model.AbuseDismissed:
+	default:
+		return nil, ErrAbuseStatus
+	}
+	f, err := d.findings.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	before := map[string]interface{}{"status": f.Status}
+	now := d.now()
+	f.Status, f.ReviewNote = status, truncate(strings.TrimSpace(note), 500)
+	f.ReviewedBy, f.ReviewedAt = &actor.UserID, &now
+	err = d.audit.Tx(func(tx *gorm.DB) error {
+		findings := d.findings
+		if r, ok := findings.(*repository.AbuseFindingRepository); ok {
+			findings = r.WithTx(tx)
+		}
+		if err := findings.Save(f); err != nil {
+			return err
+		}
+		after := map[string]interface{}{"finding_id": f.ID, "kind": f.Kind, "subject": f.Subject, "status": f.Status, "note": f.ReviewNote}
+		return d.audit.Record(tx, actor, "shop.abuse_review", model.AuditEntityShop, f.ShopID, before, after)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
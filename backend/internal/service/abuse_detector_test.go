@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"metronic/internal/model"
+	"metronic/internal/repository"
+)
+
+func TestAbuseIgnoredDomains(t *testing.T) {
+	d := NewAbuseDetector(nil, nil, nil, AbuseConfig{IgnoreDomains: []string{"localhost", "test"}})
+	for host, want := range map[string]bool{
+		"localhost":      true,
+		"shop.localhost": true,
+		"dev.shop.test":  true,
+		"mylocalhost":    false,
+		"shop.vn":        false,
+	} {
+		if got := d.ignored(host); got != want {
+			t.Errorf("ignored(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
+
+func TestMergeUsageWidensWindow(t *testing.T) {
+	t0 := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+	u := repository.APILogUsage{Calls: 3, DistinctIPs: 2, FirstSeen: t0, LastSeen: t0.Add(time.Hour)}
+	mergeUsage(&u, repository.APILogUsage{Calls: 4, DistinctIPs: 5, FirstSeen: t0.Add(-time.Hour), LastSeen: t0})
+	if u.Calls != 7 || u.DistinctIPs != 5 {
+		t.Fatalf("calls=%d ips=%d, want 7 and 5", u.Calls, u.DistinctIPs)
+	}
+	if !u.FirstSeen.Equal(t0.Add(-time.Hour)) || !u.LastSeen.Equal(t0.Add(time.Hour)) {
+		t.Fatalf("window = %v..%v", u.FirstSeen, u.LastSeen)
+	}
+}
+
+func TestAbuseSummaryListsFindings(t *testing.T) {
+	rep := AbuseReport{New: []model.AbuseFinding{
+		{ShopID: 7, Kind: model.AbuseDomainMismatch, Subject: "copy.vn", Calls: 12, DistinctIPs: 1, Suspended: true},
+		{ShopID: 9, Kind: model.AbuseIPSpread, Calls: 400, DistinctIPs: 60},
+	}}
+	msg := abuseSummary(rep).Text
+	for _, want := range []string{"shop #7 — domain_mismatch copy.vn", "đã tạm ngưng", "shop #9 — ip_spread (400 lượt, 60 IP)"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("summary missing %q:\n%s", want, msg)
+		}
+	}
+}
+
+// memLogs is an AbuseLogSource with fixed usage rows
+type memLogs struct {
+	usage  []repository.APILogUsage
+	spread []repository.APILogUsage
+}
+
+func (m *memLogs) UUIDDomainUsage(from, to time.Time) ([]repository.APILogUsage, error) {
+	return m.usage, nil
+}
+
+func (m *memLogs) IPSpread(from, to time.Time, maxIPs int) ([]repository.APILogUsage, error) {
+	var out []repository.APILogUsage
+	for _, u := range m.spread {
+		if u.DistinctIPs > int64(maxIPs) {
+			out = append(out, u)
+		}
+	}
+	return out, nil
+}
+
+func (m *memLogs) SampleClients(shopID uint, domains []string, from, to time.Time, n int) ([]string, []string, error) {
+	return []string{"203.0.113.9"}, []string{"curl/8"}, nil
+}
+
+// memFindings is an in-memory AbuseFindingStore
+type memFindings struct {
+	AbuseFindingStore
+	items      []*model.AbuseFinding
+	failCreate error
+}
+
+func (m *memFindings) FindSubject(shopID uint, kind, subject string) (*model.AbuseFinding, error) {
+	for _, f := range m.items {
+		if f.ShopID == shopID && f.Kind == kind && f.Subject == subject {
+			c := *f
+			return &c, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memFindings) FindByID(id uint) (*model.AbuseFinding, error) {
+	for _, f := range m.items {
+		if f.ID == id {
+			c := *f
+			return &c, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memFindings) Create(f *model.AbuseFinding) error {
+	if m.failCreate != nil {
+		return m.failCreate
+	}
+	f.ID = uint(len(m.items) + 1)
+	c := *f
+	m.items = append(m.items, &c)
+	return nil
+}
+
+func (m *memFindings) Save(f *model.AbuseFinding) error {
+	c := *f
+	m.items[f.ID-1] = &c
+	return nil
+}
+
+// newAbuseFixture has shop 1 (shop.vn, alias alias.vn and *.shop.vn) and
+// shop 2 (other.vn), and traffic with shop 1's UUID from every kind of domain
+func newAbuseFixture(cfg AbuseConfig) (*AbuseDetector, *memShops, *memFindings, *clock) {
+	clk := &clock{t: time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)}
+	shops := newMemShops()
+	shops.add(model.Shop{ID: 1, Domain: "shop.vn", Active: true}, "alias.vn", "*.shop.vn")
+	shops.add(model.Shop{ID: 2, Domain: "other.vn", Active: true})
+	shops.add(model.Shop{ID: 3, Domain: "www.brand.vn", Active: true})
+	seen := clk.t.Add(-time.Hour)
+	use := func(shopID uint, subject string, calls int64) repository.APILogUsage {
+		return repository.APILogUsage{ShopID: shopID, Subject: subject, Calls: calls, DistinctIPs: 1, FirstSeen: seen, LastSeen: seen}
+	}
+	logs := &memLogs{
+		usage: []repository.APILogUsage{
+			use(1, "shop.vn", 100),         // own primary
+			use(1, "alias.vn", 5),          // own alias
+			use(1, "a.b.shop.vn", 5),       // own wildcard
+			use(1, "dev.localhost", 3),     // ignored
+			use(1, "https://Other.vn/", 4), // another shop's domain...
+			use(1, "other.vn", 6),          // ...merged with its normalised form
+			use(1, "copy.example", 7),      // nobody's domain
+			use(1, "not a host!", 2),       // unparsable: unknown, kept verbatim
+			use(1, "www.other.vn", 1),      // another shop's domain in its www form
+			use(2, "other.vn", 50),         // shop 2 on its own domain
+			use(2, "WWW.Other.vn", 8),      // ...and its www form
+			use(3, "brand.vn", 20),         // shop 3 on the bare form of its www domain
+		},
+		spread: []repository.APILogUsage{
+			{ShopID: 2, Calls: 900, DistinctIPs: 80, FirstSeen: seen, LastSeen: seen},
+			{ShopID: 1, Calls: 90, DistinctIPs: 3, FirstSeen: seen, LastSeen: seen},
+		},
+	}
+	findings := &memFindings{}
+	svc := NewShopService(shops).WithClock(clk.now)
+	d := NewAbuseDetector(logs, findings, svc, cfg).WithClock(clk.now)
+	return d, shops, findings, clk
+}
+
+func TestAbuseClassifiesDomains(t *testing.T) {
+	d, shops, findings, _ := newAbuseFixture(AbuseConfig{MaxIPs: 50, IgnoreDomains: []string{"localhost"}})
+	rep, err := d.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range rep.New {
+		got = append(got, fmt.Sprintf("%d %s %s %d", f.ShopID, f.Kind, f.Subject, f.Calls))
+	}
+	sort.Strings(got)
+	want := []string{
+		"1 domain_mismatch other.vn 10",
+		"1 domain_mismatch www.other.vn 1",
+		"1 unknown_domain copy.example 7",
+		"1 unknown_domain not a host! 2",
+		"2 ip_spread  900",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("findings:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	for _, f := range findings.items {
+		if f.Kind == model.AbuseDomainMismatch && f.Subject == "other.vn" {
+			if !strings.Contains(string(f.Evidence), `"owner_shop_id":2`) || !strings.Contains(string(f.Evidence), `"https://Other.vn/","other.vn"`) {
+				t.Fatalf("mismatch evidence %s", f.Evidence)
+			}
+		}
+		if f.Suspended {
+			t.Fatalf("finding %d suspended its shop without AutoSuspend", f.ID)
+		}
+	}
+	if rep.Suspended != 0 || !shops.byID[1].Active || !shops.byID[2].Active {
+		t.Fatal("shops were suspended without AutoSuspend")
+	}
+
+	rep, err = d.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rep.New) != 0 || rep.Updated != 5 || len(findings.items) != 5 {
+		t.Fatalf("second scan: %d new, %d updated, %d stored", len(rep.New), rep.Updated, len(findings.items))
+	}
+}
+
+func TestAbuseAutoSuspendOnlyOnNewFindings(t *testing.T) {
+	d, shops, findings, _ := newAbuseFixture(AbuseConfig{MaxIPs: 50, AutoSuspend: true})
+	rep, err := d.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Suspended != 2 || shops.byID[1].Active || shops.byID[2].Active {
+		t.Fatalf("suspended %d, shop1 active=%v shop2 active=%v", rep.Suspended, shops.byID[1].Active, shops.byID[2].Active)
+	}
+	// shop 1 has three new findings but is suspended once; the others see it already suspended
+	flagged := map[uint]int{}
+	for _, f := range findings.items {
+		if f.Suspended {
+			flagged[f.ShopID]++
+		}
+	}
+	if flagged[1] != 1 || flagged[2] != 1 {
+		t.Fatalf("findings marked as suspending: %v", flagged)
+	}
+
+	// an admin resumes shop 1; refreshing known findings must not suspend it again
+	if _, err := d.shops.Resume(Actor{UserID: 1}, 1, "false positive"); err != nil {
+		t.Fatal(err)
+	}
+	rep, err = d.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Suspended != 0 || !shops.byID[1].Active {
+		t.Fatalf("rescan suspended %d shops, shop1 active=%v", rep.Suspended, shops.byID[1].Active)
+	}
+}
+
+func TestAbuseDoesNotSuspendWithoutFinding(t *testing.T) {
+	d, shops, findings, _ := newAbuseFixture(AbuseConfig{AutoSuspend: true})
+	findings.failCreate = errors.New("insert failed")
+	if _, err := d.Run(context.Background()); err == nil {
+		t.Fatal("scan hid the failed insert")
+	}
+	if !shops.byID[1].Active {
+		t.Fatal("shop suspended without a finding on record")
+	}
+}
+
+func TestAbuseReviewUsesClock(t *testing.T) {
+	d, _, findings, clk := newAbuseFixture(AbuseConfig{})
+	if _, err := d.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Review(Actor{UserID: 5}, 1, "closed", ""); !errors.Is(err, ErrAbuseStatus) {
+		t.Fatalf("bad status: got %v", err)
+	}
+	clk.advance(time.Hour)
+	f, err := d.Review(Actor{UserID: 5}, 1, model.AbuseDismissed, "  dev copy  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := findings.items[0]
+	if f.Status != model.AbuseDismissed || stored.ReviewNote != "dev copy" || stored.ReviewedAt == nil || !stored.ReviewedAt.Equal(clk.t) || *stored.ReviewedBy != 5 {
+		t.Fatalf("reviewed finding %+v", stored)
+	}
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"metronic/internal/domain"
+	"metronic/internal/model"
+	"metronic/internal/repository"
+	"metronic/internal/security"
+)
+
+var (
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	ErrUnknownScope   = errors.New("unknown scope")
+)
+
+// APIKey is the public view of an API key; the secret is never part of it
+type APIKey struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP *string    `json:"last_used_ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// APIKeyService manages named, scoped personal API keys for automation
+type APIKeyService struct {
+	tokens domain.TokenRepository
+	perms  *repository.RoleRepository
+}
+
+func NewAPIKeyService(t domain.TokenRepository, p *repository.RoleRepository) *APIKeyService {
+	return &APIKeyService{tokens: t, perms: p}
+}
+
+// Scopes returns every scope a key can be granted
+func (s *APIKeyService) Scopes() map[string]string {
+	return model.APIKeyScopes
+}
+
+// Create issues a key for the user and returns its plain value once.
+// A key may only carry scopes whose permission the owner currently holds.
+func (s *APIKeyService) Create(userID uint, name string, scopes []string, expiresAt *time.Time) (string, *APIKey, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, errors.New("name is required")
+	}
+	scopes = uniqueStrings(scopes)
+	if len(scopes) == 0 {
+		return "", nil, errors.New("at least one scope is required")
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return "", nil, errors.New("expires_at must be in the future")
+	}
+	granted, err := s.perms.PermissionCodesByUserID(userID)
+	if err != nil {
+		return "", nil, err
+	}
+	have := make(map[string]bool, len(granted))
+	for _, g := range granted {
+		have[g] = true
+	}
+	for _, sc := range scopes {
+		code, ok := model.APIKeyScopes[sc]
+		if !ok {
+			return "", nil, ErrUnknownScope
+		}
+		if !have[code] {
+			return "", nil, errors.New("scope " + sc + " requires permission " + code)
+		}
+	}
+	plain, hash, prefix := security.GenerateAPIKey()
+	tok := &domain.Token{
+		UserID:    userID,
+		Kind:      domain.TokenKindAPIKey,
+		Name:      &name,
+		Prefix:    prefix,
+		Scopes:    strings.Join(scopes, " "),
+		TokenHash: hash,
+		ExpiresAt: domain.NoExpiry,
+	}
+	if expiresAt != nil {
+		tok.ExpiresAt = *expiresAt
+	}
+	if err := s.tokens.Create(tok); err != nil {
+		return "", nil, err
+	}
+	return plain, toAPIKey(tok), nil
+}
+
+// List returns the user's active API keys, newest first
+func (s *APIKeyService) List(userID uint) ([]APIKey, error) {
+	toks, err := s.tokens.ListActiveByUserID(userID, []string{domain.TokenKindAPIKey}, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	items := make([]APIKey, 0, len(toks))
+	for i := range toks {
+		items = append(items, *toAPIKey(&toks[i]))
+	}
+	return items, nil
+}
+
+// Revoke disables one of the user's API keys
+func (s *APIKeyService) Revoke(userID, id uint) error {
+	tok, err := s.tokens.FindByID(id)
+	if err != nil || tok.UserID != userID || tok.Kind != domain.TokenKindAPIKey {
+		return ErrAPIKeyNotFound
+	}
+	return s.tokens.RevokeByID(tok.ID)
+}
+
+func toAPIKey(t *domain.Token) *APIKey {
+	k := &APIKey{
+		ID:         t.ID,
+		Prefix:     t.Prefix,
+		Scopes:     strings.Fields(t.Scopes),
+		LastUsedAt: t.LastUsedAt,
+		LastUsedIP: t.IP,
+		CreatedAt:  t.CreatedAt,
+	}
+	if t.Name != nil {
+		k.Name = *t.Name
+	}
+	if !t.ExpiresAt.Equal(domain.NoExpiry) {
+		exp := t.ExpiresAt
+		k.ExpiresAt = &exp
+	}
+	return k
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrRetentionRunning is returned when a prune is requested while one is in progress
+var ErrRetentionRunning = errors.New("api log retention is already running")
+
+// RetentionReport tells what one retention run did
+type RetentionReport struct {
+	Cutoff    time.Time `json:"cutoff"`
+	Days      int       `json:"days_rolled_up"`
+	RolledUp  int64     `json:"rows_rolled_up"`
+	Deleted   int64     `json:"rows_deleted"`
+	StartedAt time.Time `json:"started_at"`
+	Duration  string    `json:"duration"`
+}
+
+// APILogPruneStore is the part of the API log repository used by retention;
+// ShopAPILogRepository satisfies it
+type APILogPruneStore interface {
+	OldestBefore(t time.Time) (*time.Time, error)
+	HasRollup(day time.Time) (bool, error)
+	Rollup(day, from, to time.Time) (int64, error)
+	DeleteBefore(t time.Time, limit int) (int64, error)
+}
+
+// APILogRetention rolls raw shop_api_logs up into daily totals and deletes
+// rows older than the retention window, one day and one chunk at a time so
+// no statement locks the table for long
+type APILogRetention struct {
+	logs    APILogPruneStore
+	days    int
+	chunk   int
+	loc     *time.Location
+	now     func() time.Time
+	running sync.Mutex
+}
+
+// NewAPILogRetention keeps days of raw rows and deletes chunk rows per statement.
+// Day boundaries follow loc.
+func NewAPILogRetention(logs APILogPruneStore, days, chunk int, loc *time.Location) *APILogRetention {
+	if chunk <= 0 {
+		chunk = 5000
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+	return &APILogRetention{logs: logs, days: days, chunk: chunk, loc: loc, now: time.Now}
+}
+
+// Cutoff is the first instant whose rows are kept: local midnight, days ago
+func (r *APILogRetention) Cutoff(now time.Time) time.Time {
+	y, m, d := now.In(r.loc).Date()
+	return time.Date(y, m, d-r.days, 0, 0, 0, 0, r.loc)
+}
+
+// Run rolls up and prunes every whole day before the cutoff. A day is rolled
+// up only once, before its first row is deleted, so a run interrupted halfway
+// through deleting a day resumes without undercounting it.
+func (r *APILogRetention) Run(ctx context.Context) (rep RetentionReport, err error) {
+	if !r.running.TryLock() {
+		return RetentionReport{}, ErrRetentionRunning
+	}
+	defer r.running.Unlock()
+
+	start := r.now()
+	rep = RetentionReport{Cutoff: r.Cutoff(start), StartedAt: start}
+	// rep is a named result so the deferred write reaches the caller
+	defer func() { rep.Duration = r.now().Sub(start).Round(time.Millisecond).String() }()
+	if r.days <= 0 {
+		return rep, nil
+	}
+	oldest, err := r.logs.OldestBefore(rep.Cutoff)
+	if err != nil || oldest == nil {
+		return rep, err
+	}
+	y, m, d := oldest.In(r.loc).Date()
+	for day := time.Date(y, m, d, 0, 0, 0, 0, r.loc); day.Before(rep.Cutoff); day = day.AddDate(0, 0, 1) {
+		end := day.AddDate(0, 0, 1)
+		done, err := r.logs.HasRollup(day)
+		if err != nil {
+			return rep, err
+		}
+		if !done {
+			n, err := r.logs.Rollup(day, day, end)
+			if err != nil {
+				return rep, err
+			}
+			if n > 0 {
+				rep.Days++
+				rep.RolledUp += n
+			}
+		}
+		for {
+			if err := ctx.Err(); err != nil {
+				return rep, err
+			}
+			n, err := r.logs.DeleteBefore(end, r.chunk)
+			if err != nil {
+				return rep, err
+			}
+			rep.Deleted += n
+			if n < int64(r.chunk) {
+				break
+			}
+		}
+	}
+	return rep, nil
+}
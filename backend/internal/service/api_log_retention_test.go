@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRetentionCutoffIsLocalMidnight(t *testing.T) {
+	loc := time.FixedZone("ICT", 7*3600)
+	r := NewAPILogRetention(nil, 30, 0, loc)
+	// 2026-03-31 01:00 in ICT is still 2026-03-30 in UTC
+	now := time.Date(2026, 3, 30, 18, 0, 0, 0, time.UTC)
+	want := time.Date(2026, 3, 1, 0, 0, 0, 0, loc)
+	if got := r.Cutoff(now); !got.Equal(want) {
+		t.Fatalf("cutoff = %v, want %v", got, want)
+	}
+}
+
+// memPrune is an in-memory APILogPruneStore over raw row timestamps
+type memPrune struct {
+	rows    []time.Time
+	rollups map[string]int64 // date as seen by MySQL -> rows
+}
+
+func (m *memPrune) OldestBefore(t time.Time) (*time.Time, error) {
+	var oldest *time.Time
+	for i := range m.rows {
+		if m.rows[i].Before(t) && (oldest == nil || m.rows[i].Before(*oldest)) {
+			oldest = &m.rows[i]
+		}
+	}
+	return oldest, nil
+}
+
+func (m *memPrune) HasRollup(day time.Time) (bool, error) {
+	_, ok := m.rollups[day.Format("2006-01-02")]
+	return ok, nil
+}
+
+func (m *memPrune) Rollup(day, from, to time.Time) (int64, error) {
+	var n int64
+	for _, r := range m.rows {
+		if !r.Before(from) && r.Before(to) {
+			n++
+		}
+	}
+	if n > 0 {
+		m.rollups[day.Format("2006-01-02")] = n
+	}
+	return n, nil
+}
+
+func (m *memPrune) DeleteBefore(t time.Time, limit int) (int64, error) {
+	kept := m.rows[:0]
+	var n int64
+	for _, r := range m.rows {
+		if r.Before(t) && n < int64(limit) {
+			n++
+			continue
+		}
+		kept = append(kept, r)
+	}
+	m.rows = kept
+	return n, nil
+}
+
+func TestRetentionRollsUpLocalDays(t *testing.T) {
+	loc := time.FixedZone("ICT", 7*3600)
+	store := &memPrune{rollups: map[string]int64{}, rows: []time.Time{
+		time.Date(2026, 2, 28, 16, 59, 0, 0, time.UTC), // 2026-02-28 23:59 ICT
+		time.Date(2026, 2, 28, 17, 0, 0, 0, time.UTC),  // 2026-03-01 00:00 ICT
+		time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC),    // 2026-03-01 16:00 ICT
+		time.Date(2026, 3, 1, 17, 30, 0, 0, time.UTC),  // 2026-03-02 00:30 ICT, kept
+	}}
+	r := NewAPILogRetention(store, 1, 1, loc)
+	// 03-03 01:00 ICT; every reading of the clock is 1.5s after the previous one
+	clk := time.Date(2026, 3, 2, 18, 0, 0, 0, time.UTC).Add(-1500 * time.Millisecond)
+	r.now = func() time.Time {
+		clk = clk.Add(1500 * time.Millisecond)
+		return clk
+	}
+
+	rep, err := r.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rep.Cutoff.Equal(time.Date(2026, 3, 2, 0, 0, 0, 0, loc)) {
+		t.Fatalf("cutoff = %v", rep.Cutoff)
+	}
+	want := map[string]int64{"2026-02-28": 1, "2026-03-01": 2}
+	if len(store.rollups) != len(want) || store.rollups["2026-02-28"] != 1 || store.rollups["2026-03-01"] != 2 {
+		t.Fatalf("rollups = %v, want %v", store.rollups, want)
+	}
+	if rep.Days != 2 || rep.RolledUp != 3 || rep.Deleted != 3 || len(store.rows) != 1 {
+		t.Fatalf("report %+v, %d rows left", rep, len(store.rows))
+	}
+	if rep.Duration != "1.5s" {
+		t.Fatalf("duration = %q, want 1.5s", rep.Duration)
+	}
+
+	// the disabled run returns early and still reports its duration
+	off := NewAPILogRetention(store, 0, 1, loc)
+	off.now = r.now
+	if rep, err := off.Run(context.Background()); err != nil || rep.Duration != "1.5s" {
+		t.Fatalf("disabled run: %+v, %v", rep, err)
+	}
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"metronic/internal/model"
+)
+
+// APILogSink stores batches of API log rows; ShopAPILogRepository satisfies it
+type APILogSink interface {
+	CreateBatch(recs []model.ShopAPILog) error
+}
+
+// API log queue policies when the buffer is full
+const (
+	APILogDrop  = "drop"  // discard the row and count it
+	APILogBlock = "block" // make the request wait for room (backpressure)
+)
+
+// APILogWriterConfig tunes the log pipeline
+type APILogWriterConfig struct {
+	Buffer        int           // rows queued before the policy kicks in
+	Workers       int           // goroutines inserting batches
+	BatchSize     int           // rows per INSERT
+	FlushInterval time.Duration // max time a row waits for its batch to fill
+	Policy        string        // APILogDrop or APILogBlock
+}
+
+// DefaultAPILogWriterConfig suits a single backend instance
+func DefaultAPILogWriterConfig() APILogWriterConfig {
+	return APILogWriterConfig{Buffer: 10000, Workers: 2, BatchSize: 200, FlushInterval: time.Second, Policy: APILogDrop}
+}
+
+// APILogStats are the pipeline counters since start
+type APILogStats struct {
+	Enqueued uint64 `json:"enqueued"`
+	Written  uint64 `json:"written"`
+	Dropped  uint64 `json:"dropped"`
+	Failed   uint64 `json:"failed"`
+	Queued   int    `json:"queued"`
+	Policy   string `json:"policy"`
+}
+
+// APILogWriter is a bounded, buffered pipeline that batches API log inserts
+// so /shops/check never spawns a goroutine or an INSERT per request
+type APILogWriter struct {
+	sink  APILogSink
+	cfg   APILogWriterConfig
+	queue chan model.ShopAPILog
+	wg    sync.WaitGroup
+
+	mu     sync.RWMutex // guards closed against sends on a closed queue
+	closed bool
+
+	enqueued, written, dropped, failed atomic.Uint64
+}
+
+// NewAPILogWriter starts the workers; call Close to flush and stop them
+func NewAPILogWriter(sink APILogSink, cfg APILogWriterConfig) *APILogWriter {
+	def := DefaultAPILogWriterConfig()
+	if cfg.Buffer <= 0 {
+		cfg.Buffer = def.Buffer
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = def.Workers
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = def.BatchSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = def.FlushInterval
+	}
+	if cfg.Policy != APILogBlock {
+		cfg.Policy = APILogDrop
+	}
+	w := &APILogWriter{sink: sink, cfg: cfg, queue: make(chan model.ShopAPILog, cfg.Buffer)}
+	for i := 0; i < cfg.Workers; i++ {
+		w.wg.Add(1)
+		go w.run()
+	}
+	return w
+}
+
+// Write queues rec. With the drop policy a full buffer discards it; with the
+// block policy the caller waits. Rows written after Close are dropped.
+func (w *APILogWriter) Write(rec model.ShopAPILog) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		w.dropped.Add(1)
+		return
+	}
+	if w.cfg.Policy == APILogBlock {
+		w.queue <- rec
+		w.enqueued.Add(1)
+		return
+	}
+	select {
+	case w.queue <- rec:
+		w.enqueued.Add(1)
+	default:
+		w.dropped.Add(1)
+	}
+}
+
+// Close stops accepting rows and waits until everything queued is written,
+// or ctx ends
+func (w *APILogWriter) Close(ctx context.Context) error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stats returns the current counters
+func (w *APILogWriter) Stats() APILogStats {
+	return APILogStats{
+		Enqueued: w.enqueued.Load(),
+		Written:  w.written.Load(),
+		Dropped:  w.dropped.Load(),
+		Failed:   w.failed.Load(),
+		Queued:   len(w.queue),
+		Policy:   w.cfg.Policy,
+	}
+}
+
+func (w *APILogWriter) run() {
+	defer w.wg.Done()
+	batch := make([]model.ShopAPILog, 0, w.cfg.BatchSize)
+	ticker := time.NewTicker(w.cfg.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case rec, ok := <-w.queue:
+			if !ok {
+				w.flush(batch)
+				return
+			}
+			batch = append(batch, rec)
+			if len(batch) >= w.cfg.BatchSize {
+				batch = w.flush(batch)
+			}
+		case <-ticker.C:
+			batch = w.flush(batch)
+		}
+	}
+}
+
+// flush inserts batch and returns it emptied for reuse. When the batch
+// insert fails the rows are retried one by one, so a single bad row does not
+// take the others down with it.
+func (w *APILogWriter) flush(batch []model.ShopAPILog) []model.ShopAPILog {
+	if len(batch) == 0 {
+		return batch
+	}
+	err := w.sink.CreateBatch(batch)
+	if err == nil {
+		w.written.Add(uint64(len(batch)))
+		return batch[:0]
+	}
+	lost := 0
+	for i := range batch {
+		if err = w.sink.CreateBatch(batch[i : i+1]); err != nil {
+			lost++
+			w.failed.Add(1)
+			log.Printf("api log writer: row lost: %v", err)
+		} else {
+			w.written.Add(1)
+		}
+	}
+	if lost > 0 {
+		log.Printf("api log writer: %d of %d rows lost", lost, len(batch))
+	}
+	return batch[:0]
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"metronic/internal/model"
+)
+
+type memSink struct {
+	mu      sync.Mutex
+	batches [][]model.ShopAPILog
+	gate    chan struct{}               // when set, CreateBatch waits on it
+	reject  func(model.ShopAPILog) bool // rows failing the whole insert they are in
+}
+
+func (s *memSink) CreateBatch(recs []model.ShopAPILog) error {
+	if s.gate != nil {
+		<-s.gate
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range recs {
+		if s.reject != nil && s.reject(r) {
+			return errors.New("data too long")
+		}
+	}
+	s.batches = append(s.batches, append([]model.ShopAPILog(nil), recs...))
+	return nil
+}
+
+func (s *memSink) rows() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, b := range s.batches {
+		n += len(b)
+	}
+	return n
+}
+
+func TestAPILogWriterBatchesAndFlushesOnClose(t *testing.T) {
+	sink := &memSink{}
+	w := NewAPILogWriter(sink, APILogWriterConfig{Workers: 1, BatchSize: 10, FlushInterval: time.Hour})
+	for i := 0; i < 25; i++ {
+		w.Write(model.ShopAPILog{Status: "valid"})
+	}
+	if err := w.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if sink.rows() != 25 {
+		t.Fatalf("rows = %d, want 25", sink.rows())
+	}
+	if len(sink.batches) != 3 {
+		t.Fatalf("batches = %d, want 3 (10+10+5)", len(sink.batches))
+	}
+	w.Write(model.ShopAPILog{})
+	if st := w.Stats(); st.Written != 25 || st.Dropped != 1 {
+		t.Fatalf("stats = %+v", st)
+	}
+}
+
+func TestAPILogWriterFlushesOnInterval(t *testing.T) {
+	sink := &memSink{}
+	w := NewAPILogWriter(sink, APILogWriterConfig{Workers: 1, BatchSize: 100, FlushInterval: 10 * time.Millisecond})
+	defer w.Close(context.Background())
+	w.Write(model.ShopAPILog{})
+	deadline := time.Now().Add(time.Second)
+	for sink.rows() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if sink.rows() != 1 {
+		t.Fatal("partial batch was not flushed on interval")
+	}
+}
+
+func TestAPILogWriterDropsWhenFull(t *testing.T) {
+	sink := &memSink{gate: make(chan struct{})}
+	w := NewAPILogWriter(sink, APILogWriterConfig{Buffer: 2, Workers: 1, BatchSize: 1, FlushInterval: time.Hour, Policy: APILogDrop})
+	for i := 0; i < 10; i++ {
+		w.Write(model.ShopAPILog{})
+	}
+	st := w.Stats()
+	// one row is held by the blocked worker, two sit in the buffer
+	if st.Dropped < 7 || st.Enqueued+st.Dropped != 10 {
+		t.Fatalf("stats = %+v", st)
+	}
+	close(sink.gate)
+	if err := w.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if uint64(sink.rows()) != st.Enqueued {
+		t.Fatalf("rows = %d, want %d", sink.rows(), st.Enqueued)
+	}
+}
+
+func TestAPILogWriterRetriesFailedBatchRowByRow(t *testing.T) {
+	sink := &memSink{reject: func(r model.ShopAPILog) bool { return r.UserAgent == "bad" }}
+	w := NewAPILogWriter(sink, APILogWriterConfig{Workers: 1, BatchSize: 5, FlushInterval: time.Hour})
+	for i := 0; i < 5; i++ {
+		ua := "ok"
+		if i == 2 {
+			ua = "bad"
+		}
+		w.Write(model.ShopAPILog{ShopID: uint(i), UserAgent: ua})
+	}
+	if err := w.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if sink.rows() != 4 {
+		t.Fatalf("rows = %d, want the 4 good ones", sink.rows())
+	}
+	if st := w.Stats(); st.Written != 4 || st.Failed != 1 {
+		t.Fatalf("written %d failed %d, want 4 and 1", st.Written, st.Failed)
+	}
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"gorm.io/gorm"
+
+	"metronic/internal/model"
+	"metronic/internal/repository"
+)
+
+// Actor identifies who performs an administrative change
+type Actor struct {
+	UserID    uint
+	IP        string
+	UserAgent string
+}
+
+// AuditService records and lists audit events. Services hold an optional
+// *AuditService; its Tx and Record methods are no-ops on a nil receiver so
+// unwired services keep working unchanged.
+type AuditService struct {
+	events *repository.AuditRepository
+}
+
+func NewAuditService(r *repository.AuditRepository) *AuditService {
+	return &AuditService{events: r}
+}
+
+// Tx runs fn in one transaction so a change and its audit event commit together.
+// Without auditing fn gets a nil tx and repositories use their own handle.
+func (s *AuditService) Tx(fn func(tx *gorm.DB) error) error {
+	if s == nil {
+		return fn(nil)
+	}
+	return s.events.Transaction(fn)
+}
+
+// Record writes an event with the fields that differ between before and after
+// (snapshots from snapshot). Updates that changed nothing are not recorded.
+func (s *AuditService) Record(tx *gorm.DB, actor Actor, action, entityType string, entityID uint, before, after map[string]interface{}) error {
+	if s == nil {
+		return nil
+	}
+	if before != nil && after != nil {
+		before, after = diffSnapshots(before, after)
+		if len(before) == 0 && len(after) == 0 {
+			return nil
+		}
+	}
+	e := &model.AuditEvent{
+		ActorID:    actor.UserID,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		IP:         truncate(actor.IP, 64),
+		UserAgent:  truncate(actor.UserAgent, 255),
+	}
+	var err error
+	if e.Before, err = marshalSnapshot(before); err != nil {
+		return err
+	}
+	if e.After, err = marshalSnapshot(after); err != nil {
+		return err
+	}
+	return s.events.WithTx(tx).Create(e)
+}
+
+// List returns events matching f, newest first, plus the total count
+func (s *AuditService) List(f repository.AuditFilter, page, limit int) ([]model.AuditEvent, int64, error) {
+	return s.events.ListPaged(f, page, limit)
+}
+
+// ShopTimeline returns every recorded change of one shop, newest first
+func (s *AuditService) ShopTimeline(shopID uint, page, limit int) ([]model.AuditEvent, int64, error) {
+	return s.events.ListPaged(repository.AuditFilter{EntityType: model.AuditEntityShop, EntityID: shopID}, page, limit)
+}
+
+// snapshot captures v as its JSON field map, so later mutations of v do not
+// affect it. Fields hidden from JSON (passwords, secrets) stay out of the trail.
+func snapshot(v interface{}) map[string]interface{} {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil
+	}
+	return m
+}
+
+// diffSnapshots drops the keys whose values are equal on both sides
+func diffSnapshots(before, after map[string]interface{}) (map[string]interface{}, map[string]interface{}) {
+	b := make(map[string]interface{}, len(before))
+	a := make(map[string]interface{}, len(after))
+	for k, v := range before {
+		if w, ok := after[k]; !ok || !reflect.DeepEqual(v, w) {
+			b[k] = v
+		}
+	}
+	for k, w := range after {
+		if v, ok := before[k]; !ok || !reflect.DeepEqual(v, w) {
+			a[k] = w
+		}
+	}
+	return b, a
+}
+
+func marshalSnapshot(m map[string]interface{}) (model.JSON, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return json.Marshal(m)
+}
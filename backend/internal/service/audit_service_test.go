@@ -0,0 +1,36 @@
+package service
+
+import (
+	"testing"
+
+	"gorm.io/gorm"
+
+	"metronic/internal/model"
+)
+
+func TestDiffSnapshotsKeepsOnlyChangedFields(t *testing.T) {
+	before := snapshot(&model.Shop{ID: 1, Domain: "a.example", PricePerCycle: 100, CycleMonths: 12})
+	after := snapshot(&model.Shop{ID: 1, Domain: "b.example", PricePerCycle: 100, CycleMonths: 12})
+	b, a := diffSnapshots(before, after)
+	if len(b) != 1 || b["domain"] != "a.example" {
+		t.Fatalf("before diff = %v", b)
+	}
+	if len(a) != 1 || a["domain"] != "b.example" {
+		t.Fatalf("after diff = %v", a)
+	}
+}
+
+func TestNilAuditServiceRunsWithoutTransaction(t *testing.T) {
+	var s *AuditService
+	called := false
+	err := s.Tx(func(tx *gorm.DB) error {
+		called = true
+		if tx != nil {
+			t.Fatal("expected nil tx without auditing")
+		}
+		return s.Record(tx, Actor{UserID: 1}, "shop.update", model.AuditEntityShop, 1, nil, nil)
+	})
+	if err != nil || !called {
+		t.Fatalf("called=%v err=%v", called, err)
+	}
+}
@@ -8,22 +8,87 @@ import (
 
 	"metronic/internal/domain"
 	"metronic/internal/model"
-	"metronic/internal/repository"
 	"metronic/internal/security"
 )
 
+// UserStore is the part of the user repository used by login, 2FA and the
+// login guard; UserRepository satisfies it
+type UserStore interface {
+	Create(user *model.User) error
+	Count() (int64, error)
+	FindByEmail(email string) (*model.User, error)
+	FindByID(id uint) (*model.User, error)
+	Update(user *model.User) error
+	AdvanceTOTPStep(id uint, step int64) (bool, error)
+	IncrementFailedLogins(id uint) (int, error)
+	LockAccount(id uint, until time.Time) error
+	ResetLoginFailures(id uint) error
+}
+
+// RoleGranter gives a user a role by name; RoleRepository satisfies it
+type RoleGranter interface {
+	GrantByName(user *model.User, name string) error
+}
+
 // AuthService provides authentication methods based on opaque tokens
 type AuthService struct {
-	users  *repository.UserRepository
+	users  UserStore
 	tokens domain.TokenRepository
+	mfa    *MFAService
+	guard  *LoginGuard
+	now    func() time.Time
+	roles  RoleGranter
+	// registrationClosed disables self sign-up once the first user exists
+	registrationClosed bool
+}
+
+func NewAuthService(u UserStore, t domain.TokenRepository) *AuthService {
+	return &AuthService{users: u, tokens: t, now: time.Now}
+}
+
+// WithClock replaces the time source used for token expiry (tests)
+func (s *AuthService) WithClock(now func() time.Time) *AuthService {
+	s.now = now
+	return s
+}
+
+// WithMFA enables the two-factor step in Login (optional wiring style)
+func (s *AuthService) WithMFA(m *MFAService) *AuthService {
+	s.mfa = m
+	return s
 }
 
-func NewAuthService(u *repository.UserRepository, t domain.TokenRepository) *AuthService {
-	return &AuthService{users: u, tokens: t}
+// WithLoginGuard enables brute-force protection in Login and VerifyMFA
+func (s *AuthService) WithLoginGuard(g *LoginGuard) *AuthService {
+	s.guard = g
+	return s
 }
 
+// WithRegistration toggles public self sign-up. When closed, new accounts come
+// from invitations; the very first account may still register to bootstrap.
+func (s *AuthService) WithRegistration(open bool) *AuthService {
+	s.registrationClosed = !open
+	return s
+}
+
+// WithRoles makes the first account ever registered an admin, which is how a
+// fresh install gets its administrator (optional wiring style)
+func (s *AuthService) WithRoles(r RoleGranter) *AuthService {
+	s.roles = r
+	return s
+}
+
+var ErrRegistrationClosed = errors.New("registration is disabled")
+
 // Register creates a new user
 func (s *AuthService) Register(email, password string) error {
+	n, err := s.users.Count()
+	if err != nil {
+		return err
+	}
+	if s.registrationClosed && n > 0 {
+		return ErrRegistrationClosed
+	}
 	if _, err := s.users.FindByEmail(email); err == nil {
 		return errors.New("email already exists")
 	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
@@ -34,69 +99,211 @@ func (s *AuthService) Register(email, password string) error {
 		return err
 	}
 	user := &model.User{Email: email, Password: hash}
-	return s.users.Create(user)
+	if err := s.users.Create(user); err != nil {
+		return err
+	}
+	if n == 0 && s.roles != nil {
+		return s.roles.GrantByName(user, model.AdminRoleName)
+	}
+	return nil
+}
+
+// TokenPair is the result of a login or refresh: a short-lived access token
+// and a long-lived refresh token from the same family.
+type TokenPair struct {
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
+// MFAChallenge is returned by Login instead of tokens when the user has 2FA
+// enabled. Its token is exchanged for a TokenPair through VerifyMFA.
+type MFAChallenge struct {
+	Token     string
+	ExpiresAt time.Time
 }
 
-// Login validates credentials and issues a new token
-func (s *AuthService) Login(email, password, ip, ua string) (string, time.Time, *model.User, error) {
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+	ErrInvalidMFAChallenge = errors.New("invalid or expired mfa token")
+)
+
+// Login validates credentials and issues a new access/refresh token pair.
+// For users with 2FA enabled it issues an MFA challenge instead.
+func (s *AuthService) Login(email, password, ip, ua string) (*TokenPair, *MFAChallenge, *model.User, error) {
 	user, err := s.users.FindByEmail(email)
-	if err != nil || !security.CheckPassword(user.Password, password) {
-		return "", time.Time{}, nil, errors.New("invalid credentials")
+	if err != nil {
+		user = nil
 	}
-	plain, hash, exp := security.GenerateToken()
-	token := &domain.Token{
-		UserID:    user.ID,
-		TokenHash: hash,
-		ExpiresAt: exp,
+	if err := s.checkGuard(user, email, ip, ua); err != nil {
+		return nil, nil, nil, err
 	}
-	if ip != "" {
-		token.IP = &ip
+	if user == nil || !security.CheckPassword(user.Password, password) {
+		if s.guard != nil {
+			_ = s.guard.Fail(user, email, ip, ua, "invalid_credentials")
+		}
+		return nil, nil, nil, errors.New("invalid credentials")
 	}
-	if ua != "" {
-		token.UserAgent = &ua
+	// With 2FA the attempt only counts as a success once the code is verified
+	if s.mfa != nil && user.TOTPEnabled {
+		plain, exp, err := s.issueToken(user.ID, domain.TokenKindMFAPending, "", security.MFAChallengeTTL, ip, ua)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return nil, &MFAChallenge{Token: plain, ExpiresAt: exp}, user, nil
 	}
-	if err := s.tokens.Create(token); err != nil {
-		return "", time.Time{}, nil, err
+	if s.guard != nil {
+		_ = s.guard.Succeed(user, ip, ua)
+	}
+	pair, err := s.issuePair(user.ID, security.NewFamilyID(), ip, ua)
+	if err != nil {
+		return nil, nil, nil, err
 	}
-	return plain, exp, user, nil
+	return pair, nil, user, nil
 }
 
-// Refresh rotates the token and issues a new one
-func (s *AuthService) Refresh(tokenStr, ip, ua string) (string, time.Time, *model.User, error) {
+// checkGuard asks the login guard whether this attempt may proceed
+func (s *AuthService) checkGuard(user *model.User, email, ip, ua string) error {
+	if s.guard == nil {
+		return nil
+	}
+	err := s.guard.Check(user, email, ip)
+	var blocked *LoginBlockedError
+	if errors.As(err, &blocked) {
+		_ = s.guard.Blocked(user, email, ip, ua, blocked)
+	}
+	return err
+}
+
+// VerifyMFA completes a login: the challenge from Login plus a TOTP or
+// recovery code yields a normal token pair. The challenge is single-use.
+func (s *AuthService) VerifyMFA(challenge, code, ip, ua string) (*TokenPair, *model.User, error) {
+	if s.mfa == nil {
+		return nil, nil, ErrMFANotEnabled
+	}
+	stored, err := s.tokens.FindByHash(security.HashToken(challenge))
+	if err != nil || stored.Kind != domain.TokenKindMFAPending || stored.Revoked || stored.ExpiresAt.Before(s.now()) {
+		return nil, nil, ErrInvalidMFAChallenge
+	}
+	user, err := s.users.FindByID(stored.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.checkGuard(user, user.Email, ip, ua); err != nil {
+		return nil, nil, err
+	}
+	if err := s.mfa.Verify(user, code); err != nil {
+		if s.guard != nil && errors.Is(err, ErrInvalidMFACode) {
+			_ = s.guard.Fail(user, user.Email, ip, ua, "mfa")
+		}
+		return nil, nil, err
+	}
+	ok, err := s.tokens.RevokeIfActive(stored.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok {
+		return nil, nil, ErrInvalidMFAChallenge
+	}
+	if s.guard != nil {
+		_ = s.guard.Succeed(user, ip, ua)
+	}
+	pair, err := s.issuePair(user.ID, security.NewFamilyID(), ip, ua)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pair, user, nil
+}
+
+// Refresh rotates a refresh token: the presented token is revoked and a new
+// pair from the same family is issued. Presenting an already rotated token
+// revokes every token of the family, since one of the copies was stolen.
+func (s *AuthService) Refresh(tokenStr, ip, ua string) (*TokenPair, *model.User, error) {
 	hash := security.HashToken(tokenStr)
 	stored, err := s.tokens.FindByHash(hash)
-	if err != nil || stored.Revoked || stored.ExpiresAt.Before(time.Now()) {
-		return "", time.Time{}, nil, errors.New("invalid token")
+	if err != nil || stored.Kind != domain.TokenKindRefresh {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	if stored.Revoked {
+		if err := s.tokens.RevokeFamily(stored.FamilyID); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrRefreshTokenReused
 	}
-	if err := s.tokens.RevokeByID(stored.ID); err != nil {
-		return "", time.Time{}, nil, err
+	if stored.ExpiresAt.Before(s.now()) {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	// Conditional revoke so two concurrent refreshes cannot both win
+	ok, err := s.tokens.RevokeIfActive(stored.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok {
+		if err := s.tokens.RevokeFamily(stored.FamilyID); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrRefreshTokenReused
 	}
 	user, err := s.users.FindByID(stored.UserID)
 	if err != nil {
-		return "", time.Time{}, nil, err
+		return nil, nil, err
+	}
+	pair, err := s.issuePair(user.ID, stored.FamilyID, ip, ua)
+	if err != nil {
+		return nil, nil, err
 	}
-	plain, newHash, exp := security.GenerateToken()
-	newToken := &domain.Token{
-		UserID:    stored.UserID,
-		TokenHash: newHash,
+	return pair, user, nil
+}
+
+func (s *AuthService) issuePair(userID uint, familyID, ip, ua string) (*TokenPair, error) {
+	access, accessExp, err := s.issueToken(userID, domain.TokenKindAccess, familyID, security.AccessTokenTTL, ip, ua)
+	if err != nil {
+		return nil, err
+	}
+	refresh, refreshExp, err := s.issueToken(userID, domain.TokenKindRefresh, familyID, security.RefreshTokenTTL, ip, ua)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		AccessToken:      access,
+		AccessExpiresAt:  accessExp,
+		RefreshToken:     refresh,
+		RefreshExpiresAt: refreshExp,
+	}, nil
+}
+
+func (s *AuthService) issueToken(userID uint, kind, familyID string, ttl time.Duration, ip, ua string) (string, time.Time, error) {
+	plain, hash, _ := security.GenerateTokenWithTTL(ttl)
+	exp := s.now().Add(ttl)
+	token := &domain.Token{
+		UserID:    userID,
+		Kind:      kind,
+		FamilyID:  familyID,
+		TokenHash: hash,
 		ExpiresAt: exp,
 	}
 	if ip != "" {
-		newToken.IP = &ip
+		token.IP = &ip
 	}
 	if ua != "" {
-		newToken.UserAgent = &ua
+		token.UserAgent = &ua
 	}
-	if err := s.tokens.Create(newToken); err != nil {
-		return "", time.Time{}, nil, err
+	if err := s.tokens.Create(token); err != nil {
+		return "", time.Time{}, err
 	}
-	return plain, exp, user, nil
+	return plain, exp, nil
 }
 
-// Logout revokes token or all tokens
+// Logout revokes the current session (access token and its refresh token), or
+// with all every session of the user. API keys are not sessions and stay valid.
 func (s *AuthService) Logout(tok *domain.Token, all bool) error {
 	if all {
-		return s.tokens.RevokeAllByUserID(tok.UserID)
+		return revokeSessions(s.tokens, tok.UserID)
+	}
+	if tok.FamilyID != "" {
+		return s.tokens.RevokeFamily(tok.FamilyID)
 	}
 	return s.tokens.RevokeByID(tok.ID)
 }
@@ -0,0 +1,431 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"metronic/internal/domain"
+	"metronic/internal/model"
+	"metronic/internal/security"
+)
+
+// memUsers is an in-memory UserStore; lookups return copies like the repository
+type memUsers struct {
+	mu    sync.Mutex
+	users map[uint]model.User
+}
+
+func newMemUsers(us ...model.User) *memUsers {
+	m := &memUsers{users: map[uint]model.User{}}
+	for _, u := range us {
+		m.users[u.ID] = u
+	}
+	return m
+}
+
+func (m *memUsers) Create(u *model.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u.ID = uint(len(m.users) + 1)
+	m.users[u.ID] = *u
+	return nil
+}
+
+func (m *memUsers) Count() (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return int64(len(m.users)), nil
+}
+
+func (m *memUsers) FindByEmail(email string) (*model.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, u := range m.users {
+		if u.Email == email {
+			return &u, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memUsers) FindByID(id uint) (*model.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, ok := m.users[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &u, nil
+}
+
+func (m *memUsers) Update(u *model.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.users[u.ID] = *u
+	return nil
+}
+
+func (m *memUsers) AdvanceTOTPStep(id uint, step int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u := m.users[id]
+	if u.TOTPLastStep >= step {
+		return false, nil
+	}
+	u.TOTPLastStep = step
+	m.users[id] = u
+	return true, nil
+}
+
+func (m *memUsers) IncrementFailedLogins(id uint) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u := m.users[id]
+	u.FailedLoginCount++
+	m.users[id] = u
+	return u.FailedLoginCount, nil
+}
+
+func (m *memUsers) LockAccount(id uint, until time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u := m.users[id]
+	u.FailedLoginCount, u.LockedUntil = 0, &until
+	m.users[id] = u
+	return nil
+}
+
+func (m *memUsers) ResetLoginFailures(id uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u := m.users[id]
+	u.FailedLoginCount, u.LockedUntil = 0, nil
+	m.users[id] = u
+	return nil
+}
+
+// memTokens is an in-memory domain.TokenRepository
+type memTokens struct {
+	mu     sync.Mutex
+	tokens []domain.Token
+}
+
+func (m *memTokens) Create(t *domain.Token) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t.ID = uint(len(m.tokens) + 1)
+	m.tokens = append(m.tokens, *t)
+	return nil
+}
+
+func (m *memTokens) find(match func(*domain.Token) bool) (*domain.Token, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.tokens {
+		if match(&m.tokens[i]) {
+			t := m.tokens[i]
+			return &t, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memTokens) FindByID(id uint) (*domain.Token, error) {
+	return m.find(func(t *domain.Token) bool { return t.ID == id })
+}
+
+func (m *memTokens) FindByHash(hash string) (*domain.Token, error) {
+	return m.find(func(t *domain.Token) bool { return t.TokenHash == hash })
+}
+
+func (m *memTokens) ListActiveByUserID(userID uint, kinds []string, now time.Time) ([]domain.Token, error) {
+	return nil, nil
+}
+
+func (m *memTokens) CountActiveByUserID(userID uint, kinds []string, now time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (m *memTokens) revoke(match func(*domain.Token) bool) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for i := range m.tokens {
+		if match(&m.tokens[i]) && !m.tokens[i].Revoked {
+			m.tokens[i].Revoked = true
+			n++
+		}
+	}
+	return n
+}
+
+func (m *memTokens) RevokeByID(id uint) error {
+	m.revoke(func(t *domain.Token) bool { return t.ID == id })
+	return nil
+}
+
+func (m *memTokens) RevokeIfActive(id uint) (bool, error) {
+	return m.revoke(func(t *domain.Token) bool { return t.ID == id }) == 1, nil
+}
+
+func (m *memTokens) RevokeFamily(familyID string) error {
+	m.revoke(func(t *domain.Token) bool { return t.FamilyID == familyID })
+	return nil
+}
+
+func (m *memTokens) RevokeAllByUserID(userID uint) error {
+	m.revoke(func(t *domain.Token) bool { return t.UserID == userID })
+	return nil
+}
+
+func (m *memTokens) RevokeAllByUserIDAndKind(userID uint, kind string) error {
+	m.revoke(func(t *domain.Token) bool { return t.UserID == userID && t.Kind == kind })
+	return nil
+}
+
+func (m *memTokens) UpdateUsage(id uint, ip, ua string, t time.Time) error { return nil }
+
+// memRecovery is an in-memory RecoveryCodeStore keyed by hash
+type memRecovery struct {
+	codes map[string]*time.Time
+}
+
+func (m *memRecovery) ReplaceForUser(userID uint, hashes []string) error {
+	m.codes = map[string]*time.Time{}
+	for _, h := range hashes {
+		m.codes[h] = nil
+	}
+	return nil
+}
+
+func (m *memRecovery) Consume(userID uint, hash string, t time.Time) (bool, error) {
+	used, ok := m.codes[hash]
+	if !ok || used != nil {
+		return false, nil
+	}
+	m.codes[hash] = &t
+	return true, nil
+}
+
+func (m *memRecovery) CountUnused(userID uint) (int64, error) {
+	var n int64
+	for _, used := range m.codes {
+		if used == nil {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *memRecovery) DeleteByUserID(userID uint) error {
+	m.codes = nil
+	return nil
+}
+
+// clock is a settable time source shared by the services under test
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time          { return c.t }
+func (c *clock) advance(d time.Duration) { c.t = c.t.Add(d) }
+func (c *clock) code(t *testing.T, secret string) string {
+	t.Helper()
+	code, err := security.TOTPCode(secret, c.t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+func newMFAUser(t *testing.T, clk *clock) (*memUsers, *MFAService, string, []string) {
+	t.Helper()
+	hash, err := security.HashPassword("s3cret-pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := newMemUsers(model.User{ID: 1, Email: "a@example.com", Password: hash})
+	mfa := NewMFAService(users, &memRecovery{}, "Subly").WithClock(clk.now)
+	secret, _, err := mfa.Setup(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	codes, err := mfa.Confirm(1, clk.code(t, secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return users, mfa, secret, codes
+}
+
+func TestLoginWithMFAChallenge(t *testing.T) {
+	clk := &clock{t: time.Date(2030, 1, 2, 3, 4, 0, 0, time.UTC)}
+	users, mfa, secret, _ := newMFAUser(t, clk)
+	tokens := &memTokens{}
+	auth := NewAuthService(users, tokens).WithMFA(mfa).WithClock(clk.now)
+
+	pair, challenge, _, err := auth.Login("a@example.com", "s3cret-pass", "1.2.3.4", "test")
+	if err != nil || pair != nil || challenge == nil {
+		t.Fatalf("login: pair=%v challenge=%v err=%v", pair, challenge, err)
+	}
+	if want := clk.t.Add(security.MFAChallengeTTL); !challenge.ExpiresAt.Equal(want) {
+		t.Fatalf("challenge expires %v, want %v", challenge.ExpiresAt, want)
+	}
+
+	if _, _, err := auth.VerifyMFA(challenge.Token, "000000", "1.2.3.4", "test"); !errors.Is(err, ErrInvalidMFACode) {
+		t.Fatalf("wrong code: %v", err)
+	}
+	// the code used to confirm enrolment cannot be replayed
+	if _, _, err := auth.VerifyMFA(challenge.Token, clk.code(t, secret), "1.2.3.4", "test"); !errors.Is(err, ErrInvalidMFACode) {
+		t.Fatalf("replayed code: %v", err)
+	}
+
+	clk.advance(30 * time.Second)
+	pair, u, err := auth.VerifyMFA(challenge.Token, clk.code(t, secret), "1.2.3.4", "test")
+	if err != nil || pair == nil || u.ID != 1 {
+		t.Fatalf("verify: pair=%v err=%v", pair, err)
+	}
+	if want := clk.t.Add(security.AccessTokenTTL); !pair.AccessExpiresAt.Equal(want) {
+		t.Fatalf("access token expires %v, want %v", pair.AccessExpiresAt, want)
+	}
+
+	clk.advance(30 * time.Second)
+	if _, _, err := auth.VerifyMFA(challenge.Token, clk.code(t, secret), "1.2.3.4", "test"); !errors.Is(err, ErrInvalidMFAChallenge) {
+		t.Fatalf("challenge reused: %v", err)
+	}
+}
+
+func TestMFAChallengeExpires(t *testing.T) {
+	clk := &clock{t: time.Date(2030, 1, 2, 3, 4, 0, 0, time.UTC)}
+	users, mfa, secret, _ := newMFAUser(t, clk)
+	auth := NewAuthService(users, &memTokens{}).WithMFA(mfa).WithClock(clk.now)
+
+	_, challenge, _, err := auth.Login("a@example.com", "s3cret-pass", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clk.advance(security.MFAChallengeTTL + time.Second)
+	if _, _, err := auth.VerifyMFA(challenge.Token, clk.code(t, secret), "", ""); !errors.Is(err, ErrInvalidMFAChallenge) {
+		t.Fatalf("expired challenge: %v", err)
+	}
+}
+
+func TestMFAConfirmRequiresSetupAndValidCode(t *testing.T) {
+	clk := &clock{t: time.Date(2030, 1, 2, 3, 4, 0, 0, time.UTC)}
+	users := newMemUsers(model.User{ID: 1, Email: "a@example.com"})
+	mfa := NewMFAService(users, &memRecovery{}, "").WithClock(clk.now)
+
+	if _, err := mfa.Confirm(1, "123456"); !errors.Is(err, ErrMFANotSetUp) {
+		t.Fatalf("confirm before setup: %v", err)
+	}
+	secret, _, err := mfa.Setup(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clk.advance(-5 * time.Minute)
+	stale := clk.code(t, secret)
+	clk.advance(5 * time.Minute)
+	if _, err := mfa.Confirm(1, stale); !errors.Is(err, ErrInvalidMFACode) {
+		t.Fatalf("stale code: %v", err)
+	}
+	codes, err := mfa.Confirm(1, clk.code(t, secret))
+	if err != nil || len(codes) != recoveryCodeCount {
+		t.Fatalf("confirm: %d codes, err=%v", len(codes), err)
+	}
+	if _, err := mfa.Confirm(1, clk.code(t, secret)); !errors.Is(err, ErrMFAAlreadyEnabled) {
+		t.Fatalf("second confirm: %v", err)
+	}
+	on, left, err := mfa.Status(1)
+	if err != nil || !on || left != recoveryCodeCount {
+		t.Fatalf("status: on=%v left=%d err=%v", on, left, err)
+	}
+}
+
+func TestMFARecoveryCodeIsSingleUse(t *testing.T) {
+	clk := &clock{t: time.Date(2030, 1, 2, 3, 4, 0, 0, time.UTC)}
+	users, mfa, _, codes := newMFAUser(t, clk)
+	u, _ := users.FindByID(1)
+
+	if err := mfa.Verify(u, codes[0]); err != nil {
+		t.Fatalf("recovery code: %v", err)
+	}
+	if err := mfa.Verify(u, codes[0]); !errors.Is(err, ErrInvalidMFACode) {
+		t.Fatalf("reused recovery code: %v", err)
+	}
+	if err := mfa.Verify(u, "not-a-code"); !errors.Is(err, ErrInvalidMFACode) {
+		t.Fatalf("unknown recovery code: %v", err)
+	}
+	if _, left, _ := mfa.Status(1); left != recoveryCodeCount-1 {
+		t.Fatalf("unused codes = %d, want %d", left, recoveryCodeCount-1)
+	}
+}
+
+// memGrants records role grants
+type memGrants map[uint][]string
+
+func (g memGrants) GrantByName(user *model.User, name string) error {
+	g[user.ID] = append(g[user.ID], name)
+	return nil
+}
+
+func TestFirstRegisteredUserBecomesAdmin(t *testing.T) {
+	users := newMemUsers()
+	grants := memGrants{}
+	auth := NewAuthService(users, &memTokens{}).WithRegistration(false).WithRoles(grants)
+
+	if err := auth.Register("first@example.com", "password123"); err != nil {
+		t.Fatal(err)
+	}
+	if err := auth.Register("second@example.com", "password123"); !errors.Is(err, ErrRegistrationClosed) {
+		t.Fatalf("second sign-up with registration closed: %v", err)
+	}
+	auth.WithRegistration(true)
+	if err := auth.Register("second@example.com", "password123"); err != nil {
+		t.Fatal(err)
+	}
+	if len(grants) != 1 || len(grants[1]) != 1 || grants[1][0] != model.AdminRoleName {
+		t.Fatalf("grants = %v, want admin for user 1 only", grants)
+	}
+}
+
+func TestLogoutEverywhereKeepsAPIKeys(t *testing.T) {
+	hash, err := security.HashPassword("s3cret-pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := newMemUsers(model.User{ID: 1, Email: "a@example.com", Password: hash})
+	tokens := &memTokens{}
+	auth := NewAuthService(users, tokens)
+
+	var pairs []*TokenPair
+	for i := 0; i < 2; i++ {
+		pair, _, _, err := auth.Login("a@example.com", "s3cret-pass", "1.2.3.4", "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		pairs = append(pairs, pair)
+	}
+	key, keyHash, _ := security.GenerateAPIKey()
+	if err := tokens.Create(&domain.Token{UserID: 1, Kind: domain.TokenKindAPIKey, TokenHash: keyHash, ExpiresAt: domain.NoExpiry}); err != nil {
+		t.Fatal(err)
+	}
+
+	current, err := tokens.FindByHash(security.HashToken(pairs[0].AccessToken))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := auth.Logout(current, true); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range pairs {
+		for _, plain := range []string{p.AccessToken, p.RefreshToken} {
+			if tok, _ := tokens.FindByHash(security.HashToken(plain)); tok == nil || !tok.Revoked {
+				t.Fatalf("session token %+v survived logout everywhere", tok)
+			}
+		}
+	}
+	if tok, err := tokens.FindByHash(security.HashToken(key)); err != nil || tok.Revoked {
+		t.Fatalf("API key revoked by logout everywhere: %+v, %v", tok, err)
+	}
+}
@@ -1,6 +1,8 @@
 package service
 
 import (
+    "gorm.io/gorm"
+
     "metronic/internal/model"
     "metronic/internal/repository"
 )
@@ -8,12 +10,19 @@ import (
 // CustomerService provides read-only access to customers
 type CustomerService struct {
     customers *repository.CustomerRepository
+    audit     *AuditService
 }
 
 func NewCustomerService(r *repository.CustomerRepository) *CustomerService {
     return &CustomerService{customers: r}
 }
 
+// WithAudit records customer deletions in the audit trail (optional wiring style)
+func (s *CustomerService) WithAudit(a *AuditService) *CustomerService {
+    s.audit = a
+    return s
+}
+
 func (s *CustomerService) List() ([]model.Customer, error) {
     return s.customers.List()
 }
@@ -22,8 +31,18 @@ func (s *CustomerService) Get(id uint) (*model.Customer, error) {
     return s.customers.FindByID(id)
 }
 
-func (s *CustomerService) Delete(id uint) error {
-    return s.customers.DeleteByID(id)
+func (s *CustomerService) Delete(actor Actor, id uint) error {
+    return s.audit.Tx(func(tx *gorm.DB) error {
+        customers := s.customers.WithTx(tx)
+        c, err := customers.FindByID(id)
+        if err != nil {
+            return err
+        }
+        if err := customers.DeleteByID(id); err != nil {
+            return err
+        }
+        return s.audit.Record(tx, actor, "customer.delete", model.AuditEntityCustomer, id, snapshot(c), nil)
+    })
 }
 
 func (s *CustomerService) ListByShop(shopUUID string) ([]model.Customer, error) {
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"metronic/internal/notify"
+)
+
+// ExpiryReport summarises the shops that expired or expire within about a month
+type ExpiryReport struct {
+	Total    int
+	Expired  int
+	Expiring int
+	Message  notify.Message
+}
+
+// ExpiryReport lists shops within ±30 days of expiry ("notOver1y" in
+// repository terms) and renders the message sent to notification channels
+func (s *ShopService) ExpiryReport(now time.Time, title string) (ExpiryReport, error) {
+	items, _, err := s.ListPagedFiltered(1, 2000, "notOver1y", "")
+	if err != nil {
+		return ExpiryReport{}, err
+	}
+	var expired []string
+	var expiring []string
+	for _, m := range items {
+		if m.ExpiredAt == nil {
+			continue
+		}
+		days := int(m.ExpiredAt.Sub(now).Hours() / 24)
+		// future days might be truncated; make non-zero future days at least 1
+		if m.ExpiredAt.After(now) && days < 1 {
+			days = 1
+		}
+		if m.ExpiredAt.Before(now) {
+			expired = append(expired, fmt.Sprintf("• %s — hết hạn %d ngày", m.Domain, -days))
+		} else {
+			expiring = append(expiring, fmt.Sprintf("• %s — còn %d ngày", m.Domain, days))
+		}
+	}
+	text := fmt.Sprintf("Ngày: %s\nTổng: %d\nHết hạn: %d\nSắp hết hạn: %d\n\n",
+		now.Format("2006-01-02"), len(items), len(expired), len(expiring))
+	if len(expired) > 0 {
+		text += "Hết hạn:\n" + strings.Join(expired, "\n") + "\n\n"
+	}
+	if len(expiring) > 0 {
+		text += "Sắp hết hạn:\n" + strings.Join(expiring, "\n")
+	}
+	return ExpiryReport{
+		Total:    len(items),
+		Expired:  len(expired),
+		Expiring: len(expiring),
+		Message:  notify.Message{Subject: title, Text: strings.TrimRight(text, "\n")},
+	}, nil
+}
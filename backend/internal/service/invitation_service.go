@@ -0,0 +1,253 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"metronic/internal/mailer"
+	"metronic/internal/model"
+	"metronic/internal/security"
+)
+
+var (
+	ErrInvalidInvite = errors.New("invalid or expired invitation")
+	ErrInvitePending = errors.New("a pending invitation already exists for this email")
+	ErrInviteNotOpen = errors.New("invitation was already accepted or revoked")
+	ErrEmailTaken    = errors.New("email already exists")
+	ErrUnknownRole   = errors.New("unknown role")
+	// ErrRoleNotGrantable is returned when the inviter could not assign the role themselves
+	ErrRoleNotGrantable = errors.New("you can only invite with a role whose permissions you hold")
+)
+
+// InvitationStore persists invitations; InvitationRepository satisfies it
+type InvitationStore interface {
+	Create(inv *model.Invitation) error
+	Update(inv *model.Invitation) error
+	FindByID(id uint) (*model.Invitation, error)
+	FindPendingByEmail(email string, now time.Time) (*model.Invitation, error)
+	List(status string, now time.Time) ([]model.Invitation, error)
+	Accept(inv *model.Invitation, user *model.User, now time.Time) error
+}
+
+// RoleLookup resolves roles and what a user may do; RoleRepository satisfies it
+type RoleLookup interface {
+	FindByID(id uint) (*model.Role, error)
+	PermissionCodesByUserID(userID uint) ([]string, error)
+}
+
+// InvitationService lets admins invite users by email with a pre-selected role.
+// Links carry an HMAC-signed "<id>.<expiry>.<nonce>" payload; only the hash of
+// the nonce is stored, so resending a link invalidates the previous one.
+type InvitationService struct {
+	invites   InvitationStore
+	users     UserStore
+	roles     RoleLookup
+	mail      mailer.Mailer
+	acceptURL string
+	secret    []byte
+	now       func() time.Time
+}
+
+// NewInvitationService builds the service. acceptURL is the frontend page that
+// receives the token as ?token=... and posts it to /auth/invitations/accept.
+func NewInvitationService(i InvitationStore, u UserStore, r RoleLookup, m mailer.Mailer, acceptURL string, secret []byte) *InvitationService {
+	return &InvitationService{invites: i, users: u, roles: r, mail: m, acceptURL: acceptURL, secret: secret, now: time.Now}
+}
+
+// Invite creates an invitation for email with the given role and mails the link.
+// The role must be one the inviter could assign: see canGrant.
+func (s *InvitationService) Invite(email string, roleID, invitedBy uint) (*model.Invitation, error) {
+	email = normalizeEmail(email)
+	if _, err := s.users.FindByEmail(email); err == nil {
+		return nil, ErrEmailTaken
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if _, err := s.invites.FindPendingByEmail(email, s.now()); err == nil {
+		return nil, ErrInvitePending
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	role, err := s.roles.FindByID(roleID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUnknownRole
+		}
+		return nil, err
+	}
+	if err := s.canGrant(invitedBy, role); err != nil {
+		return nil, err
+	}
+	inv := &model.Invitation{Email: email, RoleID: role.ID, InvitedBy: invitedBy}
+	token := s.rotate(inv)
+	if err := s.invites.Create(inv); err != nil {
+		return nil, err
+	}
+	inv.Role = role
+	return inv, s.send(inv, token)
+}
+
+// canGrant allows a role when the inviter manages roles or already holds every
+// permission it grants, so users.manage alone cannot mint an admin account
+func (s *InvitationService) canGrant(inviter uint, role *model.Role) error {
+	codes, err := s.roles.PermissionCodesByUserID(inviter)
+	if err != nil {
+		return err
+	}
+	held := make(map[string]bool, len(codes))
+	for _, c := range codes {
+		held[c] = true
+	}
+	if held[model.PermRolesManage] {
+		return nil
+	}
+	for _, p := range role.Permissions {
+		if !held[p.Code] {
+			return ErrRoleNotGrantable
+		}
+	}
+	return nil
+}
+
+// List returns invitations by status (pending, accepted, revoked, expired; empty for all)
+func (s *InvitationService) List(status string) ([]model.Invitation, error) {
+	return s.invites.List(status, s.now())
+}
+
+// Resend issues a fresh link with a new expiry; earlier links stop working
+func (s *InvitationService) Resend(id uint) (*model.Invitation, error) {
+	inv, err := s.invites.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if inv.AcceptedAt != nil || inv.RevokedAt != nil {
+		return nil, ErrInviteNotOpen
+	}
+	token := s.rotate(inv)
+	if err := s.invites.Update(inv); err != nil {
+		return nil, err
+	}
+	return inv, s.send(inv, token)
+}
+
+// Revoke cancels an invitation that has not been accepted yet
+func (s *InvitationService) Revoke(id uint) error {
+	inv, err := s.invites.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if inv.AcceptedAt != nil || inv.RevokedAt != nil {
+		return ErrInviteNotOpen
+	}
+	now := s.now()
+	inv.RevokedAt = &now
+	return s.invites.Update(inv)
+}
+
+// Lookup returns the open invitation behind a token, e.g. to prefill the accept form
+func (s *InvitationService) Lookup(token string) (*model.Invitation, error) {
+	id, nonce, err := s.parse(token)
+	if err != nil {
+		return nil, err
+	}
+	inv, err := s.invites.FindByID(id)
+	if err != nil || inv.NonceHash != security.HashToken(nonce) ||
+		inv.AcceptedAt != nil || inv.RevokedAt != nil || !inv.ExpiresAt.After(s.now()) {
+		return nil, ErrInvalidInvite
+	}
+	return inv, nil
+}
+
+// Accept creates the invited user with the chosen name and password and the
+// pre-selected role. The invitation is single-use.
+func (s *InvitationService) Accept(token, name, password string) (*model.User, error) {
+	inv, err := s.Lookup(token)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.users.FindByEmail(inv.Email); err == nil {
+		return nil, ErrEmailTaken
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	hash, err := security.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	user := &model.User{Name: strings.TrimSpace(name), Email: inv.Email, Password: hash}
+	if err := s.invites.Accept(inv, user, s.now()); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidInvite
+		}
+		return nil, err
+	}
+	if inv.Role != nil {
+		user.Roles = []model.Role{*inv.Role}
+	}
+	return user, nil
+}
+
+// rotate gives inv a new nonce and expiry and returns the plain nonce. The
+// link is signed in send, once the row (and so its ID) exists.
+func (s *InvitationService) rotate(inv *model.Invitation) string {
+	nonce, hash, exp := security.GenerateTokenWithTTL(security.InviteTTL)
+	inv.NonceHash = hash
+	inv.ExpiresAt = exp
+	return nonce
+}
+
+func (s *InvitationService) sign(inv *model.Invitation, nonce string) string {
+	payload := fmt.Sprintf("%d.%d.%s", inv.ID, inv.ExpiresAt.Unix(), nonce)
+	return security.SignToken(s.secret, payload)
+}
+
+func (s *InvitationService) parse(token string) (uint, string, error) {
+	payload, err := security.VerifySignedToken(s.secret, token)
+	if err != nil {
+		return 0, "", ErrInvalidInvite
+	}
+	parts := strings.SplitN(payload, ".", 3)
+	if len(parts) != 3 {
+		return 0, "", ErrInvalidInvite
+	}
+	id, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, "", ErrInvalidInvite
+	}
+	exp, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || !time.Unix(exp, 0).After(s.now()) {
+		return 0, "", ErrInvalidInvite
+	}
+	return uint(id), parts[2], nil
+}
+
+func (s *InvitationService) send(inv *model.Invitation, nonce string) error {
+	link := s.link(s.sign(inv, nonce))
+	roleName := ""
+	if inv.Role != nil {
+		roleName = inv.Role.Name
+	}
+	body := fmt.Sprintf("Xin chào,\n\n"+
+		"Bạn được mời tham gia trang quản trị với vai trò %s.\n"+
+		"Mở liên kết sau để đặt tên và mật khẩu (hiệu lực đến %s):\n\n%s\n\n"+
+		"Nếu bạn không mong đợi lời mời này, hãy bỏ qua email.\n",
+		roleName, inv.ExpiresAt.Format("15:04 02/01/2006"), link)
+	return s.mail.Send(mailer.Message{To: []string{inv.Email}, Subject: "Lời mời tham gia", Body: body})
+}
+
+func (s *InvitationService) link(token string) string {
+	u, err := url.Parse(s.acceptURL)
+	if err != nil || s.acceptURL == "" {
+		return token
+	}
+	q := u.Query()
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"metronic/internal/mailer"
+	"metronic/internal/model"
+)
+
+// memInvites is an in-memory InvitationStore
+type memInvites struct {
+	items []model.Invitation
+}
+
+func (m *memInvites) Create(inv *model.Invitation) error {
+	inv.ID = uint(len(m.items) + 1)
+	m.items = append(m.items, *inv)
+	return nil
+}
+
+func (m *memInvites) Update(inv *model.Invitation) error {
+	m.items[inv.ID-1] = *inv
+	return nil
+}
+
+func (m *memInvites) FindByID(id uint) (*model.Invitation, error) {
+	if id == 0 || int(id) > len(m.items) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	inv := m.items[id-1]
+	return &inv, nil
+}
+
+func (m *memInvites) FindPendingByEmail(email string, now time.Time) (*model.Invitation, error) {
+	for _, inv := range m.items {
+		if inv.Email == email && inv.AcceptedAt == nil && inv.RevokedAt == nil && inv.ExpiresAt.After(now) {
+			return &inv, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memInvites) List(status string, now time.Time) ([]model.Invitation, error) {
+	return m.items, nil
+}
+
+func (m *memInvites) Accept(inv *model.Invitation, user *model.User, now time.Time) error {
+	inv.AcceptedAt = &now
+	return m.Update(inv)
+}
+
+// memRoles is an in-memory RoleLookup: roles by ID and role IDs per user
+type memRoles struct {
+	roles map[uint]model.Role
+	users map[uint][]uint
+}
+
+func (m *memRoles) FindByID(id uint) (*model.Role, error) {
+	r, ok := m.roles[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &r, nil
+}
+
+func (m *memRoles) PermissionCodesByUserID(userID uint) ([]string, error) {
+	var codes []string
+	for _, id := range m.users[userID] {
+		for _, p := range m.roles[id].Permissions {
+			codes = append(codes, p.Code)
+		}
+	}
+	return codes, nil
+}
+
+type nopMailer struct{ sent int }
+
+func (m *nopMailer) Send(mailer.Message) error {
+	m.sent++
+	return nil
+}
+
+func perms(codes ...string) []model.Permission {
+	out := make([]model.Permission, len(codes))
+	for i, c := range codes {
+		out[i] = model.Permission{Code: c}
+	}
+	return out
+}
+
+func TestInviteOnlyWithGrantableRoles(t *testing.T) {
+	roles := &memRoles{
+		roles: map[uint]model.Role{
+			1: {ID: 1, Name: "admin", Permissions: perms(model.PermUsersManage, model.PermRolesManage, model.PermShopsManage)},
+			2: {ID: 2, Name: "support", Permissions: perms(model.PermUsersManage, model.PermShopsView)},
+			3: {ID: 3, Name: "viewer", Permissions: perms(model.PermShopsView)},
+		},
+		// user 10 is an admin, user 20 has users.manage but not roles.manage
+		users: map[uint][]uint{10: {1}, 20: {2}},
+	}
+	mail := &nopMailer{}
+	svc := NewInvitationService(&memInvites{}, newMemUsers(), roles, mail, "", []byte("secret"))
+
+	if _, err := svc.Invite("x@example.com", 1, 20); !errors.Is(err, ErrRoleNotGrantable) {
+		t.Fatalf("users.manage inviting an admin: %v", err)
+	}
+	if _, err := svc.Invite("x@example.com", 3, 20); err != nil {
+		t.Fatalf("inviting with a subset of the inviter's permissions: %v", err)
+	}
+	if _, err := svc.Invite("y@example.com", 1, 10); err != nil {
+		t.Fatalf("roles.manage inviting an admin: %v", err)
+	}
+	if _, err := svc.Invite("z@example.com", 9, 10); !errors.Is(err, ErrUnknownRole) {
+		t.Fatalf("unknown role: %v", err)
+	}
+	if mail.sent != 2 {
+		t.Fatalf("sent %d invitations, want 2", mail.sent)
+	}
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"metronic/internal/model"
+)
+
+func TestParseIPRange(t *testing.T) {
+	for raw, want := range map[string]string{
+		"203.0.113.7":          "203.0.113.7/32",
+		" 10.1.2.3/8 ":         "10.0.0.0/8",
+		"2001:db8::1":          "2001:db8::1/128",
+		"2001:db8:abcd::/48":   "2001:db8:abcd::/48",
+		"::ffff:192.0.2.1":     "192.0.2.1/32",
+		"::ffff:192.0.2.0/120": "192.0.2.0/24",
+	} {
+		got, err := ParseIPRange(raw)
+		if err != nil || got != want {
+			t.Errorf("ParseIPRange(%q) = %q, %v; want %q", raw, got, err, want)
+		}
+	}
+	for _, raw := range []string{"", "shop.vn", "10.0.0.0/33", "1.2.3", "::ffff:0:0/95", "::ffff:0.0.0.0/64"} {
+		if _, err := ParseIPRange(raw); err != ErrIPRangeInvalid {
+			t.Errorf("ParseIPRange(%q) err = %v, want ErrIPRangeInvalid", raw, err)
+		}
+	}
+}
+
+// memIPRanges is an in-memory IPRangeStore; err makes every lookup fail
+type memIPRanges struct {
+	recs  []model.ShopIPRange
+	err   error
+	calls int
+}
+
+func (m *memIPRanges) Create(rec *model.ShopIPRange) error {
+	rec.ID = uint(len(m.recs) + 1)
+	m.recs = append(m.recs, *rec)
+	return nil
+}
+
+func (m *memIPRanges) ListByShopID(shopID uint) ([]model.ShopIPRange, error) {
+	m.calls++
+	if m.err != nil {
+		return nil, m.err
+	}
+	out := []model.ShopIPRange{}
+	for _, r := range m.recs {
+		if r.ShopID == shopID {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+func (m *memIPRanges) FindForShop(shopID, id uint) (*model.ShopIPRange, error) {
+	return nil, errors.New("not used")
+}
+
+func (m *memIPRanges) Delete(id uint) error { return errors.New("not used") }
+
+func TestIPAllowed(t *testing.T) {
+	ranges := &memIPRanges{recs: []model.ShopIPRange{
+		{ShopID: 1, CIDR: "203.0.113.0/24"},
+		{ShopID: 1, CIDR: "2001:db8::/32"},
+	}}
+	svc := NewShopService(newMemShops()).WithIPRanges(ranges)
+
+	cases := []struct {
+		shop uint
+		ip   string
+		want bool
+	}{
+		{2, "198.51.100.1", true}, // no allowlist
+		{1, "203.0.113.9", true},
+		{1, "2001:db8::7", true},
+		{1, "::ffff:203.0.113.9", true}, // IPv4-mapped client of a v4 range
+		{1, "198.51.100.1", false},
+		{1, "::ffff:198.51.100.1", false},
+		{1, "2001:db9::1", false},
+		{1, "not an ip", false},
+	}
+	for _, tc := range cases {
+		got, err := svc.IPAllowed(tc.shop, tc.ip)
+		if err != nil || got != tc.want {
+			t.Errorf("IPAllowed(%d, %q) = %v, %v; want %v", tc.shop, tc.ip, got, err, tc.want)
+		}
+		if restricted := svc.IPRestricted(tc.shop); restricted != (tc.shop == 1) {
+			t.Errorf("IPRestricted(%d) = %v", tc.shop, restricted)
+		}
+	}
+}
+
+func TestIPAllowedFailsOpen(t *testing.T) {
+	boom := errors.New("db down")
+	ranges := &memIPRanges{recs: []model.ShopIPRange{{ShopID: 1, CIDR: "203.0.113.0/24"}}, err: boom}
+	svc := NewShopService(newMemShops()).WithIPRanges(ranges)
+
+	ok, err := svc.IPAllowed(1, "198.51.100.1")
+	if !ok || !errors.Is(err, boom) {
+		t.Fatalf("store error: got %v, %v; want allowed with the error", ok, err)
+	}
+	// the answer is per client while the allowlist is unknown
+	if !svc.IPRestricted(1) {
+		t.Fatal("IPRestricted is false while the lookup fails")
+	}
+
+	// the failure is not cached: the next lookup sees the ranges
+	svc.WithCheckCache(time.Minute, time.Minute)
+	ranges.err = nil
+	if ok, err := svc.IPAllowed(1, "198.51.100.1"); ok || err != nil {
+		t.Fatalf("after recovery: got %v, %v; want denied", ok, err)
+	}
+	calls := ranges.calls
+	svc.IPAllowed(1, "203.0.113.1")
+	if ranges.calls != calls {
+		t.Fatalf("cached allowlist was loaded again")
+	}
+}
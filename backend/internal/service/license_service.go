@@ -0,0 +1,151 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"metronic/internal/model"
+	"metronic/internal/repository"
+	"metronic/internal/security"
+)
+
+// LicensePayload is the signed content of a license file. Shop servers cache
+// the file and, after verifying the signature, accept it until GraceUntil
+// (or forever when ExpiredAt is null) without calling the backend.
+type LicensePayload struct {
+	Type       string      `json:"type"`
+	Version    int         `json:"version"`
+	Serial     uint        `json:"serial"`
+	ShopUUID   string      `json:"shop_uuid"`
+	Domains    []string    `json:"domains"`
+	Active     bool        `json:"active"`
+	Plan       LicensePlan `json:"plan"`
+	ExpiredAt  *time.Time  `json:"expired_at"`
+	GraceUntil *time.Time  `json:"grace_until"`
+	IssuedAt   time.Time   `json:"issued_at"`
+}
+
+// LicensePlan describes the billing plan of the licensed shop
+type LicensePlan struct {
+	PricePerCycle int `json:"price_per_cycle"`
+	CycleMonths   int `json:"cycle_months"`
+}
+
+// LicenseService issues signed license documents for shops
+type LicenseService struct {
+	licenses *repository.ShopLicenseRepository
+	shops    *repository.ShopRepository
+	signer   *security.KeyRing
+	policy   model.ExpiryPolicy
+	now      func() time.Time
+}
+
+// NewLicenseService builds the service. policy must be the shops' expiry
+// policy, so a cached license stops verifying when /shops/check suspends.
+func NewLicenseService(l *repository.ShopLicenseRepository, s *repository.ShopRepository, signer *security.KeyRing, policy model.ExpiryPolicy) *LicenseService {
+	return &LicenseService{licenses: l, shops: s, signer: signer, policy: policy, now: time.Now}
+}
+
+// Current returns the newest license of a shop, issuing the first one on demand
+func (s *LicenseService) Current(shopID uint) (*model.ShopLicense, *model.Shop, error) {
+	m, err := s.shops.FindByID(shopID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.current(m)
+}
+
+// CurrentByUUID is Current keyed by shop UUID, for the public refresh endpoint
+func (s *LicenseService) CurrentByUUID(uuid string) (*model.ShopLicense, *model.Shop, error) {
+	m, err := s.shops.FindByUUID(uuid)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.current(m)
+}
+
+// current also re-issues a license signed by a key other than the active one:
+// after a restart with the ephemeral ring, or a rotation that dropped the old
+// key, the stored document no longer verifies against /shops/check/keys.
+func (s *LicenseService) current(m *model.Shop) (*model.ShopLicense, *model.Shop, error) {
+	l, err := s.licenses.Latest(m.ID)
+	if err == nil && l.KID == s.signer.ActiveKID() {
+		return l, m, nil
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, err
+	}
+	l, err = s.Issue(nil, m, !m.Active)
+	return l, m, err
+}
+
+// licenseExpiry returns the expiry and the end of the offline grace written
+// into m's license: the shop's grace period (its override or the global
+// one), none for a revoked license, nothing for a shop that never expires.
+func licenseExpiry(m *model.Shop, policy model.ExpiryPolicy, revoked bool) (*time.Time, *time.Time) {
+	if m.ExpiredAt == nil {
+		return nil, nil
+	}
+	exp := m.ExpiredAt.UTC().Truncate(time.Second)
+	grace := exp
+	if !revoked {
+		grace = exp.AddDate(0, 0, policy.GraceDaysFor(m))
+	}
+	return &exp, &grace
+}
+
+// Issue signs and stores a new license for m. Revoked licenses get no grace
+// window, so a revocation takes effect as soon as clients refresh.
+// It is a no-op on a nil receiver, like AuditService.Record.
+func (s *LicenseService) Issue(tx *gorm.DB, m *model.Shop, revoked bool) (*model.ShopLicense, error) {
+	if s == nil {
+		return nil, nil
+	}
+	repo := s.licenses.WithTx(tx)
+	serial, err := repo.MaxSerial(m.ID)
+	if err != nil {
+		return nil, err
+	}
+	domains, err := s.shops.WithTx(tx).DomainNames(m.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(domains) == 0 {
+		domains = []string{m.Domain}
+	}
+	p := LicensePayload{
+		Type:     "subly-license",
+		Version:  1,
+		Serial:   serial + 1,
+		ShopUUID: m.UUID,
+		Domains:  domains,
+		Active:   m.Active,
+		Plan:     LicensePlan{PricePerCycle: m.PricePerCycle, CycleMonths: m.CycleMonths},
+		IssuedAt: s.now().UTC().Truncate(time.Second),
+	}
+	p.ExpiredAt, p.GraceUntil = licenseExpiry(m, s.policy, revoked)
+	env, err := s.signer.Seal(p)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := json.Marshal(env)
+	if err != nil {
+		return nil, err
+	}
+	l := &model.ShopLicense{
+		ShopID:     m.ID,
+		Serial:     p.Serial,
+		KID:        env.KID,
+		ExpiredAt:  p.ExpiredAt,
+		GraceUntil: p.GraceUntil,
+		Document:   string(doc),
+		IssuedAt:   p.IssuedAt,
+	}
+	if err := repo.Create(l); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
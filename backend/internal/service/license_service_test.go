@@ -0,0 +1,41 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"metronic/internal/model"
+)
+
+func TestLicenseGraceFollowsExpiryPolicy(t *testing.T) {
+	exp := time.Date(2030, 5, 1, 10, 0, 0, 0, time.UTC)
+	three := 3
+	policy := model.ExpiryPolicy{GraceDays: 0, ExpiringSoonDays: 30}
+	cases := []struct {
+		name    string
+		shop    model.Shop
+		policy  model.ExpiryPolicy
+		revoked bool
+		want    time.Time
+	}{
+		{"no grace by default", model.Shop{ExpiredAt: &exp, Active: true}, policy, false, exp},
+		{"global grace", model.Shop{ExpiredAt: &exp, Active: true}, model.ExpiryPolicy{GraceDays: 7}, false, exp.AddDate(0, 0, 7)},
+		{"shop override", model.Shop{ExpiredAt: &exp, Active: true, GraceDays: &three}, policy, false, exp.AddDate(0, 0, 3)},
+		{"revoked", model.Shop{ExpiredAt: &exp, GraceDays: &three}, policy, true, exp},
+	}
+	for _, tc := range cases {
+		gotExp, gotGrace := licenseExpiry(&tc.shop, tc.policy, tc.revoked)
+		if gotExp == nil || !gotExp.Equal(exp) || gotGrace == nil || !gotGrace.Equal(tc.want) {
+			t.Fatalf("%s: expiry %v grace %v, want grace %v", tc.name, gotExp, gotGrace, tc.want)
+		}
+		// the offline license ends when /shops/check starts reporting suspended
+		if !tc.revoked {
+			if _, end := tc.policy.State(&tc.shop, exp); !end.Equal(*gotGrace) {
+				t.Fatalf("%s: license grace %v, shop grace %v", tc.name, gotGrace, end)
+			}
+		}
+	}
+	if e, g := licenseExpiry(&model.Shop{}, policy, false); e != nil || g != nil {
+		t.Fatalf("shop without expiry: %v %v", e, g)
+	}
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+
+	"metronic/internal/model"
+	"metronic/internal/repository"
+)
+
+// LoginGuardConfig tunes brute-force protection
+type LoginGuardConfig struct {
+	// BackoffAfter is how many consecutive failures per email or IP are free;
+	// every further failure doubles the wait before the next attempt.
+	BackoffAfter int
+	BackoffBase  time.Duration
+	BackoffMax   time.Duration
+	// LockAfter failures on an existing account lock it for LockDuration.
+	LockAfter    int
+	LockDuration time.Duration
+	// Window bounds how far back failures are considered.
+	Window time.Duration
+}
+
+// DefaultLoginGuardConfig returns conservative defaults
+func DefaultLoginGuardConfig() LoginGuardConfig {
+	return LoginGuardConfig{
+		BackoffAfter: 5,
+		BackoffBase:  time.Second,
+		BackoffMax:   5 * time.Minute,
+		LockAfter:    10,
+		LockDuration: 15 * time.Minute,
+		Window:       24 * time.Hour,
+	}
+}
+
+// LoginBlockedError is returned when a login is refused before checking the password
+type LoginBlockedError struct {
+	Locked     bool
+	RetryAfter time.Duration
+}
+
+func (e *LoginBlockedError) Error() string {
+	if e.Locked {
+		return "account locked"
+	}
+	return fmt.Sprintf("too many failed attempts, retry in %ds", int(e.RetryAfter.Seconds()+0.5))
+}
+
+// LoginAttemptStore records attempts and counts failures; LoginAttemptRepository satisfies it
+type LoginAttemptStore interface {
+	Create(a *model.LoginAttempt) error
+	LastSuccess(email string, since time.Time) (*time.Time, error)
+	Failures(column, value string, since time.Time) (int64, *time.Time, error)
+}
+
+// LoginGuard throttles logins by email and IP and locks accounts after repeated failures
+type LoginGuard struct {
+	attempts LoginAttemptStore
+	users    UserStore
+	cfg      LoginGuardConfig
+	now      func() time.Time
+}
+
+func NewLoginGuard(a LoginAttemptStore, u UserStore, cfg LoginGuardConfig) *LoginGuard {
+	return &LoginGuard{attempts: a, users: u, cfg: cfg, now: time.Now}
+}
+
+// WithClock replaces the time source (tests)
+func (g *LoginGuard) WithClock(now func() time.Time) *LoginGuard {
+	g.now = now
+	return g
+}
+
+// Check refuses the attempt if the account is locked or the email/IP is backing off
+func (g *LoginGuard) Check(user *model.User, email, ip string) error {
+	now := g.now()
+	if user != nil && user.LockedUntil != nil && user.LockedUntil.After(now) {
+		return &LoginBlockedError{Locked: true, RetryAfter: user.LockedUntil.Sub(now)}
+	}
+	var wait time.Duration
+	for _, key := range [][2]string{{"email", normalizeEmail(email)}, {"ip", ip}} {
+		if key[1] == "" {
+			continue
+		}
+		n, last, err := g.streak(key[0], key[1], now)
+		if err != nil {
+			return err
+		}
+		if last == nil {
+			continue
+		}
+		if d := last.Add(backoffDelay(int(n), g.cfg)).Sub(now); d > wait {
+			wait = d
+		}
+	}
+	if wait > 0 {
+		return &LoginBlockedError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// streak counts the failures within Window that decide the backoff. A success
+// ends the email streak only: one valid account must not clear the backoff of
+// an IP trying passwords against many others.
+func (g *LoginGuard) streak(column, value string, now time.Time) (int64, *time.Time, error) {
+	since := now.Add(-g.cfg.Window)
+	if column == "email" {
+		ok, err := g.attempts.LastSuccess(value, since)
+		if err != nil {
+			return 0, nil, err
+		}
+		if ok != nil {
+			since = *ok
+		}
+	}
+	return g.attempts.Failures(column, value, since)
+}
+
+// Fail records a failed attempt and locks the account when the threshold is reached
+func (g *LoginGuard) Fail(user *model.User, email, ip, ua, reason string) error {
+	rec := &model.LoginAttempt{Email: normalizeEmail(email), IP: ip, UserAgent: truncate(ua, 255), Reason: reason, CreatedAt: g.now()}
+	if user != nil {
+		rec.UserID = &user.ID
+	}
+	if err := g.attempts.Create(rec); err != nil {
+		return err
+	}
+	if user == nil {
+		return nil
+	}
+	n, err := g.users.IncrementFailedLogins(user.ID)
+	if err != nil {
+		return err
+	}
+	if g.cfg.LockAfter > 0 && n >= g.cfg.LockAfter {
+		return g.users.LockAccount(user.ID, g.now().Add(g.cfg.LockDuration))
+	}
+	return nil
+}
+
+// Blocked records an attempt that was refused by Check
+func (g *LoginGuard) Blocked(user *model.User, email, ip, ua string, blocked *LoginBlockedError) error {
+	reason := "throttled"
+	if blocked.Locked {
+		reason = "locked"
+	}
+	rec := &model.LoginAttempt{Email: normalizeEmail(email), IP: ip, UserAgent: truncate(ua, 255), Reason: reason, CreatedAt: g.now()}
+	if user != nil {
+		rec.UserID = &user.ID
+	}
+	return g.attempts.Create(rec)
+}
+
+// Succeed records a successful attempt, which also ends the email's failure streak
+func (g *LoginGuard) Succeed(user *model.User, ip, ua string) error {
+	rec := &model.LoginAttempt{UserID: &user.ID, Email: normalizeEmail(user.Email), IP: ip, UserAgent: truncate(ua, 255), Success: true, CreatedAt: g.now()}
+	if err := g.attempts.Create(rec); err != nil {
+		return err
+	}
+	if user.FailedLoginCount == 0 && user.LockedUntil == nil {
+		return nil
+	}
+	return g.users.ResetLoginFailures(user.ID)
+}
+
+// Unlock clears an account lock and its email failure streak
+func (g *LoginGuard) Unlock(userID uint) error {
+	u, err := g.users.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if err := g.users.ResetLoginFailures(u.ID); err != nil {
+		return err
+	}
+	// a success marker ends the email streak so backoff starts from zero
+	return g.attempts.Create(&model.LoginAttempt{UserID: &u.ID, Email: normalizeEmail(u.Email), Success: true, Reason: "admin_unlock", CreatedAt: g.now()})
+}
+
+// withTx returns a copy of the guard whose repositories run in tx. Stores
+// other than the gorm repositories (test fakes) are kept as they are.
+func (g *LoginGuard) withTx(tx *gorm.DB) *LoginGuard {
+	c := *g
+	if r, ok := g.users.(*repository.UserRepository); ok {
+		c.users = r.WithTx(tx)
+	}
+	if r, ok := g.attempts.(*repository.LoginAttemptRepository); ok {
+		c.attempts = r.WithTx(tx)
+	}
+	return &c
+}
+
+// backoffDelay is the wait after the n-th consecutive failure
+func backoffDelay(n int, cfg LoginGuardConfig) time.Duration {
+	over := n - cfg.BackoffAfter
+	if over < 0 {
+		return 0
+	}
+	d := cfg.BackoffBase
+	for i := 0; i < over && d < cfg.BackoffMax; i++ {
+		d *= 2
+	}
+	if d > cfg.BackoffMax {
+		d = cfg.BackoffMax
+	}
+	return d
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// truncate cuts s to at most n bytes without splitting a UTF-8 sequence
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
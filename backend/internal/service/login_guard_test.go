@@ -0,0 +1,211 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"metronic/internal/model"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	cfg := LoginGuardConfig{BackoffAfter: 3, BackoffBase: time.Second, BackoffMax: 10 * time.Second}
+	cases := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{2, 0},
+		{3, time.Second},
+		{4, 2 * time.Second},
+		{6, 8 * time.Second},
+		{7, 10 * time.Second},
+		{50, 10 * time.Second},
+	}
+	for _, tc := range cases {
+		if got := backoffDelay(tc.failures, cfg); got != tc.want {
+			t.Fatalf("failures=%d: got %v want %v", tc.failures, got, tc.want)
+		}
+	}
+}
+
+// memAttempts is an in-memory LoginAttemptStore
+type memAttempts struct {
+	recs []model.LoginAttempt
+}
+
+func (m *memAttempts) Create(a *model.LoginAttempt) error {
+	a.ID = uint(len(m.recs) + 1)
+	m.recs = append(m.recs, *a)
+	return nil
+}
+
+func (m *memAttempts) LastSuccess(email string, since time.Time) (*time.Time, error) {
+	var last *time.Time
+	for i := range m.recs {
+		r := &m.recs[i]
+		if r.Email == email && r.Success && !r.CreatedAt.Before(since) && (last == nil || r.CreatedAt.After(*last)) {
+			last = &r.CreatedAt
+		}
+	}
+	return last, nil
+}
+
+func (m *memAttempts) Failures(column, value string, since time.Time) (int64, *time.Time, error) {
+	var n int64
+	var last *time.Time
+	for i := range m.recs {
+		r := &m.recs[i]
+		key := r.Email
+		if column == "ip" {
+			key = r.IP
+		}
+		if key == value && !r.Success && r.CreatedAt.After(since) {
+			n++
+			if last == nil || r.CreatedAt.After(*last) {
+				last = &r.CreatedAt
+			}
+		}
+	}
+	return n, last, nil
+}
+
+func newTestGuard(cfg LoginGuardConfig) (*LoginGuard, *memUsers, *clock) {
+	clk := &clock{t: time.Date(2030, 1, 2, 3, 4, 0, 0, time.UTC)}
+	users := newMemUsers(
+		model.User{ID: 1, Email: "a@example.com"},
+		model.User{ID: 2, Email: "b@example.com"},
+	)
+	return NewLoginGuard(&memAttempts{}, users, cfg).WithClock(clk.now), users, clk
+}
+
+func (m *memUsers) get(t *testing.T, id uint) *model.User {
+	t.Helper()
+	u, err := m.FindByID(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func TestLoginGuardLocksAndUnlocksAfterLockExpires(t *testing.T) {
+	cfg := DefaultLoginGuardConfig()
+	cfg.BackoffAfter, cfg.LockAfter, cfg.LockDuration = 100, 3, 15*time.Minute
+	g, users, clk := newTestGuard(cfg)
+
+	for i := 0; i < 3; i++ {
+		if err := g.Fail(users.get(t, 1), "a@example.com", "1.1.1.1", "", "invalid_credentials"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var blocked *LoginBlockedError
+	err := g.Check(users.get(t, 1), "a@example.com", "1.1.1.1")
+	if !errors.As(err, &blocked) || !blocked.Locked || blocked.RetryAfter != 15*time.Minute {
+		t.Fatalf("after %d failures: %v", 3, err)
+	}
+
+	clk.advance(16 * time.Minute)
+	if err := g.Check(users.get(t, 1), "a@example.com", "1.1.1.1"); err != nil {
+		t.Fatalf("lock expired: %v", err)
+	}
+	// the counter restarted with the lock, so one more typo does not re-lock
+	if err := g.Fail(users.get(t, 1), "a@example.com", "1.1.1.1", "", "invalid_credentials"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Check(users.get(t, 1), "a@example.com", "1.1.1.1"); err != nil {
+		t.Fatalf("one failure after the lock: %v", err)
+	}
+	if u := users.get(t, 1); u.FailedLoginCount != 1 {
+		t.Fatalf("failed_login_count = %d, want 1", u.FailedLoginCount)
+	}
+}
+
+func TestLoginGuardUnlockClearsLockAndEmailStreak(t *testing.T) {
+	cfg := DefaultLoginGuardConfig()
+	cfg.BackoffAfter, cfg.LockAfter = 2, 3
+	g, users, _ := newTestGuard(cfg)
+
+	for i := 0; i < 3; i++ {
+		_ = g.Fail(users.get(t, 1), "a@example.com", "1.1.1.1", "", "invalid_credentials")
+	}
+	if err := g.Unlock(1); err != nil {
+		t.Fatal(err)
+	}
+	u := users.get(t, 1)
+	if u.LockedUntil != nil || u.FailedLoginCount != 0 {
+		t.Fatalf("after unlock: locked_until=%v count=%d", u.LockedUntil, u.FailedLoginCount)
+	}
+	if err := g.Check(u, "a@example.com", "2.2.2.2"); err != nil {
+		t.Fatalf("unlocked account from another IP: %v", err)
+	}
+}
+
+func TestLoginGuardBacksOffPerIP(t *testing.T) {
+	cfg := DefaultLoginGuardConfig()
+	cfg.BackoffAfter, cfg.BackoffBase, cfg.LockAfter = 2, time.Second, 0
+	g, users, clk := newTestGuard(cfg)
+
+	// credential stuffing: one IP, a different unknown email each time
+	for _, email := range []string{"x@example.com", "y@example.com", "z@example.com"} {
+		_ = g.Fail(nil, email, "6.6.6.6", "", "invalid_credentials")
+	}
+	var blocked *LoginBlockedError
+	if err := g.Check(nil, "w@example.com", "6.6.6.6"); !errors.As(err, &blocked) || blocked.RetryAfter != 2*time.Second {
+		t.Fatalf("third failure from the IP: %v", err)
+	}
+	if err := g.Check(nil, "w@example.com", "7.7.7.7"); err != nil {
+		t.Fatalf("other IP: %v", err)
+	}
+
+	// a valid login from the same IP ends that account's streak, not the IP's
+	if err := g.Succeed(users.get(t, 2), "6.6.6.6", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Check(nil, "w@example.com", "6.6.6.6"); !errors.As(err, &blocked) {
+		t.Fatalf("IP streak ended by another account's success: %v", err)
+	}
+
+	clk.advance(2 * time.Second)
+	if err := g.Check(nil, "w@example.com", "6.6.6.6"); err != nil {
+		t.Fatalf("after the backoff: %v", err)
+	}
+}
+
+func TestLoginGuardSuccessEndsEmailStreak(t *testing.T) {
+	cfg := DefaultLoginGuardConfig()
+	cfg.BackoffAfter, cfg.LockAfter = 1, 0
+	g, users, clk := newTestGuard(cfg)
+
+	_ = g.Fail(users.get(t, 1), "a@example.com", "1.1.1.1", "", "invalid_credentials")
+	_ = g.Fail(users.get(t, 1), "a@example.com", "2.2.2.2", "", "invalid_credentials")
+	if err := g.Check(users.get(t, 1), "a@example.com", "3.3.3.3"); err == nil {
+		t.Fatal("email streak not enforced")
+	}
+	clk.advance(time.Minute)
+	if err := g.Succeed(users.get(t, 1), "3.3.3.3", ""); err != nil {
+		t.Fatal(err)
+	}
+	clk.advance(time.Second)
+	if err := g.Check(users.get(t, 1), "a@example.com", "3.3.3.3"); err != nil {
+		t.Fatalf("after success: %v", err)
+	}
+}
+
+func TestTruncateKeepsRunesWhole(t *testing.T) {
+	cases := []struct {
+		in   string
+		n    int
+		want string
+	}{
+		{"abc", 5, "abc"},
+		{"abcdef", 3, "abc"},
+		{"lỗi kết nối", 5, "lỗi"},
+		{"lỗi", 3, "l"}, // "ỗ" is 3 bytes; cutting inside it drops it
+		{"ỗỗ", 4, "ỗ"},
+	}
+	for _, tc := range cases {
+		if got := truncate(tc.in, tc.n); got != tc.want {
+			t.Fatalf("truncate(%q, %d) = %q, want %q", tc.in, tc.n, got, tc.want)
+		}
+	}
+}
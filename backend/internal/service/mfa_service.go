@@ -0,0 +1,203 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"metronic/internal/model"
+	"metronic/internal/security"
+)
+
+var (
+	ErrMFANotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrMFANotSetUp       = errors.New("two-factor setup has not been started")
+	ErrInvalidMFACode    = errors.New("invalid two-factor code")
+)
+
+// recoveryCodeCount is how many one-time recovery codes are issued per enrolment
+const recoveryCodeCount = 10
+
+// totpSkew is how many 30s steps of clock drift are tolerated either way
+const totpSkew = 1
+
+// RecoveryCodeStore keeps hashed recovery codes; RecoveryCodeRepository satisfies it
+type RecoveryCodeStore interface {
+	ReplaceForUser(userID uint, hashes []string) error
+	Consume(userID uint, hash string, t time.Time) (bool, error)
+	CountUnused(userID uint) (int64, error)
+	DeleteByUserID(userID uint) error
+}
+
+// MFAService manages TOTP enrolment and verifies second factors
+type MFAService struct {
+	users    UserStore
+	recovery RecoveryCodeStore
+	issuer   string
+	now      func() time.Time
+}
+
+func NewMFAService(u UserStore, rc RecoveryCodeStore, issuer string) *MFAService {
+	if issuer == "" {
+		issuer = "Subly"
+	}
+	return &MFAService{users: u, recovery: rc, issuer: issuer, now: time.Now}
+}
+
+// WithClock replaces the time source (used by tests to pin TOTP steps)
+func (s *MFAService) WithClock(now func() time.Time) *MFAService {
+	s.now = now
+	return s
+}
+
+// Setup starts enrolment: a fresh secret is stored but not trusted until Confirm
+func (s *MFAService) Setup(userID uint) (secret, uri string, err error) {
+	u, err := s.users.FindByID(userID)
+	if err != nil {
+		return "", "", err
+	}
+	if u.TOTPEnabled {
+		return "", "", ErrMFAAlreadyEnabled
+	}
+	secret = security.GenerateTOTPSecret()
+	u.TOTPSecret = &secret
+	u.TOTPLastStep = 0
+	if err := s.users.Update(u); err != nil {
+		return "", "", err
+	}
+	return secret, security.TOTPURI(s.issuer, u.Email, secret), nil
+}
+
+// Confirm enables 2FA once the user proves their app produces valid codes.
+// It returns the plain recovery codes, which are shown only this once.
+func (s *MFAService) Confirm(userID uint, code string) ([]string, error) {
+	u, err := s.users.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if u.TOTPEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if u.TOTPSecret == nil {
+		return nil, ErrMFANotSetUp
+	}
+	step, ok := security.VerifyTOTP(*u.TOTPSecret, code, s.now(), totpSkew)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+	codes := security.GenerateRecoveryCodes(recoveryCodeCount)
+	if err := s.recovery.ReplaceForUser(u.ID, hashRecoveryCodes(codes)); err != nil {
+		return nil, err
+	}
+	u.TOTPEnabled = true
+	u.TOTPLastStep = step
+	if err := s.users.Update(u); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes invalidates old recovery codes after a valid TOTP code
+func (s *MFAService) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	u, err := s.users.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !u.TOTPEnabled {
+		return nil, ErrMFANotEnabled
+	}
+	if err := s.verifyTOTP(u, code); err != nil {
+		return nil, err
+	}
+	codes := security.GenerateRecoveryCodes(recoveryCodeCount)
+	if err := s.recovery.ReplaceForUser(u.ID, hashRecoveryCodes(codes)); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable turns 2FA off after a valid TOTP or recovery code
+func (s *MFAService) Disable(userID uint, code string) error {
+	u, err := s.users.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if !u.TOTPEnabled {
+		return ErrMFANotEnabled
+	}
+	if err := s.Verify(u, code); err != nil {
+		return err
+	}
+	return s.Reset(userID)
+}
+
+// Reset clears 2FA without a code; meant for admins helping a locked-out user
+func (s *MFAService) Reset(userID uint) error {
+	u, err := s.users.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	u.TOTPSecret = nil
+	u.TOTPEnabled = false
+	u.TOTPLastStep = 0
+	if err := s.users.Update(u); err != nil {
+		return err
+	}
+	return s.recovery.DeleteByUserID(userID)
+}
+
+// Status reports whether 2FA is on and how many recovery codes remain
+func (s *MFAService) Status(userID uint) (bool, int64, error) {
+	u, err := s.users.FindByID(userID)
+	if err != nil {
+		return false, 0, err
+	}
+	if !u.TOTPEnabled {
+		return false, 0, nil
+	}
+	n, err := s.recovery.CountUnused(userID)
+	return true, n, err
+}
+
+// Verify accepts either a current TOTP code or an unused recovery code
+func (s *MFAService) Verify(u *model.User, code string) error {
+	if !u.TOTPEnabled || u.TOTPSecret == nil {
+		return ErrMFANotEnabled
+	}
+	if len(code) == security.TOTPDigits {
+		return s.verifyTOTP(u, code)
+	}
+	ok, err := s.recovery.Consume(u.ID, security.HashToken(security.NormalizeRecoveryCode(code)), s.now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+// verifyTOTP checks the code and refuses a step that was already used
+func (s *MFAService) verifyTOTP(u *model.User, code string) error {
+	step, ok := security.VerifyTOTP(*u.TOTPSecret, code, s.now(), totpSkew)
+	if !ok {
+		return ErrInvalidMFACode
+	}
+	advanced, err := s.users.AdvanceTOTPStep(u.ID, step)
+	if err != nil {
+		return err
+	}
+	if !advanced {
+		return ErrInvalidMFACode
+	}
+	u.TOTPLastStep = step
+	return nil
+}
+
+func hashRecoveryCodes(codes []string) []string {
+	out := make([]string, len(codes))
+	for i, c := range codes {
+		out[i] = security.HashToken(c)
+	}
+	return out
+}
@@ -0,0 +1,322 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"metronic/internal/mailer"
+	"metronic/internal/model"
+	"metronic/internal/notify"
+	"metronic/internal/repository"
+)
+
+// ErrNoChannels is returned by Notify when no enabled channel takes the event
+var ErrNoChannels = errors.New("no notification channel is subscribed to this event")
+
+// ChannelInput creates or updates a channel. On update, nil fields keep their
+// value, config keys sent empty keep the stored secret and keys listed in
+// Clear are removed.
+type ChannelInput struct {
+	Name    *string           `json:"name"`
+	Kind    *string           `json:"kind"`
+	Config  map[string]string `json:"config"`
+	Clear   []string          `json:"clear"`
+	Events  []string          `json:"events"`
+	Enabled *bool             `json:"enabled"`
+}
+
+// NotificationService sends operator notifications to the channels stored
+// in the database and records every delivery
+type NotificationService struct {
+	repo   *repository.NotificationRepository
+	mail   mailer.Mailer
+	client *http.Client
+	audit  *AuditService
+}
+
+func NewNotificationService(r *repository.NotificationRepository, mail mailer.Mailer) *NotificationService {
+	return &NotificationService{repo: r, mail: mail}
+}
+
+// WithAudit records channel changes in the audit trail (optional wiring style)
+func (s *NotificationService) WithAudit(a *AuditService) *NotificationService {
+	s.audit = a
+	return s
+}
+
+// WithHTTPClient replaces the default 10s-timeout client of chat and webhook channels
+func (s *NotificationService) WithHTTPClient(c *http.Client) *NotificationService {
+	s.client = c
+	return s
+}
+
+// SeedLegacySlack turns SLACK_WEBHOOK_URL into a stored Slack channel the
+// first time the backend starts without any channel
+func (s *NotificationService) SeedLegacySlack(url string) error {
+	if url == "" {
+		return nil
+	}
+	n, err := s.repo.CountChannels()
+	if err != nil || n > 0 {
+		return err
+	}
+	cfg, _ := json.Marshal(notify.Config{"url": url})
+	return s.repo.CreateChannel(&model.NotificationChannel{Name: "Slack (SLACK_WEBHOOK_URL)", Kind: notify.KindSlack, Config: cfg, Enabled: true})
+}
+
+// Notify sends msg to every enabled channel subscribed to event. Each send
+// is recorded; the error joins the failures, ErrNoChannels if nothing matched.
+func (s *NotificationService) Notify(ctx context.Context, event string, msg notify.Message) ([]model.NotificationDelivery, error) {
+	channels, err := s.repo.ListEnabled()
+	if err != nil {
+		return nil, err
+	}
+	msg.Event = event
+	var out []model.NotificationDelivery
+	var errs []error
+	for i := range channels {
+		if !subscribed(&channels[i], event) {
+			continue
+		}
+		d := s.send(ctx, &channels[i], msg)
+		out = append(out, d)
+		if d.Status == model.DeliveryFailed {
+			errs = append(errs, fmt.Errorf("%s: %s", channels[i].Name, d.Error))
+		}
+	}
+	if len(out) == 0 {
+		return out, ErrNoChannels
+	}
+	return out, errors.Join(errs...)
+}
+
+// subscribed reports whether ch takes event; the test event reaches every channel
+func subscribed(ch *model.NotificationChannel, event string) bool {
+	if ch.Events == "" || event == model.NotifyTest {
+		return true
+	}
+	for _, e := range strings.Split(ch.Events, ",") {
+		if strings.TrimSpace(e) == event {
+			return true
+		}
+	}
+	return false
+}
+
+// send delivers to one channel and records the outcome
+func (s *NotificationService) send(ctx context.Context, ch *model.NotificationChannel, msg notify.Message) model.NotificationDelivery {
+	d := model.NotificationDelivery{ChannelID: ch.ID, Event: msg.Event, Subject: truncate(msg.Subject, 255), Status: model.DeliverySent}
+	start := time.Now()
+	n, err := s.notifier(ch)
+	if err == nil {
+		err = n.Send(ctx, msg)
+	}
+	d.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		d.Status, d.Error = model.DeliveryFailed, truncate(err.Error(), 500)
+	}
+	if rerr := s.repo.CreateDelivery(&d); rerr != nil && err == nil {
+		d.Error = "delivered, but not recorded: " + rerr.Error()
+	}
+	return d
+}
+
+func (s *NotificationService) notifier(ch *model.NotificationChannel) (notify.Notifier, error) {
+	var cfg notify.Config
+	if len(ch.Config) > 0 {
+		if err := json.Unmarshal(ch.Config, &cfg); err != nil {
+			return nil, fmt.Errorf("bad channel config: %w", err)
+		}
+	}
+	return notify.New(ch.Kind, cfg, s.mail, s.client)
+}
+
+// Test sends a test message to one channel, enabled or not
+func (s *NotificationService) Test(ctx context.Context, id uint) (model.NotificationDelivery, error) {
+	ch, err := s.repo.FindChannel(id)
+	if err != nil {
+		return model.NotificationDelivery{}, err
+	}
+	d := s.send(ctx, ch, notify.Message{Event: model.NotifyTest, Subject: "Subly: tin nhắn thử", Text: "Kênh \"" + ch.Name + "\" đã được cấu hình đúng."})
+	if d.Status == model.DeliveryFailed {
+		return d, errors.New(d.Error)
+	}
+	return d, nil
+}
+
+// ListChannels returns every channel with its secrets masked
+func (s *NotificationService) ListChannels() ([]model.NotificationChannel, error) {
+	items, err := s.repo.ListChannels()
+	if err != nil {
+		return nil, err
+	}
+	for i := range items {
+		mask(&items[i])
+	}
+	return items, nil
+}
+
+// mask fills Settings with the config, secrets reduced to their last 4 characters
+func mask(ch *model.NotificationChannel) {
+	var cfg notify.Config
+	_ = json.Unmarshal(ch.Config, &cfg)
+	ch.Settings = map[string]string{}
+	for k, v := range cfg {
+		if notify.SecretKeys[k] && v != "" {
+			if len(v) > 4 {
+				v = "…" + v[len(v)-4:]
+			} else {
+				v = "…"
+			}
+		}
+		ch.Settings[k] = v
+	}
+}
+
+// CreateChannel validates the config by building the notifier before storing it
+func (s *NotificationService) CreateChannel(actor Actor, in ChannelInput) (*model.NotificationChannel, error) {
+	ch := &model.NotificationChannel{Enabled: true, EventList: []string{}}
+	if err := s.apply(ch, in, notify.Config{}); err != nil {
+		return nil, err
+	}
+	err := s.audit.Tx(func(tx *gorm.DB) error {
+		if err := s.repo.WithTx(tx).CreateChannel(ch); err != nil {
+			return err
+		}
+		return s.audit.Record(tx, actor, "notification_channel.create", model.AuditEntityChannel, ch.ID, nil, snapshot(ch))
+	})
+	if err != nil {
+		return nil, err
+	}
+	mask(ch)
+	return ch, nil
+}
+
+func (s *NotificationService) UpdateChannel(actor Actor, id uint, in ChannelInput) (*model.NotificationChannel, error) {
+	ch, err := s.repo.FindChannel(id)
+	if err != nil {
+		return nil, err
+	}
+	before := snapshot(ch)
+	var stored notify.Config
+	_ = json.Unmarshal(ch.Config, &stored)
+	if stored == nil {
+		stored = notify.Config{}
+	}
+	if err := s.apply(ch, in, stored); err != nil {
+		return nil, err
+	}
+	err = s.audit.Tx(func(tx *gorm.DB) error {
+		if err := s.repo.WithTx(tx).SaveChannel(ch); err != nil {
+			return err
+		}
+		after := snapshot(ch)
+		if in.Config != nil || in.Clear != nil {
+			// secrets stay out of the trail; only note that the config changed
+			before["config"], after["config"] = "(previous)", "(updated)"
+		}
+		return s.audit.Record(tx, actor, "notification_channel.update", model.AuditEntityChannel, ch.ID, before, after)
+	})
+	if err != nil {
+		return nil, err
+	}
+	mask(ch)
+	return ch, nil
+}
+
+// apply copies in onto ch. Config values sent empty keep the stored ones, so
+// clients can resubmit the masked settings they were shown. When an endpoint
+// key changes, the stored secrets are only kept if they are sent again, so a
+// token is never delivered to a host it was not entered for.
+func (s *NotificationService) apply(ch *model.NotificationChannel, in ChannelInput, stored notify.Config) error {
+	if in.Name != nil {
+		ch.Name = strings.TrimSpace(*in.Name)
+	}
+	if ch.Name == "" {
+		return errors.New("name is required")
+	}
+	if in.Kind != nil {
+		ch.Kind = *in.Kind
+	}
+	cfg := notify.Config{}
+	for k, v := range stored {
+		cfg[k] = v
+	}
+	for _, k := range in.Clear {
+		if _, ok := in.Config[k]; ok {
+			return fmt.Errorf("config key %q is both set and cleared", k)
+		}
+		delete(cfg, k)
+	}
+	sent := map[string]bool{}
+	for k, v := range in.Config {
+		if v = strings.TrimSpace(v); v != "" && !strings.HasPrefix(v, "…") {
+			cfg[k] = v
+			sent[k] = true
+		}
+	}
+	for k := range notify.EndpointKeys {
+		if cfg[k] == stored[k] {
+			continue
+		}
+		for secret := range notify.SecretKeys {
+			if secret != k && cfg[secret] != "" && !sent[secret] {
+				return fmt.Errorf("%s changed: enter %s again or clear it", k, secret)
+			}
+		}
+	}
+	if in.Events != nil {
+		for _, e := range in.Events {
+			if !knownEvent(e) {
+				return fmt.Errorf("unknown event %q", e)
+			}
+		}
+		ch.SetEvents(in.Events)
+	}
+	if in.Enabled != nil {
+		ch.Enabled = *in.Enabled
+	}
+	if _, err := notify.New(ch.Kind, cfg, s.mail, s.client); err != nil {
+		return err
+	}
+	raw, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	ch.Config = raw
+	return nil
+}
+
+func knownEvent(e string) bool {
+	for _, k := range model.NotificationEvents {
+		if e == k {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *NotificationService) DeleteChannel(actor Actor, id uint) error {
+	ch, err := s.repo.FindChannel(id)
+	if err != nil {
+		return err
+	}
+	return s.audit.Tx(func(tx *gorm.DB) error {
+		if err := s.repo.WithTx(tx).DeleteChannel(ch.ID); err != nil {
+			return err
+		}
+		return s.audit.Record(tx, actor, "notification_channel.delete", model.AuditEntityChannel, ch.ID, snapshot(ch), nil)
+	})
+}
+
+// ListDeliveries returns the delivery log, newest first
+func (s *NotificationService) ListDeliveries(channelID uint, status string, page, limit int) ([]model.NotificationDelivery, int64, error) {
+	return s.repo.ListDeliveries(channelID, status, page, limit)
+}
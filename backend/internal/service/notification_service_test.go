@@ -0,0 +1,135 @@
+package service
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"metronic/internal/model"
+	"metronic/internal/notify"
+)
+
+func TestChannelSubscriptions(t *testing.T) {
+	all := &model.NotificationChannel{}
+	abuse := &model.NotificationChannel{Events: model.NotifyAbuseFinding}
+	if !subscribed(all, model.NotifyExpiryReport) || !subscribed(all, model.NotifyAbuseFinding) {
+		t.Fatal("a channel without events should take every event")
+	}
+	if subscribed(abuse, model.NotifyExpiryReport) || !subscribed(abuse, model.NotifyAbuseFinding) {
+		t.Fatal("subscription list not honoured")
+	}
+	if !subscribed(abuse, model.NotifyTest) {
+		t.Fatal("test messages should reach every channel")
+	}
+}
+
+func TestMaskHidesSecrets(t *testing.T) {
+	ch := &model.NotificationChannel{Config: model.JSON(`{"token":"123456:ABCDEF","chat_id":"-100","secret":"ab"}`)}
+	mask(ch)
+	want := map[string]string{"token": "…CDEF", "chat_id": "-100", "secret": "…"}
+	for k, v := range want {
+		if ch.Settings[k] != v {
+			t.Errorf("%s = %q, want %q", k, ch.Settings[k], v)
+		}
+	}
+}
+
+func TestApplyChannelConfig(t *testing.T) {
+	s := &NotificationService{}
+	webhook := func() (*model.NotificationChannel, notify.Config) {
+		return &model.NotificationChannel{Name: "hook", Kind: notify.KindWebhook},
+			notify.Config{"url": "https://a.example/hook", "secret": "s3cret"}
+	}
+	stored := func(ch *model.NotificationChannel) notify.Config {
+		var cfg notify.Config
+		if err := json.Unmarshal(ch.Config, &cfg); err != nil {
+			t.Fatal(err)
+		}
+		return cfg
+	}
+
+	// masked and empty values keep the stored ones
+	ch, cfg := webhook()
+	if err := s.apply(ch, ChannelInput{Config: map[string]string{"url": "…hook", "secret": ""}}, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if got := stored(ch); got["url"] != "https://a.example/hook" || got["secret"] != "s3cret" {
+		t.Fatalf("resubmitted mask changed the config: %v", got)
+	}
+
+	// clear removes a key
+	ch, cfg = webhook()
+	if err := s.apply(ch, ChannelInput{Clear: []string{"secret"}}, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := stored(ch)["secret"]; ok {
+		t.Fatal("cleared secret still stored")
+	}
+	ch, cfg = webhook()
+	if err := s.apply(ch, ChannelInput{Clear: []string{"url"}}, cfg); err == nil {
+		t.Fatal("clearing a required key was accepted")
+	}
+	ch, cfg = webhook()
+	if err := s.apply(ch, ChannelInput{Config: map[string]string{"secret": "x"}, Clear: []string{"secret"}}, cfg); err == nil {
+		t.Fatal("setting and clearing one key was accepted")
+	}
+
+	// a new url needs the secret again, or it to be cleared
+	ch, cfg = webhook()
+	err := s.apply(ch, ChannelInput{Config: map[string]string{"url": "https://b.example/hook"}}, cfg)
+	if err == nil || !strings.Contains(err.Error(), "secret") {
+		t.Fatalf("url change kept the old secret: %v", err)
+	}
+	if cfg["url"] != "https://a.example/hook" {
+		t.Fatal("refused update changed the stored config")
+	}
+	for _, in := range []ChannelInput{
+		{Config: map[string]string{"url": "https://b.example/hook", "secret": "n3w"}},
+		{Config: map[string]string{"url": "https://b.example/hook"}, Clear: []string{"secret"}},
+	} {
+		ch, cfg = webhook()
+		if err := s.apply(ch, in, cfg); err != nil {
+			t.Fatalf("%+v: %v", in, err)
+		}
+	}
+
+	// the same holds for a Telegram bot token when base_url moves
+	tg := &model.NotificationChannel{Name: "tg", Kind: notify.KindTelegram}
+	tgCfg := notify.Config{"token": "123:abc", "chat_id": "-100"}
+	if err := s.apply(tg, ChannelInput{Config: map[string]string{"base_url": "https://proxy.example"}}, tgCfg); err == nil {
+		t.Fatal("base_url change kept the old token")
+	}
+	if err := s.apply(tg, ChannelInput{Config: map[string]string{"base_url": "https://proxy.example", "token": "456:def"}}, tgCfg); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestChannelEventsRoundTrip(t *testing.T) {
+	s := &NotificationService{}
+	ch := &model.NotificationChannel{Name: "slack", Kind: notify.KindSlack, EventList: []string{}}
+	events := []string{model.NotifyAbuseFinding, model.NotifyExpiryReport}
+	if err := s.apply(ch, ChannelInput{Config: map[string]string{"url": "https://hooks.example/x"}, Events: events}, notify.Config{}); err != nil {
+		t.Fatal(err)
+	}
+	loaded := &model.NotificationChannel{Events: ch.Events}
+	if err := loaded.AfterFind(nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, got := range []*model.NotificationChannel{ch, loaded} {
+		b, _ := json.Marshal(got)
+		var out struct {
+			Events []string `json:"events"`
+		}
+		if err := json.Unmarshal(b, &out); err != nil {
+			t.Fatalf("events not an array: %s", b)
+		}
+		if strings.Join(out.Events, " ") != strings.Join(events, " ") {
+			t.Fatalf("events = %v, want %v", out.Events, events)
+		}
+	}
+	all := &model.NotificationChannel{}
+	all.AfterFind(nil)
+	if b, _ := json.Marshal(all); !strings.Contains(string(b), `"events":[]`) {
+		t.Fatalf("no subscription should read as []: %s", b)
+	}
+}
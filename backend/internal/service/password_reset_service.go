@@ -0,0 +1,123 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"metronic/internal/domain"
+	"metronic/internal/mailer"
+	"metronic/internal/repository"
+	"metronic/internal/security"
+)
+
+var ErrInvalidResetToken = errors.New("invalid or expired reset token")
+
+// PasswordResetService issues emailed single-use reset links and applies resets
+type PasswordResetService struct {
+	users    *repository.UserRepository
+	tokens   domain.TokenRepository
+	mail     mailer.Mailer
+	resetURL string
+	guard    *LoginGuard
+}
+
+// NewPasswordResetService builds the service. resetURL is the frontend page
+// that receives the token as ?token=... and posts it to /auth/reset-password.
+func NewPasswordResetService(u *repository.UserRepository, t domain.TokenRepository, m mailer.Mailer, resetURL string) *PasswordResetService {
+	return &PasswordResetService{users: u, tokens: t, mail: m, resetURL: resetURL}
+}
+
+// WithLoginGuard lets a successful reset also lift a login lockout
+func (s *PasswordResetService) WithLoginGuard(g *LoginGuard) *PasswordResetService {
+	s.guard = g
+	return s
+}
+
+// Forgot mails a reset link if the email belongs to a user. It returns nil for
+// unknown emails too, so the endpoint cannot be used to discover accounts.
+func (s *PasswordResetService) Forgot(email, ip, ua string) error {
+	user, err := s.users.FindByEmail(strings.TrimSpace(email))
+	if err != nil {
+		return nil
+	}
+	// only the newest link works
+	if err := s.tokens.RevokeAllByUserIDAndKind(user.ID, domain.TokenKindPasswordReset); err != nil {
+		return err
+	}
+	plain, hash, exp := security.GenerateTokenWithTTL(security.PasswordResetTTL)
+	tok := &domain.Token{
+		UserID:    user.ID,
+		Kind:      domain.TokenKindPasswordReset,
+		TokenHash: hash,
+		ExpiresAt: exp,
+	}
+	if ip != "" {
+		tok.IP = &ip
+	}
+	if ua != "" {
+		ua = truncate(ua, 255)
+		tok.UserAgent = &ua
+	}
+	if err := s.tokens.Create(tok); err != nil {
+		return err
+	}
+	link := s.link(plain)
+	body := fmt.Sprintf("Xin chào %s,\n\n"+
+		"Chúng tôi nhận được yêu cầu đặt lại mật khẩu cho tài khoản %s.\n"+
+		"Mở liên kết sau để đặt mật khẩu mới (hiệu lực đến %s, chỉ dùng được một lần):\n\n%s\n\n"+
+		"Nếu bạn không yêu cầu, hãy bỏ qua email này; mật khẩu hiện tại vẫn giữ nguyên.\n",
+		displayName(user.Name, user.Email), user.Email, exp.Format("15:04 02/01/2006"), link)
+	return s.mail.Send(mailer.Message{To: []string{user.Email}, Subject: "Đặt lại mật khẩu", Body: body})
+}
+
+// Reset sets a new password using a reset token and signs the user out everywhere
+func (s *PasswordResetService) Reset(token, newPassword string) error {
+	stored, err := s.tokens.FindByHash(security.HashToken(strings.TrimSpace(token)))
+	if err != nil || stored.Kind != domain.TokenKindPasswordReset || stored.Revoked || stored.ExpiresAt.Before(time.Now()) {
+		return ErrInvalidResetToken
+	}
+	ok, err := s.tokens.RevokeIfActive(stored.ID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidResetToken
+	}
+	user, err := s.users.FindByID(stored.UserID)
+	if err != nil {
+		return err
+	}
+	hash, err := security.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	user.Password = hash
+	if err := s.users.Update(user); err != nil {
+		return err
+	}
+	if s.guard != nil {
+		_ = s.guard.Unlock(user.ID)
+	}
+	return s.tokens.RevokeAllByUserID(user.ID)
+}
+
+func (s *PasswordResetService) link(token string) string {
+	u, err := url.Parse(s.resetURL)
+	if err != nil || s.resetURL == "" {
+		return token
+	}
+	q := u.Query()
+	q.Set("token", token)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func displayName(name, email string) string {
+	if strings.TrimSpace(name) != "" {
+		return name
+	}
+	return email
+}
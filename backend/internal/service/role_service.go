@@ -0,0 +1,252 @@
+package service
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"metronic/internal/model"
+	"metronic/internal/repository"
+)
+
+var (
+	ErrBuiltinRole       = errors.New("built-in role cannot be modified")
+	ErrUnknownPermission = errors.New("unknown permission code")
+)
+
+// RoleService manages roles, their permissions and user assignments
+type RoleService struct {
+	roles *repository.RoleRepository
+	users *repository.UserRepository
+	audit *AuditService
+}
+
+func NewRoleService(r *repository.RoleRepository, u *repository.UserRepository) *RoleService {
+	return &RoleService{roles: r, users: u}
+}
+
+// WithAudit records role changes in the audit trail (optional wiring style)
+func (s *RoleService) WithAudit(a *AuditService) *RoleService {
+	s.audit = a
+	return s
+}
+
+// List returns all roles with their permissions
+func (s *RoleService) List() ([]model.Role, error) {
+	return s.roles.List()
+}
+
+// Get returns a role by ID
+func (s *RoleService) Get(id uint) (*model.Role, error) {
+	return s.roles.FindByID(id)
+}
+
+// ListPermissions returns every known permission
+func (s *RoleService) ListPermissions() ([]model.Permission, error) {
+	return s.roles.ListPermissions()
+}
+
+// Create creates a role with an optional initial set of permission codes
+func (s *RoleService) Create(actor Actor, name string, codes []string) (*model.Role, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+	if _, err := s.roles.FindByName(name); err == nil {
+		return nil, errors.New("role already exists")
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	perms, err := s.resolvePermissions(codes)
+	if err != nil {
+		return nil, err
+	}
+	role := &model.Role{Name: name}
+	err = s.audit.Tx(func(tx *gorm.DB) error {
+		roles := s.roles.WithTx(tx)
+		if err := roles.Create(role); err != nil {
+			return err
+		}
+		if err := roles.AttachPermissions(role, perms); err != nil {
+			return err
+		}
+		role.Permissions = perms
+		return s.audit.Record(tx, actor, "role.create", model.AuditEntityRole, role.ID, nil, roleSnapshot(role))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.roles.FindByID(role.ID)
+}
+
+// Delete removes a role; the built-in admin role is protected
+func (s *RoleService) Delete(actor Actor, id uint) error {
+	role, err := s.roles.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if role.Name == model.AdminRoleName {
+		return ErrBuiltinRole
+	}
+	return s.audit.Tx(func(tx *gorm.DB) error {
+		if err := s.roles.WithTx(tx).DeleteByID(id); err != nil {
+			return err
+		}
+		return s.audit.Record(tx, actor, "role.delete", model.AuditEntityRole, id, roleSnapshot(role), nil)
+	})
+}
+
+// AttachPermissions grants the given permission codes to a role
+func (s *RoleService) AttachPermissions(actor Actor, roleID uint, codes []string) (*model.Role, error) {
+	role, err := s.roles.FindByID(roleID)
+	if err != nil {
+		return nil, err
+	}
+	perms, err := s.resolvePermissions(codes)
+	if err != nil {
+		return nil, err
+	}
+	return s.changePermissions(actor, "role.permissions_attach", role, func(roles *repository.RoleRepository) error {
+		return roles.AttachPermissions(role, perms)
+	})
+}
+
+// DetachPermissions removes the given permission codes from a role
+func (s *RoleService) DetachPermissions(actor Actor, roleID uint, codes []string) (*model.Role, error) {
+	role, err := s.roles.FindByID(roleID)
+	if err != nil {
+		return nil, err
+	}
+	if role.Name == model.AdminRoleName {
+		return nil, ErrBuiltinRole
+	}
+	perms, err := s.resolvePermissions(codes)
+	if err != nil {
+		return nil, err
+	}
+	return s.changePermissions(actor, "role.permissions_detach", role, func(roles *repository.RoleRepository) error {
+		return roles.DetachPermissions(role, perms)
+	})
+}
+
+// changePermissions applies change to role and records the permission diff
+func (s *RoleService) changePermissions(actor Actor, action string, role *model.Role, change func(roles *repository.RoleRepository) error) (*model.Role, error) {
+	before := roleSnapshot(role)
+	var updated *model.Role
+	err := s.audit.Tx(func(tx *gorm.DB) error {
+		roles := s.roles.WithTx(tx)
+		if err := change(roles); err != nil {
+			return err
+		}
+		var err error
+		if updated, err = roles.FindByID(role.ID); err != nil {
+			return err
+		}
+		return s.audit.Record(tx, actor, action, model.AuditEntityRole, role.ID, before, roleSnapshot(updated))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// AssignToUser replaces the role list of a user.
+// The last holder of the admin role cannot drop it, so the panel never locks itself out.
+func (s *RoleService) AssignToUser(actor Actor, userID uint, roleIDs []uint) (*model.User, error) {
+	user, err := s.users.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	roles, err := s.roles.FindByIDs(roleIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(roles) != len(uniqueUints(roleIDs)) {
+		return nil, errors.New("unknown role id")
+	}
+	if hasRole(user.Roles, model.AdminRoleName) && !hasRole(roles, model.AdminRoleName) {
+		admin, err := s.roles.FindByName(model.AdminRoleName)
+		if err != nil {
+			return nil, err
+		}
+		n, err := s.roles.CountUsersWithRole(admin.ID)
+		if err != nil {
+			return nil, err
+		}
+		if n <= 1 {
+			return nil, errors.New("cannot remove the last admin")
+		}
+	}
+	before := userSnapshot(user)
+	err = s.audit.Tx(func(tx *gorm.DB) error {
+		if err := s.roles.WithTx(tx).ReplaceUserRoles(user, roles); err != nil {
+			return err
+		}
+		user.Roles = roles
+		return s.audit.Record(tx, actor, "user.roles_assign", model.AuditEntityUser, user.ID, before, userSnapshot(user))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.users.FindByID(userID)
+}
+
+// roleSnapshot is the audited view of a role: its name and permission codes
+func roleSnapshot(r *model.Role) map[string]interface{} {
+	codes := make([]string, 0, len(r.Permissions))
+	for _, p := range r.Permissions {
+		codes = append(codes, p.Code)
+	}
+	sort.Strings(codes)
+	return map[string]interface{}{"name": r.Name, "permissions": codes}
+}
+
+func (s *RoleService) resolvePermissions(codes []string) ([]model.Permission, error) {
+	codes = uniqueStrings(codes)
+	perms, err := s.roles.FindPermissionsByCodes(codes)
+	if err != nil {
+		return nil, err
+	}
+	if len(perms) != len(codes) {
+		return nil, ErrUnknownPermission
+	}
+	return perms, nil
+}
+
+func hasRole(roles []model.Role, name string) bool {
+	for _, r := range roles {
+		if r.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func uniqueStrings(in []string) []string {
+	seen := make(map[string]bool, len(in))
+	out := make([]string, 0, len(in))
+	for _, v := range in {
+		v = strings.TrimSpace(v)
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		out = append(out, v)
+	}
+	return out
+}
+
+func uniqueUints(in []uint) []uint {
+	seen := make(map[uint]bool, len(in))
+	out := make([]uint, 0, len(in))
+	for _, v := range in {
+		if seen[v] {
+			continue
+		}
+		seen[v] = true
+		out = append(out, v)
+	}
+	return out
+}
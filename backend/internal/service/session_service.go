@@ -0,0 +1,132 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"metronic/internal/domain"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// Session is one login as seen by the user: all live tokens of a token family
+type Session struct {
+	ID         uint       `json:"id"`
+	UserID     uint       `json:"user_id"`
+	IP         *string    `json:"ip"`
+	UserAgent  *string    `json:"user_agent"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	Current    bool       `json:"current"`
+}
+
+// SessionService lists and revokes login sessions
+type SessionService struct {
+	tokens domain.TokenRepository
+}
+
+func NewSessionService(t domain.TokenRepository) *SessionService {
+	return &SessionService{tokens: t}
+}
+
+// List returns the active sessions of a user, newest first.
+// current may be nil (admin view); otherwise its session is flagged.
+func (s *SessionService) List(userID uint, current *domain.Token) ([]Session, int64, error) {
+	now := time.Now()
+	toks, err := s.tokens.ListActiveByUserID(userID, domain.SessionTokenKinds, now)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := s.tokens.CountActiveByUserID(userID, domain.SessionTokenKinds, now)
+	if err != nil {
+		return nil, 0, err
+	}
+	currentKey := ""
+	if current != nil {
+		currentKey = sessionKey(current)
+	}
+	items := make([]Session, 0, total)
+	index := map[string]int{}
+	// tokens come newest first, so the first token seen names the session
+	for _, t := range toks {
+		key := sessionKey(&t)
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(items)
+			items = append(items, Session{
+				ID:         t.ID,
+				UserID:     t.UserID,
+				IP:         t.IP,
+				UserAgent:  t.UserAgent,
+				CreatedAt:  t.CreatedAt,
+				LastUsedAt: t.LastUsedAt,
+				ExpiresAt:  t.ExpiresAt,
+				Current:    key == currentKey,
+			})
+			continue
+		}
+		it := &items[i]
+		if t.CreatedAt.Before(it.CreatedAt) {
+			it.CreatedAt = t.CreatedAt
+		}
+		if t.ExpiresAt.After(it.ExpiresAt) {
+			it.ExpiresAt = t.ExpiresAt
+		}
+		if t.LastUsedAt != nil && (it.LastUsedAt == nil || t.LastUsedAt.After(*it.LastUsedAt)) {
+			it.LastUsedAt = t.LastUsedAt
+			if t.IP != nil {
+				it.IP = t.IP
+			}
+			if t.UserAgent != nil {
+				it.UserAgent = t.UserAgent
+			}
+		}
+	}
+	return items, total, nil
+}
+
+// Revoke ends one session of the user; sessionID may be any token ID of the session
+func (s *SessionService) Revoke(userID, sessionID uint) error {
+	tok, err := s.tokens.FindByID(sessionID)
+	if err != nil || tok.UserID != userID || !isSessionKind(tok.Kind) {
+		return ErrSessionNotFound
+	}
+	if tok.FamilyID != "" {
+		return s.tokens.RevokeFamily(tok.FamilyID)
+	}
+	return s.tokens.RevokeByID(tok.ID)
+}
+
+// RevokeAll force-logs-out every session of the user; API keys keep working
+func (s *SessionService) RevokeAll(userID uint) error {
+	return revokeSessions(s.tokens, userID)
+}
+
+// revokeSessions revokes the access and refresh tokens of every login of the
+// user, leaving API keys alone
+func revokeSessions(tokens domain.TokenRepository, userID uint) error {
+	for _, kind := range domain.SessionTokenKinds {
+		if err := tokens.RevokeAllByUserIDAndKind(userID, kind); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sessionKey(t *domain.Token) string {
+	if t.FamilyID != "" {
+		return t.FamilyID
+	}
+	return "id:" + strconv.FormatUint(uint64(t.ID), 10)
+}
+
+func isSessionKind(kind string) bool {
+	for _, k := range domain.SessionTokenKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
@@ -1,20 +1,44 @@
 package service
 
-import "metronic/internal/repository"
+import (
+    "gorm.io/gorm"
+
+    "metronic/internal/model"
+    "metronic/internal/repository"
+)
 
 // ShopCustomerService manages customer assignments to shops
 type ShopCustomerService struct {
     custShop *repository.CustomerShopRepository
+    audit    *AuditService
 }
 
 func NewShopCustomerService(r *repository.CustomerShopRepository) *ShopCustomerService {
     return &ShopCustomerService{custShop: r}
 }
 
-func (s *ShopCustomerService) Assign(shopUUID string, customerID uint, role string, isOwner bool) error {
-    return s.custShop.Add(shopUUID, customerID, role, isOwner)
+// WithAudit records assignments on the shop's audit timeline (optional wiring style)
+func (s *ShopCustomerService) WithAudit(a *AuditService) *ShopCustomerService {
+    s.audit = a
+    return s
+}
+
+func (s *ShopCustomerService) Assign(actor Actor, shop *model.Shop, customerID uint, role string, isOwner bool) error {
+    return s.audit.Tx(func(tx *gorm.DB) error {
+        if err := s.custShop.WithTx(tx).Add(shop.UUID, customerID, role, isOwner); err != nil {
+            return err
+        }
+        after := map[string]interface{}{"customer_id": customerID, "role": role, "is_owner": isOwner}
+        return s.audit.Record(tx, actor, "shop.customer_assign", model.AuditEntityShop, shop.ID, nil, after)
+    })
 }
 
-func (s *ShopCustomerService) Remove(shopUUID string, customerID uint) error {
-    return s.custShop.Remove(shopUUID, customerID)
+func (s *ShopCustomerService) Remove(actor Actor, shop *model.Shop, customerID uint) error {
+    return s.audit.Tx(func(tx *gorm.DB) error {
+        if err := s.custShop.WithTx(tx).Remove(shop.UUID, customerID); err != nil {
+            return err
+        }
+        before := map[string]interface{}{"customer_id": customerID}
+        return s.audit.Record(tx, actor, "shop.customer_remove", model.AuditEntityShop, shop.ID, before, nil)
+    })
 }
@@ -1,35 +1,138 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"net/netip"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
 	"gorm.io/gorm"
 
+	"metronic/internal/cache"
+	"metronic/internal/hostname"
 	"metronic/internal/model"
 	"metronic/internal/repository"
+	"metronic/internal/verify"
+)
+
+var (
+	ErrShopSuspended    = errors.New("shop is already suspended")
+	ErrShopNotSuspended = errors.New("shop is not suspended")
+	ErrReasonRequired   = errors.New("reason is required")
+	ErrDomainTaken      = errors.New("domain already exists")
+	ErrDomainInvalid    = errors.New("domain is invalid")
+	ErrDomainLimit      = errors.New("shop has reached its domain limit")
+	ErrPrimaryDomain    = errors.New("the primary domain can only be changed by updating the shop")
+	ErrDomainUnverified = errors.New("the shop's primary domain is not verified")
+	ErrIPRangeInvalid   = errors.New("ip range must be an IP address or CIDR")
+	ErrIPRangeTaken     = errors.New("ip range is already in the allowlist")
+	ErrIPRangeLimit     = errors.New("shop has reached its ip range limit")
 )
 
 var uuidPattern = regexp.MustCompile(`(?i)^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
 
+// ShopStore is the part of the shop repository used by ShopService;
+// ShopRepository satisfies it
+type ShopStore interface {
+	Create(s *model.Shop) error
+	List() ([]model.Shop, error)
+	ListPaged(page, limit int) ([]model.Shop, int64, error)
+	ListPagedFiltered(page, limit int, filter string, query string, now time.Time, policy model.ExpiryPolicy) ([]model.Shop, int64, error)
+	Stats(now time.Time, policy model.ExpiryPolicy) (repository.ShopStats, error)
+	RestoreByID(id uint) error
+	ForceDeleteByID(id uint) error
+	DeleteByID(id uint) error
+	FindByID(id uint) (*model.Shop, error)
+	FindByIDWithTrashed(id uint) (*model.Shop, error)
+	FindByDomain(domain string) (*model.Shop, error)
+	FindByUUID(uuid string) (*model.Shop, error)
+	FindMany(uuids, domains []string) (map[string]*model.Shop, map[string]*model.Shop, error)
+	DomainNames(shopID uint) ([]string, error)
+	Update(s *model.Shop) error
+}
+
+// SuspensionStore keeps the suspend/resume history; ShopSuspensionRepository satisfies it
+type SuspensionStore interface {
+	Create(rec *model.ShopSuspension) error
+	ListByShopID(shopID uint) ([]model.ShopSuspension, error)
+}
+
+// IPRangeStore keeps the per-shop IP allowlists; ShopIPRangeRepository satisfies it
+type IPRangeStore interface {
+	Create(rec *model.ShopIPRange) error
+	ListByShopID(shopID uint) ([]model.ShopIPRange, error)
+	FindForShop(shopID, id uint) (*model.ShopIPRange, error)
+	Delete(id uint) error
+}
+
 // ShopService encapsulates business logic for shops
 type ShopService struct {
-	shops    *repository.ShopRepository
-	renewals *repository.ShopRenewalRepository
+	shops       ShopStore
+	renewals    *repository.ShopRenewalRepository
+	suspensions SuspensionStore
+	domains     *repository.ShopDomainRepository
+	maxDomains  int
+	hosts       hostname.Normalizer
+	verifier    *verify.Checker
+	mustVerify  bool // shops stay inactive until their primary domain is verified
+	cache       *cache.TTL[model.Shop]
+	ipRanges    IPRangeStore
+	ipCache     *cache.TTL[[]netip.Prefix]
+	audit       *AuditService
+	licenses    *LicenseService
+	policy      model.ExpiryPolicy
+	now         func() time.Time
+}
+
+// DefaultMaxDomains is how many domains (primary included) a shop may have
+const DefaultMaxDomains = 5
+
+// DefaultExpiryPolicy has no grace period and a 30-day expiring_soon window
+var DefaultExpiryPolicy = model.ExpiryPolicy{GraceDays: 0, ExpiringSoonDays: 30}
+
+func NewShopService(r ShopStore) *ShopService {
+	return &ShopService{shops: r, policy: DefaultExpiryPolicy, maxDomains: DefaultMaxDomains, now: time.Now}
+}
+
+// WithClock replaces the time source used for suspensions, expiry and
+// verification timestamps (tests)
+func (s *ShopService) WithClock(now func() time.Time) *ShopService {
+	s.now = now
+	return s
+}
+
+// shopsIn returns the shop store bound to tx. Stores other than the gorm
+// repository (test fakes) are returned as they are.
+func (s *ShopService) shopsIn(tx *gorm.DB) ShopStore {
+	if r, ok := s.shops.(*repository.ShopRepository); ok {
+		return r.WithTx(tx)
+	}
+	return s.shops
+}
+
+// WithExpiryPolicy sets the global grace and expiring_soon windows (optional wiring style)
+func (s *ShopService) WithExpiryPolicy(p model.ExpiryPolicy) *ShopService {
+	s.policy = p
+	return s
 }
 
-func NewShopService(r *repository.ShopRepository) *ShopService {
-	return &ShopService{shops: r}
+// State returns the lifecycle state of m now and when its grace period ends
+func (s *ShopService) State(m *model.Shop, now time.Time) (string, *time.Time) {
+	return s.policy.State(m, now)
 }
 
-func (s *ShopService) Create(domain string, uuid string, expiredAt *time.Time, pricePerCycle int, cycleMonths int) (*model.Shop, error) {
+func (s *ShopService) Create(actor Actor, domain string, uuid string, expiredAt *time.Time, pricePerCycle int, cycleMonths int) (*model.Shop, error) {
 	uuid = strings.TrimSpace(uuid)
+	domain, err := s.NormalizeDomain(domain)
+	if err != nil {
+		return nil, err
+	}
 	// Ensure unique domain
-	if _, err := s.shops.FindByDomain(domain); err == nil {
-		return nil, errors.New("domain already exists")
-	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+	if err := s.ensureDomainFree(domain); err != nil {
 		return nil, err
 	}
 	if uuid != "" {
@@ -48,8 +151,14 @@ func (s *ShopService) Create(domain string, uuid string, expiredAt *time.Time, p
 	if cycleMonths <= 0 {
 		cycleMonths = 12
 	}
-	m := &model.Shop{UUID: uuid, Domain: domain, Active: true, ExpiredAt: expiredAt, PricePerCycle: pricePerCycle, CycleMonths: cycleMonths}
-	if err := s.shops.Create(m); err != nil {
+	m := &model.Shop{UUID: uuid, Domain: domain, Active: !s.mustVerify, ExpiredAt: expiredAt, PricePerCycle: pricePerCycle, CycleMonths: cycleMonths}
+	err = s.tx(func(tx *gorm.DB) error {
+		if err := s.shopsIn(tx).Create(m); err != nil {
+			return err
+		}
+		return s.audit.Record(tx, actor, "shop.create", model.AuditEntityShop, m.ID, nil, snapshot(m))
+	})
+	if err != nil {
 		return nil, err
 	}
 	return m, nil
@@ -66,8 +175,8 @@ func (s *ShopService) ListPaged(page, limit int) ([]model.Shop, int64, error) {
 
 // ListPagedFiltered returns filtered page of shops plus total
 func (s *ShopService) ListPagedFiltered(page, limit int, filter string, query string) ([]model.Shop, int64, error) {
-	now := time.Now()
-	return s.shops.ListPagedFiltered(page, limit, filter, query, now)
+	now := s.now()
+	return s.shops.ListPagedFiltered(page, limit, filter, query, now, s.policy)
 }
 
 // ShopStats mirrors repository stats
@@ -78,11 +187,16 @@ type ShopStats struct {
 	NotOver1y int64 `json:"notOver1y"`
 	Expiring  int64 `json:"expiring"`
 	Trashed   int64 `json:"trashed"`
+	// lifecycle states
+	Active       int64 `json:"active"`
+	ExpiringSoon int64 `json:"expiring_soon"`
+	Grace        int64 `json:"grace"`
+	Suspended    int64 `json:"suspended"`
 }
 
 func (s *ShopService) Stats() (ShopStats, error) {
-	now := time.Now()
-	rstats, err := s.shops.Stats(now)
+	now := s.now()
+	rstats, err := s.shops.Stats(now, s.policy)
 	if err != nil {
 		return ShopStats{}, err
 	}
@@ -93,17 +207,37 @@ func (s *ShopService) Stats() (ShopStats, error) {
 		NotOver1y: rstats.NotOver1y,
 		Expiring:  rstats.Expiring,
 		Trashed:   rstats.Trashed,
+
+		Active:       rstats.Active,
+		ExpiringSoon: rstats.ExpiringSoon,
+		Grace:        rstats.Grace,
+		Suspended:    rstats.Suspended,
 	}, nil
 }
 
 // Restore brings back a soft-deleted shop
-func (s *ShopService) Restore(id uint) error {
-	return s.shops.RestoreByID(id)
+func (s *ShopService) Restore(actor Actor, id uint) error {
+	return s.tx(func(tx *gorm.DB) error {
+		if err := s.shopsIn(tx).RestoreByID(id); err != nil {
+			return err
+		}
+		return s.audit.Record(tx, actor, "shop.restore", model.AuditEntityShop, id, nil, nil)
+	})
 }
 
 // ForceDelete permanently deletes a shop
-func (s *ShopService) ForceDelete(id uint) error {
-	return s.shops.ForceDeleteByID(id)
+func (s *ShopService) ForceDelete(actor Actor, id uint) error {
+	return s.tx(func(tx *gorm.DB) error {
+		shops := s.shopsIn(tx)
+		m, err := shops.FindByIDWithTrashed(id)
+		if err != nil {
+			return err
+		}
+		if err := shops.ForceDeleteByID(id); err != nil {
+			return err
+		}
+		return s.audit.Record(tx, actor, "shop.force_delete", model.AuditEntityShop, id, snapshot(m), nil)
+	})
 }
 
 func (s *ShopService) Get(id uint) (*model.Shop, error) {
@@ -114,54 +248,253 @@ func (s *ShopService) FindByUUID(uuid string) (*model.Shop, error) {
 	return s.shops.FindByUUID(uuid)
 }
 
+// FindByDomain resolves any spelling of a host (URL, Host header with port,
+// Unicode IDN) to its shop
 func (s *ShopService) FindByDomain(domain string) (*model.Shop, error) {
+	domain, err := s.NormalizeDomain(domain)
+	if err != nil {
+		return nil, err
+	}
 	return s.shops.FindByDomain(domain)
 }
 
-func (s *ShopService) Update(id uint, domain string, expiredAt *time.Time) (*model.Shop, error) {
+// FindBatch resolves many UUIDs and raw domains at once. The domain map is
+// keyed by the strings as given; unmatched or invalid keys are absent.
+func (s *ShopService) FindBatch(uuids, domains []string) (map[string]*model.Shop, map[string]*model.Shop, error) {
+	canonical := make(map[string]string, len(domains))
+	names := make([]string, 0, len(domains))
+	for _, raw := range domains {
+		d, err := s.NormalizeDomain(raw)
+		if err != nil {
+			continue
+		}
+		canonical[raw] = d
+		names = append(names, d)
+	}
+	byUUID, found, err := s.shops.FindMany(uuids, names)
+	if err != nil {
+		return nil, nil, err
+	}
+	byDomain := make(map[string]*model.Shop, len(canonical))
+	for raw, d := range canonical {
+		if m, ok := found[d]; ok {
+			byDomain[raw] = m
+		}
+	}
+	return byUUID, byDomain, nil
+}
+
+// WithHostNormalizer sets the domain normalisation policy (optional wiring style)
+func (s *ShopService) WithHostNormalizer(n hostname.Normalizer) *ShopService {
+	s.hosts = n
+	return s
+}
+
+// NormalizeDomain returns the canonical form of a single host; wildcards are
+// only accepted as aliases
+func (s *ShopService) NormalizeDomain(raw string) (string, error) {
+	d, err := s.hosts.Normalize(raw)
+	if err != nil || strings.HasPrefix(d, "*.") {
+		return "", ErrDomainInvalid
+	}
+	return d, nil
+}
+
+func (s *ShopService) Update(actor Actor, id uint, domain string, expiredAt *time.Time) (*model.Shop, error) {
 	m, err := s.shops.FindByID(id)
 	if err != nil {
 		return nil, err
 	}
+	before := snapshot(m)
+	if domain != "" {
+		if domain, err = s.NormalizeDomain(domain); err != nil {
+			return nil, err
+		}
+	}
 	if domain != "" && domain != m.Domain {
-		if _, err := s.shops.FindByDomain(domain); err == nil {
-			return nil, errors.New("domain already exists")
-		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		if err := s.ensureDomainFree(domain); err != nil {
 			return nil, err
 		}
 		m.Domain = domain
+		m.VerifiedAt = nil
+		if s.mustVerify && m.SuspendedAt == nil {
+			// back to pending until the new domain is verified
+			m.Active = false
+		}
 	}
 	// Do not modify ExpiredAt unless explicitly provided (nil means keep current)
 	if expiredAt != nil {
 		m.ExpiredAt = expiredAt
 	}
 	// price/cycle are updated via specialized method to keep signature minimal
-	if err := s.shops.Update(m); err != nil {
+	if err := s.save(actor, "shop.update", m, before); err != nil {
 		return nil, err
 	}
 	return m, nil
 }
 
 // UpdateBilling updates price per cycle and cycle months
-func (s *ShopService) UpdateBilling(id uint, pricePerCycle, cycleMonths *int) (*model.Shop, error) {
+func (s *ShopService) UpdateBilling(actor Actor, id uint, pricePerCycle, cycleMonths *int) (*model.Shop, error) {
 	m, err := s.shops.FindByID(id)
 	if err != nil {
 		return nil, err
 	}
+	before := snapshot(m)
 	if pricePerCycle != nil && *pricePerCycle > 0 {
 		m.PricePerCycle = *pricePerCycle
 	}
 	if cycleMonths != nil && *cycleMonths > 0 {
 		m.CycleMonths = *cycleMonths
 	}
-	if err := s.shops.Update(m); err != nil {
+	if err := s.save(actor, "shop.update_billing", m, before); err != nil {
 		return nil, err
 	}
 	return m, nil
 }
 
-func (s *ShopService) Delete(id uint) error {
-	return s.shops.DeleteByID(id)
+// SetGracePeriod overrides the grace period of one shop; nil restores the global default
+func (s *ShopService) SetGracePeriod(actor Actor, id uint, days *int) (*model.Shop, error) {
+	if days != nil && *days < 0 {
+		return nil, errors.New("grace_days must be >= 0")
+	}
+	m, err := s.shops.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	before := snapshot(m)
+	m.GraceDays = days
+	if err := s.save(actor, "shop.set_grace", m, before); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (s *ShopService) Delete(actor Actor, id uint) error {
+	return s.tx(func(tx *gorm.DB) error {
+		shops := s.shopsIn(tx)
+		m, err := shops.FindByID(id)
+		if err != nil {
+			return err
+		}
+		if err := shops.DeleteByID(id); err != nil {
+			return err
+		}
+		return s.audit.Record(tx, actor, "shop.delete", model.AuditEntityShop, id, snapshot(m), nil)
+	})
+}
+
+// tx runs fn in the audit transaction, then drops cached check lookups so
+// /shops/check sees the change right away
+func (s *ShopService) tx(fn func(tx *gorm.DB) error) error {
+	err := s.audit.Tx(fn)
+	s.invalidate()
+	return err
+}
+
+func (s *ShopService) invalidate() {
+	if s.cache != nil {
+		s.cache.Flush()
+	}
+	if s.ipCache != nil {
+		s.ipCache.Flush()
+	}
+}
+
+// WithCheckCache caches the shop lookups of /shops/check for ttl, and misses
+// for negativeTTL (optional wiring style)
+func (s *ShopService) WithCheckCache(ttl, negativeTTL time.Duration) *ShopService {
+	s.cache = cache.New[model.Shop](ttl, negativeTTL, 50000)
+	s.ipCache = cache.New[[]netip.Prefix](ttl, ttl, 50000)
+	return s
+}
+
+// LookupForCheck finds the shop for a /shops/check call by UUID, or else by
+// domain, through the cache when one is configured. Misses return
+// gorm.ErrRecordNotFound.
+func (s *ShopService) LookupForCheck(shopUUID, domain string) (*model.Shop, error) {
+	var key string
+	var find func() (*model.Shop, error)
+	if shopUUID != "" {
+		key, find = "u:"+shopUUID, func() (*model.Shop, error) { return s.shops.FindByUUID(shopUUID) }
+	} else {
+		d, err := s.NormalizeDomain(domain)
+		if err != nil {
+			return nil, gorm.ErrRecordNotFound
+		}
+		key, find = "d:"+d, func() (*model.Shop, error) { return s.shops.FindByDomain(d) }
+	}
+	if s.cache == nil {
+		return find()
+	}
+	m, found, err := s.cache.Get(key, func() (model.Shop, bool, error) {
+		m, err := find()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.Shop{}, false, nil
+		}
+		if err != nil {
+			return model.Shop{}, false, err
+		}
+		return *m, true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &m, nil
+}
+
+// WithAudit records every mutation in the audit trail (optional wiring style)
+func (s *ShopService) WithAudit(a *AuditService) *ShopService {
+	s.audit = a
+	return s
+}
+
+// WithLicenses re-issues the shop's license whenever its expiry changes (optional wiring style)
+func (s *ShopService) WithLicenses(l *LicenseService) *ShopService {
+	s.licenses = l
+	return s
+}
+
+// save updates m and records the change against the before snapshot. Domain
+// and plan are part of the license, so a real change also re-issues it.
+func (s *ShopService) save(actor Actor, action string, m *model.Shop, before map[string]interface{}) error {
+	return s.tx(func(tx *gorm.DB) error {
+		if err := s.shopsIn(tx).Update(m); err != nil {
+			return err
+		}
+		after := snapshot(m)
+		if b, a := diffSnapshots(before, after); len(b) > 0 || len(a) > 0 {
+			if _, err := s.licenses.Issue(tx, m, !m.Active); err != nil {
+				return err
+			}
+		}
+		return s.audit.Record(tx, actor, action, model.AuditEntityShop, m.ID, before, after)
+	})
+}
+
+// saveRenewal updates m, stores its renewal record and re-issues the license
+// in the same transaction
+func (s *ShopService) saveRenewal(actor Actor, action string, m *model.Shop, before map[string]interface{}, rec *model.ShopRenewal) error {
+	return s.tx(func(tx *gorm.DB) error {
+		if err := s.shopsIn(tx).Update(m); err != nil {
+			return err
+		}
+		if _, err := s.licenses.Issue(tx, m, !m.Active); err != nil {
+			return err
+		}
+		if s.renewals != nil {
+			if err := s.renewals.WithTx(tx).Create(rec); err != nil {
+				return err
+			}
+		}
+		after := snapshot(m)
+		if after != nil {
+			after["note"] = rec.Note
+		}
+		return s.audit.Record(tx, actor, action, model.AuditEntityShop, m.ID, before, after)
+	})
 }
 
 // WithRenewalRepo injects the renewal repository (optional wiring style)
@@ -171,21 +504,31 @@ func (s *ShopService) WithRenewalRepo(rr *repository.ShopRenewalRepository) *Sho
 }
 
 // RevokeNow sets shop to expired immediately (even if previously unlimited)
-func (s *ShopService) RevokeNow(shopID uint) (*model.Shop, error) {
+func (s *ShopService) RevokeNow(actor Actor, shopID uint) (*model.Shop, error) {
 	m, err := s.shops.FindByID(shopID)
 	if err != nil {
 		return nil, err
 	}
-	past := time.Now().Add(-1 * time.Second)
+	before := snapshot(m)
+	past := s.now().Add(-1 * time.Second)
 	m.ExpiredAt = &past
-	if err := s.shops.Update(m); err != nil {
+	err = s.tx(func(tx *gorm.DB) error {
+		if err := s.shopsIn(tx).Update(m); err != nil {
+			return err
+		}
+		if _, err := s.licenses.Issue(tx, m, true); err != nil {
+			return err
+		}
+		return s.audit.Record(tx, actor, "shop.revoke", model.AuditEntityShop, m.ID, before, snapshot(m))
+	})
+	if err != nil {
 		return nil, err
 	}
 	return m, nil
 }
 
 // Renew extends shop expiration by given months and records history
-func (s *ShopService) Renew(shopID uint, months int, performedBy uint, note *string) (*model.Shop, *model.ShopRenewal, error) {
+func (s *ShopService) Renew(actor Actor, shopID uint, months int, note *string) (*model.Shop, *model.ShopRenewal, error) {
 	if months <= 0 {
 		return nil, nil, errors.New("months must be > 0")
 	}
@@ -193,7 +536,7 @@ func (s *ShopService) Renew(shopID uint, months int, performedBy uint, note *str
 	if err != nil {
 		return nil, nil, err
 	}
-	now := time.Now()
+	now := s.now()
 	var base time.Time
 	if m.ExpiredAt != nil && m.ExpiredAt.After(now) {
 		base = *m.ExpiredAt
@@ -202,33 +545,29 @@ func (s *ShopService) Renew(shopID uint, months int, performedBy uint, note *str
 	}
 	newExp := addMonths(base, months)
 	old := m.ExpiredAt
+	before := snapshot(m)
 	m.ExpiredAt = &newExp
-	if err := s.shops.Update(m); err != nil {
-		return nil, nil, err
-	}
 	rec := &model.ShopRenewal{
 		ShopID:       m.ID,
 		Months:       months,
 		OldExpiredAt: old,
 		NewExpiredAt: newExp,
 		Note:         note,
-		PerformedBy:  performedBy,
+		PerformedBy:  actor.UserID,
 	}
-	if s.renewals != nil {
-		if err := s.renewals.Create(rec); err != nil {
-			return nil, nil, err
-		}
+	if err := s.saveRenewal(actor, "shop.renew", m, before, rec); err != nil {
+		return nil, nil, err
 	}
 	return m, rec, nil
 }
 
 // RenewToDate sets new expiration to target date and records months approximated
-func (s *ShopService) RenewToDate(shopID uint, target time.Time, performedBy uint, note *string) (*model.Shop, *model.ShopRenewal, error) {
+func (s *ShopService) RenewToDate(actor Actor, shopID uint, target time.Time, note *string) (*model.Shop, *model.ShopRenewal, error) {
 	m, err := s.shops.FindByID(shopID)
 	if err != nil {
 		return nil, nil, err
 	}
-	now := time.Now()
+	now := s.now()
 	var base time.Time
 	if m.ExpiredAt != nil && m.ExpiredAt.After(now) {
 		base = *m.ExpiredAt
@@ -247,22 +586,18 @@ func (s *ShopService) RenewToDate(shopID uint, target time.Time, performedBy uin
 		months++
 	}
 	old := m.ExpiredAt
+	before := snapshot(m)
 	m.ExpiredAt = &target
-	if err := s.shops.Update(m); err != nil {
-		return nil, nil, err
-	}
 	rec := &model.ShopRenewal{
 		ShopID:       m.ID,
 		Months:       months,
 		OldExpiredAt: old,
 		NewExpiredAt: target,
 		Note:         note,
-		PerformedBy:  performedBy,
+		PerformedBy:  actor.UserID,
 	}
-	if s.renewals != nil {
-		if err := s.renewals.Create(rec); err != nil {
-			return nil, nil, err
-		}
+	if err := s.saveRenewal(actor, "shop.renew", m, before, rec); err != nil {
+		return nil, nil, err
 	}
 	return m, rec, nil
 }
@@ -300,28 +635,620 @@ func (s *ShopService) ListRenewals(shopID uint) ([]model.ShopRenewal, error) {
 
 // SetExpiryDate sets shop's expired_at to an exact date and records a log entry.
 // This is a manual adjustment (not necessarily a renewal by months).
-func (s *ShopService) SetExpiryDate(shopID uint, newExp time.Time, performedBy uint, note *string) (*model.Shop, *model.ShopRenewal, error) {
+func (s *ShopService) SetExpiryDate(actor Actor, shopID uint, newExp time.Time, note *string) (*model.Shop, *model.ShopRenewal, error) {
 	m, err := s.shops.FindByID(shopID)
 	if err != nil {
 		return nil, nil, err
 	}
 	old := m.ExpiredAt
+	before := snapshot(m)
 	m.ExpiredAt = &newExp
-	if err := s.shops.Update(m); err != nil {
-		return nil, nil, err
-	}
 	rec := &model.ShopRenewal{
 		ShopID:       m.ID,
 		Months:       0,
 		OldExpiredAt: old,
 		NewExpiredAt: newExp,
 		Note:         note,
-		PerformedBy:  performedBy,
+		PerformedBy:  actor.UserID,
 	}
-	if s.renewals != nil {
-		if err := s.renewals.Create(rec); err != nil {
-			return nil, nil, err
-		}
+	if err := s.saveRenewal(actor, "shop.set_expiry", m, before, rec); err != nil {
+		return nil, nil, err
 	}
 	return m, rec, nil
 }
+
+// WithSuspensionRepo injects the suspension history repository (optional wiring style)
+func (s *ShopService) WithSuspensionRepo(r SuspensionStore) *ShopService {
+	s.suspensions = r
+	return s
+}
+
+// Suspend turns a shop off without touching its expiry date. notice is shown
+// to the customer by /shops/check until the shop is resumed.
+func (s *ShopService) Suspend(actor Actor, shopID uint, reason, notice string) (*model.Shop, error) {
+	reason, notice = strings.TrimSpace(reason), strings.TrimSpace(notice)
+	if reason == "" {
+		return nil, ErrReasonRequired
+	}
+	m, err := s.shops.FindByID(shopID)
+	if err != nil {
+		return nil, err
+	}
+	if !m.Active {
+		return nil, ErrShopSuspended
+	}
+	before := snapshot(m)
+	now := s.now()
+	m.Active, m.SuspendedAt, m.Notice = false, &now, notice
+	rec := &model.ShopSuspension{ShopID: m.ID, Action: model.ShopSuspend, Reason: reason, Message: notice, PerformedBy: actor.UserID}
+	if err := s.saveSuspension(actor, "shop.suspend", m, before, rec); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Resume re-enables a suspended shop; its expiry date is whatever it was before
+func (s *ShopService) Resume(actor Actor, shopID uint, reason string) (*model.Shop, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrReasonRequired
+	}
+	m, err := s.shops.FindByID(shopID)
+	if err != nil {
+		return nil, err
+	}
+	if m.Active {
+		return nil, ErrShopNotSuspended
+	}
+	if s.mustVerify && m.VerifiedAt == nil {
+		return nil, ErrDomainUnverified
+	}
+	before := snapshot(m)
+	m.Active, m.SuspendedAt, m.Notice = true, nil, ""
+	rec := &model.ShopSuspension{ShopID: m.ID, Action: model.ShopResume, Reason: reason, PerformedBy: actor.UserID}
+	if err := s.saveSuspension(actor, "shop.resume", m, before, rec); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// saveSuspension updates m, stores the history record and re-issues the
+// license (which carries the active flag) in one transaction
+func (s *ShopService) saveSuspension(actor Actor, action string, m *model.Shop, before map[string]interface{}, rec *model.ShopSuspension) error {
+	return s.tx(func(tx *gorm.DB) error {
+		if err := s.shopsIn(tx).Update(m); err != nil {
+			return err
+		}
+		if _, err := s.licenses.Issue(tx, m, !m.Active); err != nil {
+			return err
+		}
+		if s.suspensions != nil {
+			suspensions := s.suspensions
+			if r, ok := suspensions.(*repository.ShopSuspensionRepository); ok {
+				suspensions = r.WithTx(tx)
+			}
+			if err := suspensions.Create(rec); err != nil {
+				return err
+			}
+		}
+		after := snapshot(m)
+		if after != nil {
+			after["reason"] = rec.Reason
+		}
+		return s.audit.Record(tx, actor, action, model.AuditEntityShop, m.ID, before, after)
+	})
+}
+
+// ListSuspensions returns the suspend/resume history of a shop (newest first)
+func (s *ShopService) ListSuspensions(shopID uint) ([]model.ShopSuspension, error) {
+	if s.suspensions == nil {
+		return []model.ShopSuspension{}, nil
+	}
+	return s.suspensions.ListByShopID(shopID)
+}
+
+// WithDomains enables alias management; max is the default number of domains
+// per shop, primary included (optional wiring style)
+func (s *ShopService) WithDomains(r *repository.ShopDomainRepository, max int) *ShopService {
+	s.domains = r
+	if max > 0 {
+		s.maxDomains = max
+	}
+	return s
+}
+
+// ensureDomainFree fails when any shop already owns exactly this domain.
+// Wildcards of other shops do not block it: exact entries win on lookup.
+func (s *ShopService) ensureDomainFree(domain string) error {
+	var err error
+	if s.domains != nil {
+		_, err = s.domains.FindByDomain(domain)
+	} else {
+		_, err = s.shops.FindByDomain(domain)
+	}
+	if err == nil {
+		return ErrDomainTaken
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return nil
+}
+
+// DomainLimit returns how many domains m may have
+func (s *ShopService) DomainLimit(m *model.Shop) int {
+	if m.MaxDomains != nil {
+		return *m.MaxDomains
+	}
+	return s.maxDomains
+}
+
+// SetDomainLimit overrides the domain limit of one shop; nil restores the global default
+func (s *ShopService) SetDomainLimit(actor Actor, id uint, max *int) (*model.Shop, error) {
+	if max != nil && *max < 1 {
+		return nil, errors.New("max_domains must be >= 1")
+	}
+	m, err := s.shops.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	before := snapshot(m)
+	m.MaxDomains = max
+	if err := s.save(actor, "shop.set_domain_limit", m, before); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ListDomains returns the domains of a shop, primary first
+func (s *ShopService) ListDomains(shopID uint) ([]model.ShopDomain, error) {
+	if s.domains == nil {
+		return []model.ShopDomain{}, nil
+	}
+	if _, err := s.shops.FindByID(shopID); err != nil {
+		return nil, err
+	}
+	return s.domains.ListByShopID(shopID)
+}
+
+// AddDomain attaches an alias or staging domain (optionally "*.example.com")
+// to a shop and re-issues its license
+func (s *ShopService) AddDomain(actor Actor, shopID uint, domain, kind string) (*model.ShopDomain, error) {
+	if s.domains == nil {
+		return nil, errors.New("domain management is not configured")
+	}
+	domain, err := s.hosts.Normalize(domain)
+	if err != nil || !model.ValidDomainPattern(domain) {
+		return nil, ErrDomainInvalid
+	}
+	if kind == "" {
+		kind = model.ShopDomainAlias
+	}
+	if kind != model.ShopDomainAlias && kind != model.ShopDomainStaging {
+		return nil, errors.New("kind must be alias or staging")
+	}
+	m, err := s.shops.FindByID(shopID)
+	if err != nil {
+		return nil, err
+	}
+	d := &model.ShopDomain{ShopID: m.ID, Domain: domain, Kind: kind}
+	err = s.tx(func(tx *gorm.DB) error {
+		domains := s.domains.WithTx(tx)
+		n, err := domains.CountByShopID(m.ID)
+		if err != nil {
+			return err
+		}
+		if n >= int64(s.DomainLimit(m)) {
+			return ErrDomainLimit
+		}
+		if _, err := domains.FindByDomain(domain); err == nil {
+			return ErrDomainTaken
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err := domains.Create(d); err != nil {
+			return err
+		}
+		if _, err := s.licenses.Issue(tx, m, !m.Active); err != nil {
+			return err
+		}
+		return s.audit.Record(tx, actor, "shop.domain_add", model.AuditEntityShop, m.ID, nil,
+			map[string]interface{}{"domain": d.Domain, "kind": d.Kind})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// RemoveDomain detaches a non-primary domain and re-issues the license
+func (s *ShopService) RemoveDomain(actor Actor, shopID, domainID uint) error {
+	if s.domains == nil {
+		return errors.New("domain management is not configured")
+	}
+	m, err := s.shops.FindByID(shopID)
+	if err != nil {
+		return err
+	}
+	return s.tx(func(tx *gorm.DB) error {
+		domains := s.domains.WithTx(tx)
+		d, err := domains.FindForShop(m.ID, domainID)
+		if err != nil {
+			return err
+		}
+		if d.Kind == model.ShopDomainPrimary {
+			return ErrPrimaryDomain
+		}
+		if err := domains.Delete(d.ID); err != nil {
+			return err
+		}
+		if _, err := s.licenses.Issue(tx, m, !m.Active); err != nil {
+			return err
+		}
+		return s.audit.Record(tx, actor, "shop.domain_remove", model.AuditEntityShop, m.ID,
+			map[string]interface{}{"domain": d.Domain, "kind": d.Kind}, nil)
+	})
+}
+
+// DomainChange is one entry rewritten (or to be rewritten) by RenormalizeDomains
+type DomainChange struct {
+	ID     uint   `json:"id"`
+	ShopID uint   `json:"shop_id"`
+	Kind   string `json:"kind"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+}
+
+// DomainCollision lists entries that would share one canonical domain
+type DomainCollision struct {
+	Domain  string             `json:"domain"`
+	Entries []model.ShopDomain `json:"entries"`
+}
+
+// DomainMigrationReport is the outcome of RenormalizeDomains
+type DomainMigrationReport struct {
+	Changed    []DomainChange     `json:"changed"`
+	Collisions []DomainCollision  `json:"collisions"`
+	Invalid    []model.ShopDomain `json:"invalid"`
+}
+
+// RenormalizeDomains rewrites stored domains into canonical form. Entries
+// that would collide with another entry, or that do not parse, are reported
+// and left untouched. With apply false nothing is written.
+func (s *ShopService) RenormalizeDomains(apply bool) (*DomainMigrationReport, error) {
+	if s.domains == nil {
+		return nil, errors.New("domain management is not configured")
+	}
+	all, err := s.domains.All()
+	if err != nil {
+		return nil, err
+	}
+	report := &DomainMigrationReport{}
+	target := make(map[uint]string, len(all))
+	groups := map[string][]model.ShopDomain{}
+	for _, d := range all {
+		to, err := s.hosts.Normalize(d.Domain)
+		if err != nil {
+			report.Invalid = append(report.Invalid, d)
+			to = d.Domain
+		}
+		target[d.ID] = to
+		groups[to] = append(groups[to], d)
+	}
+	for _, d := range all {
+		to := target[d.ID]
+		if len(groups[to]) > 1 || to == d.Domain {
+			continue
+		}
+		report.Changed = append(report.Changed, DomainChange{ID: d.ID, ShopID: d.ShopID, Kind: d.Kind, From: d.Domain, To: to})
+	}
+	for domain, entries := range groups {
+		if len(entries) > 1 {
+			report.Collisions = append(report.Collisions, DomainCollision{Domain: domain, Entries: entries})
+		}
+	}
+	sort.Slice(report.Collisions, func(i, j int) bool { return report.Collisions[i].Domain < report.Collisions[j].Domain })
+	if !apply {
+		return report, nil
+	}
+
+	reissue := map[uint]bool{}
+	for _, c := range report.Changed {
+		d := &model.ShopDomain{ID: c.ID, ShopID: c.ShopID, Kind: c.Kind}
+		if err := s.domains.Rename(d, c.To); err != nil {
+			return report, err
+		}
+		reissue[c.ShopID] = true
+	}
+	s.invalidate()
+	for id := range reissue {
+		m, err := s.shops.FindByIDWithTrashed(id)
+		if err != nil {
+			return report, err
+		}
+		if _, err := s.licenses.Issue(nil, m, !m.Active); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// WithVerifier enables domain ownership checks. With required set, new shops
+// start inactive and are activated once their primary domain is verified.
+func (s *ShopService) WithVerifier(c *verify.Checker, required bool) *ShopService {
+	s.verifier = c
+	s.mustVerify = required
+	return s
+}
+
+// ensureVerifyToken issues a token to an entry that somehow has none; entries
+// get one on insert and older rows from BackfillVerifyTokens at startup
+func (s *ShopService) ensureVerifyToken(d *model.ShopDomain) error {
+	if d.VerifyToken != "" {
+		return nil
+	}
+	d.VerifyToken = model.NewVerifyToken()
+	return s.domains.Save(d)
+}
+
+// VerifyDomain checks one domain entry of a shop right away
+func (s *ShopService) VerifyDomain(ctx context.Context, actor Actor, shopID, domainID uint) (*model.ShopDomain, error) {
+	if s.domains == nil || s.verifier == nil {
+		return nil, errors.New("domain verification is not configured")
+	}
+	d, err := s.domains.FindForShop(shopID, domainID)
+	if err != nil {
+		return nil, err
+	}
+	if d.VerifiedAt != nil {
+		return d, nil
+	}
+	if err := s.checkDomain(ctx, actor, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// VerifyWindow is how long after a domain is added the background job keeps
+// re-checking it; older entries are only checked on demand
+const VerifyWindow = 7 * 24 * time.Hour
+
+// VerifyPending checks up to limit unverified entries not checked within
+// minAge and returns how many were verified. Used by the background job.
+func (s *ShopService) VerifyPending(ctx context.Context, now time.Time, minAge time.Duration, limit int) (int, error) {
+	if s.domains == nil || s.verifier == nil {
+		return 0, nil
+	}
+	items, err := s.domains.ListUnverified(now.Add(-VerifyWindow), now.Add(-minAge), limit)
+	if err != nil {
+		return 0, err
+	}
+	verified := 0
+	for i := range items {
+		if err := s.checkDomain(ctx, Actor{}, &items[i]); err != nil {
+			return verified, err
+		}
+		if items[i].VerifiedAt != nil {
+			verified++
+		}
+	}
+	return verified, nil
+}
+
+// checkDomain runs the ownership check and stores its outcome. A failed check
+// is not an error; it is kept in CheckError for the admin to see.
+func (s *ShopService) checkDomain(ctx context.Context, actor Actor, d *model.ShopDomain) error {
+	if err := s.ensureVerifyToken(d); err != nil {
+		return err
+	}
+	method, checkErr := s.verifier.Check(ctx, d.Domain, d.VerifyToken)
+	now := s.now()
+	d.CheckedAt = &now
+	if checkErr != nil {
+		d.CheckError = truncate(checkErr.Error(), 255)
+		return s.domains.Save(d)
+	}
+	d.VerifiedAt, d.VerifyMethod, d.CheckError = &now, method, ""
+	return s.tx(func(tx *gorm.DB) error {
+		if err := s.domains.WithTx(tx).Save(d); err != nil {
+			return err
+		}
+		entry := map[string]interface{}{"domain": d.Domain, "kind": d.Kind, "method": method}
+		if d.Kind != model.ShopDomainPrimary {
+			return s.audit.Record(tx, actor, "shop.domain_verified", model.AuditEntityShop, d.ShopID, nil, entry)
+		}
+		shops := s.shopsIn(tx)
+		m, err := shops.FindByID(d.ShopID)
+		if err != nil {
+			return err
+		}
+		before := snapshot(m)
+		m.VerifiedAt = &now
+		if s.mustVerify && !m.Active && m.SuspendedAt == nil {
+			m.Active = true
+		}
+		if err := shops.Update(m); err != nil {
+			return err
+		}
+		if _, err := s.licenses.Issue(tx, m, !m.Active); err != nil {
+			return err
+		}
+		after := snapshot(m)
+		if after != nil {
+			after["method"] = method
+		}
+		return s.audit.Record(tx, actor, "shop.domain_verified", model.AuditEntityShop, m.ID, before, after)
+	})
+}
+
+// MaxIPRanges caps the allowlist of one shop
+const MaxIPRanges = 50
+
+// WithIPRanges enables per-shop IP allowlists for /shops/check (optional wiring style)
+func (s *ShopService) WithIPRanges(r IPRangeStore) *ShopService {
+	s.ipRanges = r
+	return s
+}
+
+// ParseIPRange accepts a CIDR or a single address and returns its canonical
+// prefix form, e.g. "203.0.113.7" -> "203.0.113.7/32", "10.1.2.3/8" -> "10.0.0.0/8"
+func ParseIPRange(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if !strings.Contains(raw, "/") {
+		a, err := netip.ParseAddr(raw)
+		if err != nil {
+			return "", ErrIPRangeInvalid
+		}
+		a = a.Unmap()
+		return netip.PrefixFrom(a, a.BitLen()).String(), nil
+	}
+	p, err := netip.ParsePrefix(raw)
+	if err != nil {
+		return "", ErrIPRangeInvalid
+	}
+	if p.Addr().Is4In6() {
+		// shorter than /96 the prefix reaches outside ::ffff:0:0/96
+		if p.Bits() < 96 {
+			return "", ErrIPRangeInvalid
+		}
+		p = netip.PrefixFrom(p.Addr().Unmap(), p.Bits()-96)
+	}
+	return p.Masked().String(), nil
+}
+
+func (s *ShopService) ListIPRanges(shopID uint) ([]model.ShopIPRange, error) {
+	if s.ipRanges == nil {
+		return []model.ShopIPRange{}, nil
+	}
+	return s.ipRanges.ListByShopID(shopID)
+}
+
+// AddIPRange adds cidr to the shop's allowlist. The first range turns the
+// allowlist on: from then on checks from other addresses get ip_not_allowed.
+func (s *ShopService) AddIPRange(actor Actor, shopID uint, cidr, note string) (*model.ShopIPRange, error) {
+	if s.ipRanges == nil {
+		return nil, errors.New("ip allowlists are not configured")
+	}
+	cidr, err := ParseIPRange(cidr)
+	if err != nil {
+		return nil, err
+	}
+	m, err := s.shops.FindByID(shopID)
+	if err != nil {
+		return nil, err
+	}
+	rec := &model.ShopIPRange{ShopID: m.ID, CIDR: cidr, Note: truncate(strings.TrimSpace(note), 255), CreatedBy: actor.UserID}
+	err = s.tx(func(tx *gorm.DB) error {
+		ranges := s.ipRangesIn(tx)
+		existing, err := ranges.ListByShopID(m.ID)
+		if err != nil {
+			return err
+		}
+		if len(existing) >= MaxIPRanges {
+			return ErrIPRangeLimit
+		}
+		for _, r := range existing {
+			if r.CIDR == cidr {
+				return ErrIPRangeTaken
+			}
+		}
+		if err := ranges.Create(rec); err != nil {
+			return err
+		}
+		return s.audit.Record(tx, actor, "shop.ip_range_add", model.AuditEntityShop, m.ID, nil,
+			map[string]interface{}{"cidr": rec.CIDR, "note": rec.Note})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// RemoveIPRange deletes one range; removing the last one lifts the restriction
+func (s *ShopService) RemoveIPRange(actor Actor, shopID, id uint) error {
+	if s.ipRanges == nil {
+		return errors.New("ip allowlists are not configured")
+	}
+	return s.tx(func(tx *gorm.DB) error {
+		ranges := s.ipRangesIn(tx)
+		rec, err := ranges.FindForShop(shopID, id)
+		if err != nil {
+			return err
+		}
+		if err := ranges.Delete(rec.ID); err != nil {
+			return err
+		}
+		return s.audit.Record(tx, actor, "shop.ip_range_remove", model.AuditEntityShop, shopID,
+			map[string]interface{}{"cidr": rec.CIDR, "note": rec.Note}, nil)
+	})
+}
+
+// ipRangesIn returns the range store bound to tx. Stores other than the gorm
+// repository (test fakes) are returned as they are.
+func (s *ShopService) ipRangesIn(tx *gorm.DB) IPRangeStore {
+	if r, ok := s.ipRanges.(*repository.ShopIPRangeRepository); ok {
+		return r.WithTx(tx)
+	}
+	return s.ipRanges
+}
+
+// allowlist returns the parsed ranges of shopID through the cache; found is
+// false when the shop has none
+func (s *ShopService) allowlist(shopID uint) ([]netip.Prefix, bool, error) {
+	load := func() ([]netip.Prefix, bool, error) {
+		items, err := s.ipRanges.ListByShopID(shopID)
+		if err != nil {
+			return nil, false, err
+		}
+		prefixes := make([]netip.Prefix, 0, len(items))
+		for _, r := range items {
+			if p, err := netip.ParsePrefix(r.CIDR); err == nil {
+				prefixes = append(prefixes, p)
+			}
+		}
+		return prefixes, len(items) > 0, nil
+	}
+	if s.ipCache != nil {
+		return s.ipCache.Get(fmt.Sprintf("%d", shopID), load)
+	}
+	return load()
+}
+
+// IPRestricted reports whether shopID has an allowlist, so its check answers
+// depend on the client address. A lookup error counts as restricted.
+func (s *ShopService) IPRestricted(shopID uint) bool {
+	if s.ipRanges == nil {
+		return false
+	}
+	_, found, err := s.allowlist(shopID)
+	return found || err != nil
+}
+
+// IPAllowed reports whether ip may check shopID: always when the shop has no
+// allowlist, otherwise only from inside one of its ranges. It fails open: when
+// the ranges cannot be loaded the call is allowed and the error returned for
+// logging, like a failing rate limit store, so a database hiccup does not
+// turn every shop's answer into a signed ip_not_allowed.
+func (s *ShopService) IPAllowed(shopID uint, ip string) (bool, error) {
+	if s.ipRanges == nil {
+		return true, nil
+	}
+	prefixes, found, err := s.allowlist(shopID)
+	if err != nil {
+		return true, err
+	}
+	if !found {
+		return true, nil
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false, nil
+	}
+	addr = addr.Unmap()
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"metronic/internal/model"
+)
+
+// memShops is an in-memory ShopStore; methods a test does not need panic
+// through the nil embedded interface
+type memShops struct {
+	ShopStore
+	byID    map[uint]*model.Shop
+	domains map[string]uint // primary, alias and wildcard entries
+}
+
+func newMemShops(shops ...model.Shop) *memShops {
+	m := &memShops{byID: map[uint]*model.Shop{}, domains: map[string]uint{}}
+	for _, sh := range shops {
+		m.add(sh)
+	}
+	return m
+}
+
+func (m *memShops) add(sh model.Shop, aliases ...string) {
+	m.byID[sh.ID] = &sh
+	m.domains[sh.Domain] = sh.ID
+	for _, a := range aliases {
+		m.domains[a] = sh.ID
+	}
+}
+
+func (m *memShops) FindByID(id uint) (*model.Shop, error) {
+	sh, ok := m.byID[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	c := *sh
+	return &c, nil
+}
+
+func (m *memShops) FindByUUID(uuid string) (*model.Shop, error) {
+	for _, sh := range m.byID {
+		if sh.UUID == uuid {
+			c := *sh
+			return &c, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// FindByDomain matches an exact entry first, then the most specific wildcard
+func (m *memShops) FindByDomain(domain string) (*model.Shop, error) {
+	for _, d := range append([]string{domain}, model.DomainWildcards(domain)...) {
+		if id, ok := m.domains[d]; ok {
+			return m.FindByID(id)
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *memShops) Update(sh *model.Shop) error {
+	c := *sh
+	m.byID[sh.ID] = &c
+	return nil
+}
+
+// memSuspensions is an in-memory SuspensionStore
+type memSuspensions struct {
+	recs []model.ShopSuspension
+	now  func() time.Time
+}
+
+func (m *memSuspensions) Create(rec *model.ShopSuspension) error {
+	rec.ID = uint(len(m.recs) + 1)
+	rec.CreatedAt = m.now()
+	m.recs = append(m.recs, *rec)
+	return nil
+}
+
+func (m *memSuspensions) ListByShopID(shopID uint) ([]model.ShopSuspension, error) {
+	out := []model.ShopSuspension{}
+	for i := len(m.recs) - 1; i >= 0; i-- {
+		if m.recs[i].ShopID == shopID {
+			out = append(out, m.recs[i])
+		}
+	}
+	return out, nil
+}
+
+func TestSuspendAndResumeKeepHistory(t *testing.T) {
+	clk := &clock{t: time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)}
+	exp := clk.t.AddDate(1, 0, 0)
+	shops := newMemShops(model.Shop{ID: 7, Domain: "shop.example", Active: true, ExpiredAt: &exp})
+	hist := &memSuspensions{now: clk.now}
+	svc := NewShopService(shops).WithSuspensionRepo(hist).WithClock(clk.now)
+	admin := Actor{UserID: 3}
+
+	if _, err := svc.Suspend(admin, 7, "  ", "notice"); !errors.Is(err, ErrReasonRequired) {
+		t.Fatalf("blank reason: got %v", err)
+	}
+	if _, err := svc.Resume(admin, 7, "back"); !errors.Is(err, ErrShopNotSuspended) {
+		t.Fatalf("resume of an active shop: got %v", err)
+	}
+
+	m, err := svc.Suspend(admin, 7, " unpaid ", " Please contact billing ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Active || m.SuspendedAt == nil || !m.SuspendedAt.Equal(clk.t) || m.Notice != "Please contact billing" {
+		t.Fatalf("suspended shop: active=%v suspended_at=%v notice=%q", m.Active, m.SuspendedAt, m.Notice)
+	}
+	if st, _ := svc.State(shops.byID[7], clk.t); st != model.ShopStateSuspended {
+		t.Fatalf("stored state %q, want suspended", st)
+	}
+	if !shops.byID[7].ExpiredAt.Equal(exp) {
+		t.Fatalf("suspend changed the expiry to %v", shops.byID[7].ExpiredAt)
+	}
+
+	clk.advance(time.Hour)
+	if _, err := svc.Suspend(admin, 7, "again", ""); !errors.Is(err, ErrShopSuspended) {
+		t.Fatalf("second suspend: got %v", err)
+	}
+
+	clk.advance(time.Hour)
+	m, err = svc.Resume(Actor{UserID: 4}, 7, "paid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.Active || m.SuspendedAt != nil || m.Notice != "" {
+		t.Fatalf("resumed shop: active=%v suspended_at=%v notice=%q", m.Active, m.SuspendedAt, m.Notice)
+	}
+	if _, err := svc.Resume(admin, 7, "paid"); !errors.Is(err, ErrShopNotSuspended) {
+		t.Fatalf("second resume: got %v", err)
+	}
+
+	got, err := svc.ListSuspensions(7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []model.ShopSuspension{
+		{ID: 2, ShopID: 7, Action: model.ShopResume, Reason: "paid", PerformedBy: 4, CreatedAt: clk.t},
+		{ID: 1, ShopID: 7, Action: model.ShopSuspend, Reason: "unpaid", Message: "Please contact billing", PerformedBy: 3, CreatedAt: clk.t.Add(-2 * time.Hour)},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("history %+v, want %d records", got, len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("history[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestResumeRequiresVerifiedDomain(t *testing.T) {
+	now := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	shops := newMemShops(model.Shop{ID: 1, Domain: "shop.example", Active: false, SuspendedAt: &now})
+	hist := &memSuspensions{now: func() time.Time { return now }}
+	svc := NewShopService(shops).WithSuspensionRepo(hist).WithVerifier(nil, true)
+
+	if _, err := svc.Resume(Actor{UserID: 1}, 1, "paid"); !errors.Is(err, ErrDomainUnverified) {
+		t.Fatalf("got %v, want ErrDomainUnverified", err)
+	}
+	if len(hist.recs) != 0 || shops.byID[1].Active {
+		t.Fatalf("refused resume still changed the shop or its history")
+	}
+}
@@ -2,6 +2,7 @@ package service
 
 import (
     "errors"
+    "sort"
 
     "gorm.io/gorm"
 
@@ -13,6 +14,8 @@ import (
 // UserService encapsulates business logic for users
 type UserService struct {
     users *repository.UserRepository
+    guard *LoginGuard
+    audit *AuditService
 }
 
 var ErrDeleteSelf = errors.New("cannot delete current user")
@@ -21,8 +24,20 @@ func NewUserService(u *repository.UserRepository) *UserService {
     return &UserService{users: u}
 }
 
+// WithLoginGuard injects the login guard used by Unlock
+func (s *UserService) WithLoginGuard(g *LoginGuard) *UserService {
+    s.guard = g
+    return s
+}
+
+// WithAudit records user changes in the audit trail (optional wiring style)
+func (s *UserService) WithAudit(a *AuditService) *UserService {
+    s.audit = a
+    return s
+}
+
 // Create creates a new user with hashed password
-func (s *UserService) Create(name, email, password string) (*model.User, error) {
+func (s *UserService) Create(actor Actor, name, email, password string) (*model.User, error) {
     if _, err := s.users.FindByEmail(email); err == nil {
         return nil, errors.New("email already exists")
     } else if !errors.Is(err, gorm.ErrRecordNotFound) {
@@ -33,7 +48,13 @@ func (s *UserService) Create(name, email, password string) (*model.User, error)
         return nil, err
     }
     u := &model.User{Name: name, Email: email, Password: hash}
-    if err := s.users.Create(u); err != nil {
+    err = s.audit.Tx(func(tx *gorm.DB) error {
+        if err := s.users.WithTx(tx).Create(u); err != nil {
+            return err
+        }
+        return s.audit.Record(tx, actor, "user.create", model.AuditEntityUser, u.ID, nil, userSnapshot(u))
+    })
+    if err != nil {
         return nil, err
     }
     return u, nil
@@ -50,11 +71,12 @@ func (s *UserService) Get(id uint) (*model.User, error) {
 }
 
 // Update updates user info; if password non-empty, re-hash
-func (s *UserService) Update(id uint, name, email, password string) (*model.User, error) {
+func (s *UserService) Update(actor Actor, id uint, name, email, password string) (*model.User, error) {
     u, err := s.users.FindByID(id)
     if err != nil {
         return nil, err
     }
+    before := userSnapshot(u)
     // Ensure email uniqueness if changed
     if email != "" && email != u.Email {
         if _, err := s.users.FindByEmail(email); err == nil {
@@ -74,17 +96,60 @@ func (s *UserService) Update(id uint, name, email, password string) (*model.User
         }
         u.Password = hash
     }
-    if err := s.users.Update(u); err != nil {
+    err = s.audit.Tx(func(tx *gorm.DB) error {
+        if err := s.users.WithTx(tx).Update(u); err != nil {
+            return err
+        }
+        after := userSnapshot(u)
+        if password != "" {
+            // the hash itself never enters the trail
+            after["password"] = "changed"
+        }
+        return s.audit.Record(tx, actor, "user.update", model.AuditEntityUser, u.ID, before, after)
+    })
+    if err != nil {
         return nil, err
     }
     return u, nil
 }
 
 // Delete removes user by ID
-func (s *UserService) Delete(id, currentID uint) error {
-	if id == currentID {
-		return ErrDeleteSelf
-	}
-	return s.users.DeleteByID(id)
+func (s *UserService) Delete(actor Actor, id uint) error {
+    if id == actor.UserID {
+        return ErrDeleteSelf
+    }
+    return s.audit.Tx(func(tx *gorm.DB) error {
+        users := s.users.WithTx(tx)
+        u, err := users.FindByID(id)
+        if err != nil {
+            return err
+        }
+        if err := users.DeleteByID(id); err != nil {
+            return err
+        }
+        return s.audit.Record(tx, actor, "user.delete", model.AuditEntityUser, id, userSnapshot(u), nil)
+    })
 }
 
+// Unlock clears a login lockout on the user's account
+func (s *UserService) Unlock(actor Actor, id uint) error {
+    if s.guard == nil {
+        return errors.New("login guard not configured")
+    }
+    return s.audit.Tx(func(tx *gorm.DB) error {
+        if err := s.guard.withTx(tx).Unlock(id); err != nil {
+            return err
+        }
+        return s.audit.Record(tx, actor, "user.unlock", model.AuditEntityUser, id, nil, nil)
+    })
+}
+
+// userSnapshot is the audited view of a user; credentials are left out
+func userSnapshot(u *model.User) map[string]interface{} {
+    roles := make([]string, 0, len(u.Roles))
+    for _, r := range u.Roles {
+        roles = append(roles, r.Name)
+    }
+    sort.Strings(roles)
+    return map[string]interface{}{"name": u.Name, "email": u.Email, "roles": roles}
+}
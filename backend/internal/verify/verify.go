@@ -0,0 +1,132 @@
+// Package verify checks that whoever registered a domain controls it, by
+// looking for a token in a DNS TXT record or a well-known file on the site.
+package verify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// WellKnownPath is where the HTTP method expects the bare token
+	WellKnownPath = "/.well-known/subly-verification.txt"
+	// TXTPrefix prefixes the token in the TXT record of RecordName(domain)
+	TXTPrefix = "subly-verification="
+
+	MethodDNS  = "dns"
+	MethodHTTP = "http"
+)
+
+// ErrNotFound means neither method found the token
+var ErrNotFound = errors.New("verification token not found")
+
+// Resolver looks up TXT records; *net.Resolver satisfies it
+type Resolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// HTTPClient sends requests; *http.Client satisfies it
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Checker verifies domain ownership. Resolver and Client are swappable so
+// tests can run against local stand-ins.
+type Checker struct {
+	Resolver Resolver
+	Client   HTTPClient
+	// Scheme of the well-known URL, "https" unless a test overrides it
+	Scheme string
+}
+
+// NewChecker uses the system resolver and an HTTP client with a short timeout
+// that only follows redirects within the domain being checked
+func NewChecker() *Checker {
+	return &Checker{
+		Resolver: net.DefaultResolver,
+		Client:   &http.Client{Timeout: 10 * time.Second, CheckRedirect: sameHostOnly},
+		Scheme:   "https",
+	}
+}
+
+// sameHostOnly lets http -> https and moved paths through but refuses a
+// redirect to another host, whose owner would otherwise answer for the domain
+func sameHostOnly(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	if !strings.EqualFold(req.URL.Hostname(), via[0].URL.Hostname()) {
+		return fmt.Errorf("redirect to another host %s", req.URL.Hostname())
+	}
+	return nil
+}
+
+// RecordName is the DNS name holding the TXT record for domain. Wildcard
+// entries are proven on their base domain.
+func RecordName(domain string) string {
+	return "_subly-verification." + strings.TrimPrefix(domain, "*.")
+}
+
+// Check looks for token via DNS first, then HTTP, and returns the method that
+// found it. The error explains why both failed.
+func (c *Checker) Check(ctx context.Context, domain, token string) (string, error) {
+	if token == "" {
+		return "", errors.New("no verification token issued")
+	}
+	dnsErr := c.checkDNS(ctx, domain, token)
+	if dnsErr == nil {
+		return MethodDNS, nil
+	}
+	httpErr := c.checkHTTP(ctx, domain, token)
+	if httpErr == nil {
+		return MethodHTTP, nil
+	}
+	return "", fmt.Errorf("%w (dns: %v; http: %v)", ErrNotFound, dnsErr, httpErr)
+}
+
+func (c *Checker) checkDNS(ctx context.Context, domain, token string) error {
+	records, err := c.Resolver.LookupTXT(ctx, RecordName(domain))
+	if err != nil {
+		return err
+	}
+	for _, r := range records {
+		if strings.TrimSpace(r) == TXTPrefix+token {
+			return nil
+		}
+	}
+	return errors.New("no matching TXT record")
+}
+
+func (c *Checker) checkHTTP(ctx context.Context, domain, token string) error {
+	scheme := c.Scheme
+	if scheme == "" {
+		scheme = "https"
+	}
+	host := strings.TrimPrefix(domain, "*.")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, scheme+"://"+host+WellKnownPath, nil)
+	if err != nil {
+		return err
+	}
+	res, err := c.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d", res.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1024))
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(string(body)) != token {
+		return errors.New("file content does not match")
+	}
+	return nil
+}
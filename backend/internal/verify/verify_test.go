@@ -0,0 +1,85 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type fakeResolver map[string][]string
+
+func (f fakeResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	if r, ok := f[name]; ok {
+		return r, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+// siteClient sends every request to srv, whatever host the URL names
+func siteClient(srv *httptest.Server) HTTPClient {
+	c := srv.Client()
+	c.Transport = &http.Transport{DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, srv.Listener.Addr().String())
+	}}
+	return c
+}
+
+func TestCheckDNS(t *testing.T) {
+	c := &Checker{Resolver: fakeResolver{
+		"_subly-verification.shop.vn": {"v=spf1 -all", "subly-verification=tok"},
+	}, Client: http.DefaultClient}
+	method, err := c.Check(context.Background(), "*.shop.vn", "tok")
+	if err != nil || method != MethodDNS {
+		t.Fatalf("got %q, %v", method, err)
+	}
+}
+
+func TestCheckHTTPFallback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != WellKnownPath || r.Host != "shop.vn" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("tok\n"))
+	}))
+	defer srv.Close()
+	c := &Checker{Resolver: fakeResolver{}, Client: siteClient(srv), Scheme: "http"}
+	method, err := c.Check(context.Background(), "shop.vn", "tok")
+	if err != nil || method != MethodHTTP {
+		t.Fatalf("got %q, %v", method, err)
+	}
+	if _, err := c.Check(context.Background(), "shop.vn", "other"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestCheckHTTPFollowsRedirectsOnlyWithinHost(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Host == "shop.vn" && r.URL.Path == WellKnownPath:
+			http.Redirect(w, r, "http://shop.vn/moved.txt", http.StatusFound)
+		case r.Host == "shop.vn" && r.URL.Path == "/moved.txt":
+			_, _ = w.Write([]byte("tok"))
+		case r.Host == "other.vn" && r.URL.Path == WellKnownPath:
+			http.Redirect(w, r, "http://attacker.example"+WellKnownPath, http.StatusFound)
+		case r.Host == "attacker.example":
+			_, _ = w.Write([]byte("tok"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	c := NewChecker()
+	c.Resolver, c.Scheme = fakeResolver{}, "http"
+	c.Client.(*http.Client).Transport = siteClient(srv).(*http.Client).Transport
+
+	if method, err := c.Check(context.Background(), "shop.vn", "tok"); err != nil || method != MethodHTTP {
+		t.Fatalf("same-host redirect: got %q, %v", method, err)
+	}
+	if _, err := c.Check(context.Background(), "other.vn", "tok"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("cross-host redirect: expected ErrNotFound, got %v", err)
+	}
+}
@@ -1,24 +1,35 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"crypto/rand"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 
 	"metronic/internal/config"
 	"metronic/internal/database"
 	"metronic/internal/domain"
 	"metronic/internal/handler"
+	"metronic/internal/hostname"
+	"metronic/internal/mailer"
+	"metronic/internal/model"
+	"metronic/internal/ratelimit"
 	"metronic/internal/repository"
 	route "metronic/internal/route"
+	"metronic/internal/security"
 	"metronic/internal/service"
+	"metronic/internal/verify"
 )
 
 func main() {
@@ -29,7 +40,7 @@ func main() {
 		log.Fatalf("db connect: %v", err)
 	}
 
-	// Seed default roles and demo users
+	// Seed built-in permissions and the admin role
 	if err := database.Seed(db); err != nil {
 		log.Fatalf("db seed: %v", err)
 	}
@@ -38,23 +49,104 @@ func main() {
 	tokenRepo := repository.NewTokenRepository(db)
 	shopRepo := repository.NewShopRepository(db)
 	shopRenewalRepo := repository.NewShopRenewalRepository(db)
+	shopDomainRepo := repository.NewShopDomainRepository(db)
+	if n, err := shopDomainRepo.BackfillPrimary(); err != nil {
+		log.Fatalf("shop domains backfill: %v", err)
+	} else if n > 0 {
+		log.Printf("shop domains: added primary entry for %d shops", n)
+	}
+	if n, err := shopDomainRepo.BackfillVerifyTokens(); err != nil {
+		log.Fatalf("shop domains verify tokens: %v", err)
+	} else if n > 0 {
+		log.Printf("shop domains: issued verify tokens for %d entries", n)
+	}
 	customerRepo := repository.NewCustomerRepository(db)
+	roleRepo := repository.NewRoleRepository(db)
+	auditService := service.NewAuditService(repository.NewAuditRepository(db))
 
-	authService := service.NewAuthService(userRepo, tokenRepo)
+	mfaService := service.NewMFAService(userRepo, repository.NewRecoveryCodeRepository(db), cfg.MFAIssuer)
+	guardCfg := service.DefaultLoginGuardConfig()
+	guardCfg.BackoffAfter = cfg.LoginBackoffAfter
+	guardCfg.LockAfter = cfg.LoginLockAfter
+	guardCfg.LockDuration = cfg.LoginLockDuration
+	loginGuard := service.NewLoginGuard(repository.NewLoginAttemptRepository(db), userRepo, guardCfg)
+	authService := service.NewAuthService(userRepo, tokenRepo).WithMFA(mfaService).WithLoginGuard(loginGuard).
+		WithRegistration(cfg.RegistrationEnabled).WithRoles(roleRepo)
 	authHandler := handler.NewAuthHandler(authService)
-	userService := service.NewUserService(userRepo)
+	userService := service.NewUserService(userRepo).WithLoginGuard(loginGuard).WithAudit(auditService)
+	mail, err := newMailer(cfg)
+	if err != nil {
+		log.Fatalf("mailer: %v", err)
+	}
+	passwordResetHandler := handler.NewPasswordResetHandler(
+		service.NewPasswordResetService(userRepo, tokenRepo, mail, cfg.PasswordResetURL).WithLoginGuard(loginGuard))
+	invitationHandler := handler.NewInvitationHandler(service.NewInvitationService(
+		repository.NewInvitationRepository(db), userRepo, roleRepo, mail, cfg.InviteURL, appSecret(cfg)))
 	userHandler := handler.NewUserHandler(userService)
-	shopService := service.NewShopService(shopRepo).WithRenewalRepo(shopRenewalRepo)
-	shopAPILogRepo := repository.NewShopAPILogRepository(db)
-	shopHandler := handler.NewShopHandler(shopService).WithAPILogRepo(shopAPILogRepo).WithSlackWebhook(cfg.SlackWebhook)
+	signer, err := keyRing(cfg)
+	if err != nil {
+		log.Fatalf("signing keys: %v", err)
+	}
+	expiryPolicy := model.ExpiryPolicy{GraceDays: cfg.ShopGraceDays, ExpiringSoonDays: cfg.ShopExpiringSoonDays}
+	licenseService := service.NewLicenseService(repository.NewShopLicenseRepository(db), shopRepo, signer, expiryPolicy)
+	shopService := service.NewShopService(shopRepo).WithRenewalRepo(shopRenewalRepo).WithAudit(auditService).
+		WithSuspensionRepo(repository.NewShopSuspensionRepository(db)).
+		WithDomains(shopDomainRepo, cfg.ShopMaxDomains).
+		WithIPRanges(repository.NewShopIPRangeRepository(db)).
+		WithLicenses(licenseService).
+		WithExpiryPolicy(expiryPolicy).
+		WithHostNormalizer(hostname.Normalizer{StripWWW: cfg.DomainStripWWW}).
+		WithVerifier(verify.NewChecker(), cfg.RequireDomainVerification).
+		WithCheckCache(cfg.CheckCacheTTL, cfg.CheckNegativeTTL)
+	// One-off maintenance: `backend normalize-domains [--apply]`
+	if len(os.Args) > 1 && os.Args[1] == "normalize-domains" {
+		os.Exit(normalizeDomains(shopService, len(os.Args) > 2 && os.Args[2] == "--apply"))
+	}
+	notificationService := service.NewNotificationService(repository.NewNotificationRepository(db), mail).WithAudit(auditService)
+	if err := notificationService.SeedLegacySlack(cfg.SlackWebhook); err != nil {
+		log.Printf("notification channels: %v", err)
+	}
+	appLoc := parseLocationOrFixed(cfg.Timezone)
+	shopAPILogRepo := repository.NewShopAPILogRepository(db).WithLocation(appLoc)
+	apiLogRetention := service.NewAPILogRetention(shopAPILogRepo, cfg.APILogRetentionDays, cfg.APILogPruneChunk, appLoc)
+	apiLogWriter := service.NewAPILogWriter(shopAPILogRepo, service.APILogWriterConfig{
+		Buffer:        cfg.APILogBuffer,
+		Workers:       cfg.APILogWorkers,
+		BatchSize:     cfg.APILogBatchSize,
+		FlushInterval: cfg.APILogFlushInterval,
+		Policy:        cfg.APILogPolicy,
+	})
+	abuseDetector := service.NewAbuseDetector(shopAPILogRepo, repository.NewAbuseFindingRepository(db), shopService,
+		service.AbuseConfig{
+			Window:        cfg.AbuseWindow,
+			MaxIPs:        cfg.AbuseMaxIPs,
+			AutoSuspend:   cfg.AbuseAutoSuspend,
+			IgnoreDomains: cfg.AbuseIgnore,
+		}).WithAudit(auditService).WithNotifications(notificationService)
+	shopHandler := handler.NewShopHandler(shopService).WithAPILogRepo(shopAPILogRepo).WithNotifications(notificationService).
+		WithSigner(signer).WithCheckMaxAge(cfg.CheckMaxAge).WithAPILogWriter(apiLogWriter).WithRetention(apiLogRetention).
+		WithAbuseDetector(abuseDetector).
+		WithCheckRateLimit(rateLimitStore(cfg, db),
+			ratelimit.Limit{PerMin: cfg.CheckRateLimitIP}, ratelimit.Limit{PerMin: cfg.CheckRateLimitShop})
 	// Shop-Customer membership wiring
 	custShopRepo := repository.NewCustomerShopRepository(db)
-	shopCustSvc := service.NewShopCustomerService(custShopRepo)
+	shopCustSvc := service.NewShopCustomerService(custShopRepo).WithAudit(auditService)
 	shopCustHandler := handler.NewShopCustomerHandler(shopCustSvc, shopRepo)
-	customerService := service.NewCustomerService(customerRepo)
+	customerService := service.NewCustomerService(customerRepo).WithAudit(auditService)
 	customerHandler := handler.NewCustomerHandler(customerService)
+	roleService := service.NewRoleService(roleRepo, userRepo).WithAudit(auditService)
+	roleHandler := handler.NewRoleHandler(roleService)
+	sessionHandler := handler.NewSessionHandler(service.NewSessionService(tokenRepo))
+	apiKeyHandler := handler.NewAPIKeyHandler(service.NewAPIKeyService(tokenRepo, roleRepo))
+	mfaHandler := handler.NewMFAHandler(mfaService)
+	auditHandler := handler.NewAuditHandler(auditService)
+	licenseHandler := handler.NewLicenseHandler(licenseService)
+	notificationHandler := handler.NewNotificationHandler(notificationService)
 
 	r := gin.Default()
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("TRUSTED_PROXIES: %v", err)
+	}
 	r.Use(cors.New(cors.Config{
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
 		AllowHeaders:     []string{"*"},
@@ -67,12 +159,48 @@ func main() {
 		c.JSON(200, gin.H{"message": "pong"})
 	})
 
-	setUpRouter(r, authHandler, userHandler, shopHandler, customerHandler, tokenRepo, shopCustHandler)
+	setUpRouter(r, cfg.RateLimit, authHandler, userHandler, shopHandler, customerHandler, roleHandler, sessionHandler, apiKeyHandler, mfaHandler, passwordResetHandler, invitationHandler, auditHandler, licenseHandler, notificationHandler, tokenRepo, roleRepo, shopCustHandler)
 
-	// Schedule daily Slack notification at 08:00 local time if webhook is configured
-	if cfg.SlackWebhook != "" {
-		go scheduleDailyAt(cfg.Timezone, 8, 0, func(now time.Time) {
-			sendDailySlack(shopService, cfg.SlackWebhook, now)
+	// Daily expiry report at 08:00 local time to the subscribed channels
+	go scheduleDailyAt(cfg.Timezone, 8, 0, func(now time.Time) {
+		sendDailyReport(shopService, notificationService, now)
+	})
+
+	// Roll up and prune old API logs nightly at 03:00
+	if cfg.APILogRetentionDays > 0 {
+		go scheduleDailyAt(cfg.Timezone, 3, 0, func(time.Time) {
+			rep, err := apiLogRetention.Run(context.Background())
+			if err != nil {
+				log.Printf("api log retention: %v", err)
+			}
+			log.Printf("api log retention: %d days rolled up (%d rows), %d rows deleted before %s",
+				rep.Days, rep.RolledUp, rep.Deleted, rep.Cutoff.Format("2006-01-02"))
+		})
+	}
+
+	// Re-check unverified domains in the background
+	if cfg.DomainVerifyInterval > 0 {
+		go scheduleEvery(cfg.DomainVerifyInterval, func(now time.Time) {
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.DomainVerifyInterval)
+			defer cancel()
+			if n, err := shopService.VerifyPending(ctx, now, cfg.DomainVerifyInterval, 100); err != nil {
+				log.Printf("domain verification: %v", err)
+			} else if n > 0 {
+				log.Printf("domain verification: %d domains verified", n)
+			}
+		})
+	}
+
+	// Scan check traffic for licence abuse
+	if cfg.AbuseScanInterval > 0 {
+		go scheduleEvery(cfg.AbuseScanInterval, func(time.Time) {
+			rep, err := abuseDetector.Run(context.Background())
+			if err != nil {
+				log.Printf("abuse scan: %v", err)
+			}
+			if len(rep.New) > 0 {
+				log.Printf("abuse scan: %d new findings, %d shops suspended", len(rep.New), rep.Suspended)
+			}
 		})
 	}
 
@@ -80,19 +208,144 @@ func main() {
 	if !strings.Contains(addr, ":") {
 		addr = ":" + addr
 	}
-	fmt.Printf("Server running at %s\n", addr)
-	if err := r.Run(addr); err != nil {
-		log.Fatalf("server: %v", err)
+	srv := &http.Server{Addr: addr, Handler: r}
+	go func() {
+		fmt.Printf("Server running at %s\n", addr)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("server: %v", err)
+		}
+	}()
+
+	// On SIGINT/SIGTERM finish in-flight requests, then flush queued API logs
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("server shutdown: %v", err)
 	}
+	if err := apiLogWriter.Close(ctx); err != nil {
+		log.Printf("api log writer: %v", err)
+	}
+	st := apiLogWriter.Stats()
+	log.Printf("api logs: %d written, %d dropped, %d failed", st.Written, st.Dropped, st.Failed)
 }
 
-func setUpRouter(r *gin.Engine, authH *handler.AuthHandler, userH *handler.UserHandler, shopH *handler.ShopHandler, custH *handler.CustomerHandler, tokens domain.TokenRepository, shopCustH *handler.ShopCustomerHandler) {
+func setUpRouter(r *gin.Engine, rateLimit int, authH *handler.AuthHandler, userH *handler.UserHandler, shopH *handler.ShopHandler, custH *handler.CustomerHandler, roleH *handler.RoleHandler, sessionH *handler.SessionHandler, apiKeyH *handler.APIKeyHandler, mfaH *handler.MFAHandler, pwH *handler.PasswordResetHandler, inviteH *handler.InvitationHandler, auditH *handler.AuditHandler, licenseH *handler.LicenseHandler, notifyH *handler.NotificationHandler, tokens domain.TokenRepository, perms domain.PermissionRepository, shopCustH *handler.ShopCustomerHandler) {
 	api := r.Group("/api")
-	route.AuthRouter(api, authH, tokens)
-	route.UsersRouter(api, userH, tokens)
+	route.AuthRouter(api, authH, tokens, rateLimit)
+	route.PasswordResetRouter(api, pwH, rateLimit)
+	route.UsersRouter(api, userH, tokens, perms)
 	// Pass membership handler via shops router
-	route.ShopsRouter(api, shopH, tokens, shopCustH)
-	route.CustomersRouter(api, custH, tokens)
+	route.ShopsRouter(api, shopH, tokens, perms, shopCustH)
+	route.CustomersRouter(api, custH, tokens, perms)
+	route.RolesRouter(api, roleH, tokens, perms)
+	route.SessionsRouter(api, sessionH, tokens, perms)
+	route.APIKeysRouter(api, apiKeyH, tokens)
+	route.MFARouter(api, mfaH, tokens, perms)
+	route.InvitationsRouter(api, inviteH, tokens, perms, rateLimit)
+	route.AuditRouter(api, auditH, tokens, perms)
+	route.LicensesRouter(api, licenseH, tokens, perms)
+	route.NotificationsRouter(api, notifyH, tokens, perms)
+}
+
+// appSecret returns APP_SECRET, or a per-process random key so signed links
+// still work (until the next restart) in setups that did not configure one
+func appSecret(cfg *config.Config) []byte {
+	if cfg.AppSecret != "" {
+		return []byte(cfg.AppSecret)
+	}
+	log.Printf("APP_SECRET is not set; invitation links will stop working after a restart")
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		log.Fatalf("app secret: %v", err)
+	}
+	return b
+}
+
+// keyRing loads SIGNING_KEYS, or an ephemeral key so signing still works
+// (until the next restart) in setups that did not configure one
+func keyRing(cfg *config.Config) (*security.KeyRing, error) {
+	if cfg.SigningKeys != "" {
+		return security.ParseKeyRing(cfg.SigningKeys, cfg.SigningActiveKID)
+	}
+	log.Printf("SIGNING_KEYS is not set; check responses are signed with a temporary key")
+	return security.NewEphemeralKeyRing()
+}
+
+// normalizeDomains prints what RenormalizeDomains changes (or would change)
+// and returns the process exit code: 1 when collisions need manual cleanup.
+func normalizeDomains(svc *service.ShopService, apply bool) int {
+	report, err := svc.RenormalizeDomains(apply)
+	if err != nil {
+		log.Printf("normalize-domains: %v", err)
+		return 2
+	}
+	verb := "would rename"
+	if apply {
+		verb = "renamed"
+	}
+	for _, c := range report.Changed {
+		fmt.Printf("%s shop %d %s: %q -> %q\n", verb, c.ShopID, c.Kind, c.From, c.To)
+	}
+	for _, d := range report.Invalid {
+		fmt.Printf("invalid: shop %d %s %q\n", d.ShopID, d.Kind, d.Domain)
+	}
+	for _, c := range report.Collisions {
+		fmt.Printf("collision on %q:\n", c.Domain)
+		for _, d := range c.Entries {
+			fmt.Printf("  shop %d %s %q\n", d.ShopID, d.Kind, d.Domain)
+		}
+	}
+	fmt.Printf("%d %s, %d invalid, %d collisions\n", len(report.Changed), verb, len(report.Invalid), len(report.Collisions))
+	if !apply && len(report.Changed) > 0 {
+		fmt.Println("run again with --apply to write the changes")
+	}
+	if len(report.Collisions) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// newMailer builds the outgoing mailer selected by MAIL_DRIVER
+func newMailer(cfg *config.Config) (mailer.Mailer, error) {
+	switch cfg.MailDriver {
+	case "smtp":
+		if cfg.SMTPHost == "" {
+			return nil, fmt.Errorf("SMTP_HOST is required for MAIL_DRIVER=smtp")
+		}
+		return mailer.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.MailFrom), nil
+	case "log", "":
+		if cfg.MailLogFile == "" {
+			return mailer.NewLogMailer(os.Stdout, cfg.MailFrom), nil
+		}
+		f, err := os.OpenFile(cfg.MailLogFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, err
+		}
+		return mailer.NewLogMailer(f, cfg.MailFrom), nil
+	default:
+		return nil, fmt.Errorf("unknown MAIL_DRIVER %q", cfg.MailDriver)
+	}
+}
+
+// rateLimitStore picks where rate limit buckets live: "db" shares them
+// between replicas, anything else keeps them in this process
+func rateLimitStore(cfg *config.Config, db *gorm.DB) ratelimit.Store {
+	if cfg.RateLimitStore == "db" {
+		return repository.NewRateLimitRepository(db)
+	}
+	return ratelimit.NewMemory()
+}
+
+// scheduleEvery runs f every interval, starting after the first interval
+func scheduleEvery(interval time.Duration, f func(now time.Time)) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for now := range t.C {
+		f(now)
+	}
 }
 
 // scheduleDailyAt schedules f to run once per day at hour:min in the given timezone
@@ -111,73 +364,22 @@ func scheduleDailyAt(tz string, hour, min int, f func(now time.Time)) {
 	}
 }
 
-type slackPayload struct {
-	Text string `json:"text"`
-}
-
-func sendDailySlack(shopSvc *service.ShopService, webhook string, now time.Time) {
-	// Collect shops within ±30 days window (notOver1y in repo semantics)
-	items, _, err := shopSvc.ListPagedFiltered(1, 2000, "notOver1y", "")
-	if err != nil || len(items) == 0 {
+// sendDailyReport sends the expiry report when some shop expired or expires
+// within a month; delivery failures are in notification_deliveries
+func sendDailyReport(shopSvc *service.ShopService, notices *service.NotificationService, now time.Time) {
+	rep, err := shopSvc.ExpiryReport(now, "Báo cáo hằng ngày (08:00) — Shop không quá 1 tháng")
+	if err != nil {
+		log.Printf("daily report: %v", err)
 		return
 	}
-	var expired []string
-	var expiring []string
-	for _, s := range items {
-		if s.ExpiredAt == nil {
-			continue
-		}
-		days := int(s.ExpiredAt.Sub(now).Hours() / 24)
-		// future days might be truncated; make non-zero future days at least 1
-		if s.ExpiredAt.After(now) && (s.ExpiredAt.Sub(now).Hours() > 0) {
-			if days < 1 {
-				days = 1
-			}
-		}
-		if s.ExpiredAt.Before(now) {
-			expired = append(expired, fmt.Sprintf("• %s — hết hạn %d ngày", s.Domain, -days))
-		} else {
-			expiring = append(expiring, fmt.Sprintf("• %s — còn %d ngày", s.Domain, days))
-		}
-	}
-	if len(expired) == 0 && len(expiring) == 0 {
+	if rep.Expired == 0 && rep.Expiring == 0 {
 		return
 	}
-	msg := fmt.Sprintf("Báo cáo hằng ngày (08:00) — Shop không quá 1 tháng\nNgày: %s\nTổng: %d\nHết hạn: %d\nSắp hết hạn: %d\n\n", now.Format("2006-01-02"), len(items), len(expired), len(expiring))
-	if len(expired) > 0 {
-		msg += "Hết hạn:\n" + joinLines(expired) + "\n\n"
-	}
-	if len(expiring) > 0 {
-		msg += "Sắp hết hạn:\n" + joinLines(expiring)
-	}
-	body, _ := json.Marshal(slackPayload{Text: msg})
-	_ = httpPostJSON(webhook, body)
-}
-
-func httpPostJSON(url string, body []byte) error {
-	req, _ := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
-	req.Header.Set("Content-Type", "application/json")
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode >= 300 {
-		return fmt.Errorf("slack http status %d", resp.StatusCode)
-	}
-	return nil
-}
-
-func joinLines(lines []string) string {
-	if len(lines) == 0 {
-		return ""
-	}
-	out := lines[0]
-	for i := 1; i < len(lines); i++ {
-		out += "\n" + lines[i]
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if _, err := notices.Notify(ctx, model.NotifyExpiryReport, rep.Message); err != nil && !errors.Is(err, service.ErrNoChannels) {
+		log.Printf("daily report: %v", err)
 	}
-	return out
 }
 
 // parseLocationOrFixed supports IANA names or fixed offsets like "+07:00", "UTC+7"